@@ -17,15 +17,29 @@
 package libbs
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/heroku/color"
+	"github.com/mattn/go-shellwords"
 	"github.com/paketo-buildpacks/libpak/sbom"
 
 	"github.com/buildpacks/libcnb"
+	"github.com/paketo-buildpacks/libjvm"
 	"github.com/paketo-buildpacks/libpak"
 	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/paketo-buildpacks/libpak/crush"
@@ -34,6 +48,119 @@ import (
 	"github.com/paketo-buildpacks/source-removal/logic"
 )
 
+// ResolvedArtifactsMetadataKey is the layer metadata key under which the artifacts persisted to the layer are recorded.
+const ResolvedArtifactsMetadataKey = "resolved-artifacts"
+
+// ResolvedArtifact describes an artifact that was resolved from the build and persisted to the layer.
+type ResolvedArtifact struct {
+
+	// Name is the name of the artifact that was resolved.
+	Name string `toml:"name"`
+
+	// Path is the path of the persisted artifact, relative to the layer.
+	Path string `toml:"path"`
+
+	// Size is the size of the artifact in bytes.
+	Size int64 `toml:"size"`
+
+	// SHA256 is the SHA256 of a file artifact, or of the file listing of a directory artifact.
+	SHA256 string `toml:"sha256"`
+
+	// ClassPath is the resolved Class-Path manifest entries of a plain JAR artifact, if RecordClassPath is set.
+	ClassPath []string `toml:"class-path,omitempty"`
+
+	// Process is the process type that the artifact was resolved for, if it was resolved with the
+	// ProcessArtifactPatterns.
+	Process string `toml:"process,omitempty"`
+}
+
+// BuildSBOMScanner is the subset of sbom.SBOMScanner used to scan the application for its build SBOM, so that any
+// scanner, not only Syft, can be used.  Every sbom.SBOMScanner is a BuildSBOMScanner.
+type BuildSBOMScanner interface {
+
+	// ScanBuild scans scanDir and writes the build SBOM in each of the formats.
+	ScanBuild(scanDir string, formats ...libcnb.SBOMFormat) error
+}
+
+var _ BuildSBOMScanner = sbom.SBOMScanner(nil)
+
+// ParallelBuildSBOMScanner is a BuildSBOMScanner that can limit the number of concurrent workers used by a scan.
+type ParallelBuildSBOMScanner interface {
+	BuildSBOMScanner
+
+	// ScanBuildWithParallelism scans scanDir like ScanBuild, using at most parallelism concurrent workers.
+	ScanBuildWithParallelism(parallelism int, scanDir string, formats ...libcnb.SBOMFormat) error
+}
+
+// Copier copies files and directories between the application path and the layer, so that a strategy suited to the
+// filesystem, such as reflinks, can be used.
+type Copier interface {
+
+	// CopyFile copies the file from to the file to, creating its parent directories.
+	CopyFile(from string, to string) error
+
+	// CopyDir copies the contents of the directory from into the directory to, recursively.
+	CopyDir(from string, to string) error
+}
+
+// DefaultCopier is the Copier used when none is set.  It copies the contents of each file.
+type DefaultCopier struct{}
+
+func (DefaultCopier) CopyFile(from string, to string) error {
+	return copyFile(from, to)
+}
+
+func (DefaultCopier) CopyDir(from string, to string) error {
+	return copyDirectory(from, to)
+}
+
+// ArtifactUploader uploads the persisted artifacts, e.g. to an object store for retention beyond the image.
+type ArtifactUploader interface {
+
+	// Upload uploads the contents of the artifact with the given name.
+	Upload(name string, r io.Reader) error
+}
+
+// EmptyCachePolicy describes what happens when a clean build, one that starts with an empty cache, leaves the cache
+// empty.
+type EmptyCachePolicy string
+
+const (
+	// EmptyCacheIgnore does nothing.  This is the default.
+	EmptyCacheIgnore EmptyCachePolicy = "ignore"
+
+	// EmptyCacheWarn logs a warning.
+	EmptyCacheWarn EmptyCachePolicy = "warn"
+
+	// EmptyCacheFail fails the contribution.
+	EmptyCacheFail EmptyCachePolicy = "fail"
+)
+
+// ArtifactMode describes how artifacts are persisted to and restored from the layer.
+type ArtifactMode string
+
+const (
+	// ArtifactModeAuto persists a single file artifact that is a zip or a, possibly compressed, tarball as
+	// application.zip, extracting it on restore, and copies any other artifacts as-is.  This is the default.
+	ArtifactModeAuto ArtifactMode = "auto"
+
+	// ArtifactModeFile requires a single file artifact, which is persisted as application.zip and extracted on
+	// restore if it is a zip or a, possibly compressed, tarball, and copied as-is otherwise.
+	ArtifactModeFile ArtifactMode = "file"
+
+	// ArtifactModeDirectory copies all artifacts as-is and never creates application.zip.
+	ArtifactModeDirectory ArtifactMode = "directory"
+)
+
+// DefaultWarningPatterns match warning and deprecation lines in build output.
+var DefaultWarningPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)\bwarn(ing)?\b`),
+	regexp.MustCompile(`(?i)\bdeprecat(ed|ion)\b`),
+}
+
+// GradleBuildScanPattern matches the URLs of published Gradle build scans.
+var GradleBuildScanPattern = regexp.MustCompile(`https://gradle\.com/s/[A-Za-z0-9]+`)
+
 type Application struct {
 	ApplicationPath  string
 	Arguments        []string
@@ -44,150 +171,1612 @@ type Application struct {
 	LayerContributor libpak.LayerContributor
 	Logger           bard.Logger
 	BOM              *libcnb.BOM
-	SBOMScanner      sbom.SBOMScanner
+	SBOMScanner      BuildSBOMScanner
+
+	// Caches, if any are set, are the caches used by the build in place of Cache, e.g. ~/.m2 and ~/.gradle of a
+	// polyglot build contributed to a single layer.  Each is verified, pruned and checked for a clean build like Cache,
+	// and their dependencies are listed together in the build dependencies of the BOM.
+	Caches Caches
+
+	// Environment are environment variables, such as MAVEN_OPTS, that the build is run with in addition to, and in
+	// preference to, the environment of the buildpack.
+	Environment map[string]string
+
+	// PreRemoveInspector, if set, is called with the application path after the build has completed and before the
+	// workspace is purged.  Returning an error aborts the contribution without removing any files.
+	PreRemoveInspector func(appPath string) error
+
+	// ContentAddressable, if true, persists each file artifact to the layer with its SHA256, plus its extension, as its
+	// name, so that identical artifacts are stored identically across builds.  The artifacts are restored with their
+	// original names, as recorded in the layer metadata.  A single artifact that is extracted on restore is still
+	// persisted as application.zip, and one with an ArtifactName as that name.
+	ContentAddressable bool
+
+	// KeepSources, if true, or if $BP_KEEP_SOURCES is true, keeps the workspace as the build left it, so that a later
+	// tool can read the source code, rather than removing the source code and restoring the application artifacts
+	// from the layer in its place.  The artifacts are still persisted to the layer.
+	KeepSources bool
+
+	// PreserveSubdirs are the directories, relative to the application path, that are kept when the workspace is
+	// purged, e.g. src/main/resources/static for a following buildpack.  Each may be a glob, matched segment by
+	// segment.  Their siblings and the other contents of their parents are still removed.  Only applies when neither
+	// $BP_INCLUDE_FILES nor $BP_EXCLUDE_FILES is configured.
+	PreserveSubdirs []string
+
+	// KeepFiles are globs, relative to the application path, of the files and directories that are kept when the
+	// workspace is purged.  They are combined, in order of increasing precedence, with PreserveSubdirs, with the globs
+	// listed in the KeepFileName file, and with the colon separated globs in $BP_KEEP_FILES.  A glob starting with !
+	// removes what it matches even if a glob of lower precedence keeps it, and the last glob that matches a path
+	// decides whether it is kept.  Only applies when neither $BP_INCLUDE_FILES nor $BP_EXCLUDE_FILES is configured.
+	KeepFiles []string
+
+	// OutputBufferSize, if greater than zero, line-buffers the build output so that lines of up to this many bytes are
+	// written to the log whole rather than in the fragments the build tool happens to emit.
+	OutputBufferSize int
+
+	// SeedFiles are files, keyed by path relative to the application path, that are written into the workspace before
+	// the build is executed.  Seeded files are removed with the rest of the workspace after the build.
+	SeedFiles map[string][]byte
+
+	// ArtifactMode determines how artifacts are persisted to and restored from the layer.  Defaults to
+	// ArtifactModeAuto.
+	ArtifactMode ArtifactMode
+
+	// BuildScanPattern, if set, collects text in the build output that matches the pattern, such as build scan URLs,
+	// and logs it in a summary once the build has completed.
+	BuildScanPattern *regexp.Regexp
+
+	// WarningPatterns, if set, collect the lines of the build output that match any of the patterns, such as
+	// DefaultWarningPatterns, and log a deduplicated summary of them once the build has completed.  The build output
+	// is still logged in full.
+	WarningPatterns []*regexp.Regexp
+
+	// RestoreOwnership, if set, is the ownership applied to the restore path once the artifacts have been restored.
+	// Ignored unless running as root.  See NewOwnershipFromEnvironment.
+	RestoreOwnership *Ownership
+
+	// RestorePath, if set, is the writable path that the artifacts are restored to instead of the application path,
+	// e.g. when the application path is a read-only mount.  Defaults to the application path.
+	RestorePath string
+
+	// ReadOnlyDetector, if set, determines whether the application path is read-only.  Defaults to checking whether a
+	// file can be created in it.  The source code is not removed from a read-only application path, and the
+	// artifacts are only restored if a distinct RestorePath is set.
+	ReadOnlyDetector func(path string) (bool, error)
+
+	// MergeOutput, if true, line-buffers the build's stdout and stderr into a single synchronized writer so that lines
+	// from the two streams are not interleaved.  The buffer size is OutputBufferSize, if set.
+	MergeOutput bool
+
+	// NormalizeLineEndings, if true, rewrites CRLF line endings to LF in text files persisted to the layer.
+	NormalizeLineEndings bool
+
+	// TextExtensions are the extensions of the files that NormalizeLineEndings applies to.  Defaults to
+	// DefaultTextExtensions.
+	TextExtensions []string
+
+	// BuildLogPath, if set, is the path of a file that the build's output is also written to.  The file is written
+	// even if the build fails.
+	BuildLogPath string
+
+	// LogToFile, if true and no BuildLogPath is set, also writes the build's output to BuildLogFileName in the layer,
+	// so that it is available for inspection after the build, including after a failed build.
+	LogToFile bool
+
+	// BuildLogMaxSize, if greater than zero, is the maximum number of bytes of build output written to BuildLogPath.
+	// Output beyond this size is discarded and a truncation marker is written in its place.
+	BuildLogMaxSize int64
+
+	// ExplodeArtifact, if true, extracts a single executable JAR artifact into the application path even when
+	// ArtifactMode is ArtifactModeDirectory, rather than restoring the JAR file itself.
+	ExplodeArtifact bool
+
+	// ArtifactName, if set, is the name that a single file artifact is persisted to the layer as, instead of
+	// application.zip, unless ArtifactMode is ArtifactModeDirectory.  The artifact is restored verbatim with this name
+	// unless ExtractOnRestore is true.
+	ArtifactName string
+
+	// ExtractOnRestore, if true, extracts the single file artifact persisted as ArtifactName on restore, as is done for
+	// application.zip.  Only applies when ArtifactName is set.
+	ExtractOnRestore bool
+
+	// RecordClassPath, if true, records the Class-Path manifest entries of plain JAR artifacts, resolved against the
+	// location the artifact is restored to, in the resolved artifacts layer metadata.
+	RecordClassPath bool
+
+	// Nice, if non-zero, is the niceness adjustment the build is run with, e.g. 10 to lower its CPU priority on a shared
+	// builder.  Only supported on Linux, where the build process inherits the niceness of the thread that starts it, so
+	// the Executor must start the command on the goroutine that calls it, as the effect executors and GroupExecutor do.
+	// Where it cannot be applied, e.g. a negative adjustment without privileges, a warning is logged and it has no
+	// effect.
+	Nice int
+
+	// CgroupPath is the cgroup v2 directory under which a cgroup is created to apply $BP_BUILD_MEMORY_LIMIT, a size
+	// such as 2G, to the build.  Defaults to the cgroup of the current process.  The memory limit is only supported on
+	// Linux, and is ignored with a warning if the cgroup cannot be created.  A build that is killed for running out of
+	// memory while a limit is configured fails with an error recommending more memory or less parallelism.
+	CgroupPath string
+
+	// VerifyRestore, if true, fails the contribution if no file exists in the application path once the artifacts have
+	// been restored.
+	VerifyRestore bool
+
+	// VerifyRestorePattern, if set, is a glob, relative to the application path, that must match at least one file
+	// once the artifacts have been restored.  Implies VerifyRestore.
+	VerifyRestorePattern string
+
+	// EmptyCachePolicy determines what happens when a clean build leaves the cache empty, which usually indicates a
+	// misconfigured offline build that resolved no dependencies.  Defaults to EmptyCacheIgnore.
+	EmptyCachePolicy EmptyCachePolicy
+
+	// SBOMParallelism, if positive, limits the number of concurrent workers used by the build SBOM scan so that it can
+	// be throttled on shared builders.  $BP_BUILD_SBOM_PARALLELISM takes precedence when set.  A
+	// ParallelBuildSBOMScanner is passed the limit directly; any other scanner is run with $SYFT_PARALLELISM and
+	// $GOMAXPROCS set to the limit, which bounds the Syft CLI.
+	SBOMParallelism int
+
+	// RebuildDecider, if set, is called with the metadata of the previous contribution when the application layer has
+	// been restored.  Returning true forces a rebuild even if the metadata matches, e.g. because an upstream dependency
+	// has changed.  If nil, the layer is rebuilt only when its metadata changes.
+	RebuildDecider func(previous map[string]interface{}) (bool, error)
+
+	// MetricsSink, if set, receives the metrics emitted while contributing, such as the build duration, artifact sizes,
+	// whether the cached layer was reused, and the number of build dependencies.
+	MetricsSink MetricsSink
+
+	// ArtifactUploader, if set, is passed each file artifact, as persisted to the layer, with its original name.
+	// Directory artifacts are not uploaded.  A failed upload fails the contribution.
+	ArtifactUploader ArtifactUploader
+
+	// Copier, if set, copies the artifacts to and from the layer.  Defaults to DefaultCopier.
+	Copier Copier
+
+	// ArtifactPathFile, if set, is the path of a file, such as ArtifactPathFileName in the layers directory, that the
+	// paths of the restored artifacts are written to, one per line, so that a following buildpack can find them.  A
+	// single artifact that is extracted on restore is listed as the restore path.
+	ArtifactPathFile string
+
+	// VerifyReproducible, if true, builds a copy of the workspace, taken before the build, a second time and fails the
+	// contribution if the SHA256 of any artifact of the two builds differs.  The differing artifacts are reported.  The
+	// second build shares the cache, Timeout, Retries and memory limit of the first but otherwise doubles the cost of
+	// the build.
+	VerifyReproducible bool
+
+	// DeterministicSBOM, if true, normalizes the build SBOM files written by the SBOMScanner, sorting their lists of
+	// objects and removing their timestamps and serial numbers, so that scanning identical input yields identical
+	// bytes regardless of the order in which the scanner found the components.
+	DeterministicSBOM bool
+
+	// CapturedOutputMaxSize, if greater than zero, is the maximum number of bytes of build output returned by
+	// ContributeWithOutput.  Defaults to DefaultCapturedOutputMaxSize.
+	CapturedOutputMaxSize int64
+
+	// Timeout, if greater than zero, is the maximum duration of the build.  Once it has passed, the build is killed, along
+	// with its process group with GroupExecutor, and the contribution fails without the layer being marked complete.
+	// Requires the Executor to be a ContextExecutor.
+	Timeout time.Duration
+
+	// Retries, if greater than zero, is the number of times the build command is re-run, after RetryBackoff, when it
+	// fails, e.g. because of a transient failure to download a dependency.  A build that timed out or was killed for
+	// running out of memory is not retried, and neither are the SBOM scan and the persisting of artifacts.  The Timeout
+	// applies to each attempt.
+	Retries int
+
+	// RetryBackoff is the duration to wait before each retry of the build command.
+	RetryBackoff time.Duration
+
+	// Umask, if set, is the umask that the build is run, and the artifacts are persisted and restored, with, so that
+	// the modes of the files created do not depend on the umask of the builder.  The umask of the process is restored
+	// once the application has been contributed.  Has no effect on Windows.
+	Umask *int
+
+	// ArtifactFromStdout, if true, writes the standard output of the build, rather than logging it, to a file named
+	// StdoutArtifactName that is persisted as the only artifact, instead of resolving the artifacts from the
+	// application path.  This supports build tools that stream the artifact to standard output.
+	ArtifactFromStdout bool
+
+	// StdoutArtifactName is the name of the artifact written from the standard output of the build when
+	// ArtifactFromStdout is true.  Defaults to DefaultStdoutArtifactName.
+	StdoutArtifactName string
+
+	// ProcessArtifactPatterns, if set, are the patterns, keyed by process type, e.g. web and worker, that each resolve
+	// a single artifact, relative to the application path, instead of resolving the artifacts with ArtifactResolver's
+	// pattern.  Each artifact is persisted as-is in a directory of the layer named for its process type and the
+	// paths they are restored to are returned by ProcessArtifacts.
+	ProcessArtifactPatterns map[string]string
+
+	// capture, if set, is the buffer that the combined build output is also written to.
+	capture *bytes.Buffer
+
+	// metadata, if set, computes the expected metadata of the LayerContributor when it is first needed.
+	metadata *lazyMetadata
+}
+
+// lazyMetadata computes the expected metadata of the LayerContributor once, sharing the result between the copies of
+// an Application so that every Contribute compares the layer against the metadata of the unbuilt sources.
+type lazyMetadata struct {
+	compute  func() (map[string]interface{}, error)
+	once     sync.Once
+	expected map[string]interface{}
+	err      error
+}
+
+// ResolveMetadata computes the expected metadata of the LayerContributor, if the application was created with it
+// deferred, so that it is available before Contribute, which otherwise computes it.
+func (a *Application) ResolveMetadata() error {
+	if a.metadata == nil {
+		return nil
+	}
+
+	a.metadata.once.Do(func() {
+		a.metadata.expected, a.metadata.err = a.metadata.compute()
+	})
+	if a.metadata.err != nil {
+		return fmt.Errorf("failed to generate expected metadata\n%w", a.metadata.err)
+	}
+
+	a.LayerContributor.ExpectedMetadata = a.metadata.expected
+	return nil
+}
+
+// BuildLogFileName is the name of the file in the layer that the build's output is written to when LogToFile is true.
+const BuildLogFileName = "build.log"
+
+// ArtifactPathFileName is the conventional name of the ArtifactPathFile in the layers directory.
+const ArtifactPathFileName = "libbs-artifact-path"
+
+// DefaultStdoutArtifactName is the name of the artifact written from the standard output of the build when no
+// StdoutArtifactName is specified.
+const DefaultStdoutArtifactName = "application"
+
+// DefaultCapturedOutputMaxSize is the maximum number of bytes of build output returned by ContributeWithOutput when no
+// positive size is specified.
+const DefaultCapturedOutputMaxSize = 1024 * 1024
+
+// ContributeWithOutput contributes the application like Contribute, additionally returning the combined stdout and
+// stderr of the build, truncated to CapturedOutputMaxSize, e.g. for assertions in integration tests.  The output is
+// returned even if the build fails, and is empty if the layer was restored from cache without building.
+func (a Application) ContributeWithOutput(layer libcnb.Layer) (libcnb.Layer, string, error) {
+	a.capture = &bytes.Buffer{}
+
+	layer, err := a.Contribute(layer)
+	return layer, a.capture.String(), err
+}
+
+func (a Application) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	if err := a.ResolveMetadata(); err != nil {
+		return libcnb.Layer{}, err
+	}
+	a.LayerContributor.Logger = a.Logger
+
+	if a.Umask != nil {
+		defer withUmask(*a.Umask)()
+	}
+
+	if a.LogToFile && a.BuildLogPath == "" {
+		a.BuildLogPath = filepath.Join(layer.Path, BuildLogFileName)
+	}
+
+	// Resolved artifacts are recorded in the layer metadata but are not part of the expected metadata
+	previous, hasPrevious := layer.Metadata[ResolvedArtifactsMetadataKey]
+	delete(layer.Metadata, ResolvedArtifactsMetadataKey)
+
+	if a.RebuildDecider != nil && len(layer.Metadata) > 0 {
+		rebuild, err := a.RebuildDecider(layer.Metadata)
+		if err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to decide whether to rebuild\n%w", err)
+		}
+
+		if rebuild {
+			a.Logger.Body("Forcing rebuild of cached layer")
+			layer.Metadata = map[string]interface{}{}
+		}
+	}
+
+	if err := a.checkPaths(layer); err != nil {
+		return libcnb.Layer{}, err
+	}
+
+	if err := a.checkSourceRemoval(); err != nil {
+		return libcnb.Layer{}, err
+	}
+
+	var resolved []ResolvedArtifact
+	built := false
+	layer, err := a.LayerContributor.Contribute(layer, func() (libcnb.Layer, error) {
+		built = true
+
+		if prebuilt, err := a.prebuiltArtifact(); err != nil {
+			return libcnb.Layer{}, err
+		} else if prebuilt != "" {
+			a.Logger.Bodyf("Using prebuilt artifact %s, skipping build", prebuilt)
+			if resolved, err = a.persist(layer, []string{prebuilt}); err != nil {
+				return libcnb.Layer{}, err
+			}
+			return layer, nil
+		}
+
+		if a.ArtifactResolver.ConfigurationResolver.ResolveBool("BP_BUILD_VERIFY_WRAPPER") {
+			s, _ := a.ArtifactResolver.ConfigurationResolver.Resolve("BP_BUILD_WRAPPER_SHA256")
+			if err := VerifyWrapper(a.ApplicationPath, strings.Fields(s)); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to verify build tool wrapper\n%w", err)
+			}
+			a.Logger.Body("Verified build tool wrapper checksums")
+		}
+
+		if a.ArtifactResolver.ConfigurationResolver.ResolveBool("BP_BUILD_VERIFY_CACHE") {
+			if err := a.verifyCache(); err != nil {
+				return libcnb.Layer{}, err
+			}
+		}
+
+		// Seed
+		if err := a.seed(); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to seed files\n%w", err)
+		}
+
+		clean, err := a.cachesEmpty()
+		if err != nil {
+			return libcnb.Layer{}, err
+		}
+
+		var snapshot string
+		if a.VerifyReproducible && a.ArtifactFromStdout {
+			return libcnb.Layer{}, fmt.Errorf("unable to verify that an artifact written to standard output is reproducible")
+		} else if a.VerifyReproducible {
+			if snapshot, err = a.snapshotWorkspace(); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to snapshot workspace\n%w", err)
+			}
+			defer os.RemoveAll(snapshot)
+		}
+
+		// Build
+		a.Logger.Bodyf("Executing %s %s", filepath.Base(a.Command), strings.Join(a.Arguments, " "))
+		output, err := a.buildOutput()
+		if err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to create build output\n%w", err)
+		}
+		var stdout io.Writer = output.Stdout
+		var stdoutArtifact *os.File
+		if a.ArtifactFromStdout {
+			if stdoutArtifact, err = a.stdoutArtifact(); err != nil {
+				return libcnb.Layer{}, err
+			}
+			defer os.RemoveAll(filepath.Dir(stdoutArtifact.Name()))
+			stdout = stdoutArtifact
+		}
+		start := time.Now()
+		err = a.runBuild(a.ApplicationPath, stdout, output.Stderr)
+		if fErr := output.Close(); fErr != nil && err == nil {
+			err = fmt.Errorf("error running build\n%w", fErr)
+		}
+		if stdoutArtifact != nil {
+			if fErr := stdoutArtifact.Close(); fErr != nil && err == nil {
+				err = fmt.Errorf("error running build\n%w", fErr)
+			}
+		}
+		a.record(MetricBuildDuration, time.Since(start).Seconds(), map[string]string{"command": filepath.Base(a.Command)})
+		if err != nil {
+			return libcnb.Layer{}, err
+		}
+
+		if clean {
+			if err := a.checkEmptyCache(); err != nil {
+				return libcnb.Layer{}, err
+			}
+		}
+
+		// In some cases, process output does not end with a clean line of output
+		// This resets the cursor to the beginningo of the next line so indentation lines up
+		a.Logger.Info()
+
+		if matches := output.BuildScans(); len(matches) > 0 {
+			a.Logger.Header("Build scans")
+			for _, m := range matches {
+				a.Logger.Body(m)
+			}
+		}
+
+		if warnings := output.Warnings(); len(warnings) > 0 {
+			a.Logger.Headerf("Warnings (%d)", len(warnings))
+			for _, w := range warnings {
+				a.Logger.Body(w)
+			}
+		}
+
+		// Persist Artifacts
+		var processes, artifacts []string
+		if stdoutArtifact != nil {
+			artifacts = []string{stdoutArtifact.Name()}
+		} else if processes, artifacts, err = a.resolveArtifacts(a.ApplicationPath); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to resolve artifacts\n%w", err)
+		}
+		a.Logger.Debugf("Found artifacts: %s", artifacts)
+
+		if a.VerifyReproducible {
+			if err := a.verifyReproducible(snapshot, artifacts); err != nil {
+				return libcnb.Layer{}, err
+			}
+		}
+
+		if len(processes) > 0 {
+			resolved, err = a.persistProcesses(layer, processes, artifacts)
+		} else {
+			resolved, err = a.persist(layer, artifacts)
+		}
+		if err != nil {
+			return libcnb.Layer{}, err
+		}
+		for _, r := range resolved {
+			a.record(MetricArtifactSize, float64(r.Size), map[string]string{"artifact": r.Name})
+		}
+
+		return layer, nil
+	})
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to contribute application layer\n%w", err)
+	}
+	if built {
+		a.record(MetricCacheHit, 0, nil)
+		if layer.Metadata == nil {
+			layer.Metadata = map[string]interface{}{}
+		}
+		layer.Metadata[ResolvedArtifactsMetadataKey] = resolved
+	} else {
+		a.record(MetricCacheHit, 1, nil)
+		a.Logger.Body("Restoring application from cached layer")
+		if hasPrevious {
+			layer.Metadata[ResolvedArtifactsMetadataKey] = previous
+		}
+	}
+
+	pruned, err := a.pruneCaches()
+	if err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to prune cache\n%w", err)
+	}
+
+	// Create SBOM
+	if err := a.withoutCacheLink(func() error {
+		return a.scanBuild(a.ApplicationPath, libcnb.CycloneDXJSON, libcnb.SyftJSON)
+	}); err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to create Build SBoM \n%w", err)
+	}
+	if a.DeterministicSBOM {
+		layers := libcnb.Layers{Path: filepath.Dir(layer.Path)}
+		for _, f := range []libcnb.SBOMFormat{libcnb.CycloneDXJSON, libcnb.SyftJSON} {
+			if err := normalizeSBOM(layers.BuildSBOMPath(f)); err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to normalize Build SBoM\n%w", err)
+			}
+		}
+	}
+
+	bomLabel := !sherpa.ResolveBool("BP_BOM_LABEL_DISABLED")
+	forbidSnapshots := a.ArtifactResolver.ConfigurationResolver.ResolveBool("BP_BUILD_FORBID_SNAPSHOTS")
+	licenseScan := a.ArtifactResolver.ConfigurationResolver.ResolveBool("BP_BUILD_LICENSE_SCAN")
+	if !pruned && (bomLabel || forbidSnapshots || licenseScan || a.MetricsSink != nil) {
+		entry, name, err := a.cachesBOMEntry()
+		if err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to generate build dependencies\n%w", err)
+		}
+
+		d, _ := entry.Metadata["dependencies"].([]libjvm.MavenJAR)
+		a.record(MetricDependencies, float64(len(d)), map[string]string{"layer": name})
+
+		if licenseScan {
+			licenses, err := a.scanCacheLicenses()
+			if err != nil {
+				return libcnb.Layer{}, fmt.Errorf("unable to scan licenses of build dependencies\n%w", err)
+			}
+
+			file := filepath.Join(layer.Path, LicenseScanFileName)
+			if err := writeLicenses(file, licenses); err != nil {
+				return libcnb.Layer{}, err
+			}
+			a.Logger.Bodyf("Wrote licenses of %d build dependencies to %s", len(licenses), file)
+		}
+
+		if forbidSnapshots {
+			if s := snapshots(entry); len(s) > 0 {
+				return libcnb.Layer{}, fmt.Errorf("build dependencies must not be SNAPSHOT versions, found:\n%s",
+					strings.Join(s, "\n"))
+			}
+		}
+
+		if bomLabel {
+			entry.Metadata["layer"] = name
+			a.addBOMEntry(entry)
+		}
+	}
+
+	if bomLabel {
+		a.addBOMEntry(a.AsBOMEntry(layer))
+	}
+
+	// Inspect Workspace
+	if a.PreRemoveInspector != nil {
+		if err := a.PreRemoveInspector(a.ApplicationPath); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to inspect %s\n%w", a.ApplicationPath, err)
+		}
+	}
+
+	if a.KeepSources || a.ArtifactResolver.ConfigurationResolver.ResolveBool("BP_KEEP_SOURCES") {
+		a.Logger.Header("Keeping source code")
+		a.Logger.Body("Not removing source code or restoring the application artifacts")
+		return layer, nil
+	}
+
+	// Purge Workspace
+	readOnly, err := a.readOnly()
+	if err != nil {
+		return libcnb.Layer{}, err
+	}
+
+	if readOnly {
+		a.Logger.Header("Removing source code")
+		a.Logger.Body(color.YellowString("Application path %s is read-only, not removing source code", a.ApplicationPath))
+		if a.restorePath() == a.ApplicationPath {
+			a.Logger.Body(color.YellowString("Set RestorePath to restore the application artifacts to a writable path"))
+			return layer, nil
+		}
+	} else if err := a.purgeWorkspace(); err != nil {
+		return libcnb.Layer{}, err
+	}
+
+	// Restore compiled artifacts
+	if err := a.restore(layer); err != nil {
+		return libcnb.Layer{}, err
+	}
+
+	if err := a.verifyRestore(); err != nil {
+		return libcnb.Layer{}, err
+	}
+
+	if a.RestoreOwnership != nil {
+		if err := a.RestoreOwnership.Apply(a.restorePath()); err != nil {
+			return libcnb.Layer{}, fmt.Errorf("unable to change ownership of restored artifacts\n%w", err)
+		}
+	}
+
+	if a.ArtifactPathFile != "" {
+		if err := a.writeArtifactPaths(layer); err != nil {
+			return libcnb.Layer{}, err
+		}
+	}
+
+	return layer, nil
+}
+
+// writeArtifactPaths writes the paths of the restored artifacts to the ArtifactPathFile.
+func (a Application) writeArtifactPaths(layer libcnb.Layer) error {
+	var paths []string
+	for _, r := range recordedArtifacts(layer.Metadata[ResolvedArtifactsMetadataKey]) {
+		if r.Path == a.artifactName() && !a.verbatim() {
+			paths = append(paths, a.restorePath())
+		} else {
+			paths = append(paths, filepath.Join(a.restorePath(), filepath.Dir(r.Path), r.Name))
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(a.ArtifactPathFile), 0755); err != nil {
+		return fmt.Errorf("unable to create directory %s\n%w", filepath.Dir(a.ArtifactPathFile), err)
+	}
+
+	var b strings.Builder
+	for _, p := range paths {
+		b.WriteString(p)
+		b.WriteString("\n")
+	}
+	if err := os.WriteFile(a.ArtifactPathFile, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("unable to write %s\n%w", a.ArtifactPathFile, err)
+	}
+	a.Logger.Bodyf("Wrote artifact paths to %s", a.ArtifactPathFile)
+
+	return nil
+}
+
+// environment returns the environment of the buildpack merged with the Environment, or nil, so that the environment
+// of the buildpack is inherited, if no Environment is set.
+func (a Application) environment() []string {
+	if len(a.Environment) == 0 {
+		return nil
+	}
+
+	var env []string
+	for _, e := range os.Environ() {
+		if k, _, _ := strings.Cut(e, "="); !containsKey(a.Environment, k) {
+			env = append(env, e)
+		}
+	}
+
+	var keys []string
+	for k := range a.Environment {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		env = append(env, fmt.Sprintf("%s=%s", k, a.Environment[k]))
+	}
+
+	return env
+}
+
+func containsKey(m map[string]string, k string) bool {
+	_, ok := m[k]
+	return ok
+}
+
+// copier returns the Copier used to copy the artifacts.
+func (a Application) copier() Copier {
+	if a.Copier != nil {
+		return a.Copier
+	}
+	return DefaultCopier{}
+}
+
+// prebuiltArtifact returns the path of the artifact configured with $BP_BUILD_PREBUILT_ARTIFACT, relative to the
+// application path unless absolute, that is persisted instead of building the application, if one is configured.
+func (a Application) prebuiltArtifact() (string, error) {
+	path, ok := a.ArtifactResolver.ConfigurationResolver.Resolve("BP_BUILD_PREBUILT_ARTIFACT")
+	if !ok || path == "" {
+		return "", nil
+	}
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(a.ApplicationPath, path)
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return "", fmt.Errorf("unable to find prebuilt artifact %s\n%w", path, err)
+	}
+
+	return path, nil
+}
+
+// stdoutArtifact creates the file, in a temporary directory, that the standard output of the build is written to
+// when ArtifactFromStdout is true.
+func (a Application) stdoutArtifact() (*os.File, error) {
+	name := a.StdoutArtifactName
+	if name == "" {
+		name = DefaultStdoutArtifactName
+	}
+
+	dir, err := ioutil.TempDir("", "libbs-stdout")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary directory\n%w", err)
+	}
+
+	f, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0755)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("unable to open %s\n%w", filepath.Join(dir, name), err)
+	}
+
+	return f, nil
+}
+
+// artifactName returns the name that a single file artifact is persisted to the layer as.
+func (a Application) artifactName() string {
+	if a.ArtifactName != "" && a.ArtifactMode != ArtifactModeDirectory {
+		return a.ArtifactName
+	}
+	return "application.zip"
+}
+
+// verbatim determines whether a single file artifact persisted as the artifact name is restored without extracting it.
+func (a Application) verbatim() bool {
+	return a.artifactName() != "application.zip" && !a.ExtractOnRestore
+}
+
+// restorePath returns the path that artifacts are restored to.
+func (a Application) restorePath() string {
+	if a.RestorePath != "" {
+		return a.RestorePath
+	}
+	return a.ApplicationPath
+}
+
+// readOnly determines whether the application path is read-only.
+func (a Application) readOnly() (bool, error) {
+	if a.ReadOnlyDetector != nil {
+		ok, err := a.ReadOnlyDetector(a.ApplicationPath)
+		if err != nil {
+			return false, fmt.Errorf("unable to determine whether %s is read-only\n%w", a.ApplicationPath, err)
+		}
+		return ok, nil
+	}
+
+	return readOnly(a.ApplicationPath)
+}
+
+// readOnly determines whether path is read-only by creating, and removing, a file in it.
+func readOnly(path string) (bool, error) {
+	f, err := os.CreateTemp(path, ".libbs-write-check-")
+	if os.IsPermission(err) || errors.Is(err, syscall.EROFS) {
+		return true, nil
+	} else if err != nil {
+		return false, fmt.Errorf("unable to create file in %s\n%w", path, err)
+	}
+
+	if err := f.Close(); err != nil {
+		return false, fmt.Errorf("unable to close %s\n%w", f.Name(), err)
+	}
+	if err := os.Remove(f.Name()); err != nil {
+		return false, fmt.Errorf("unable to remove %s\n%w", f.Name(), err)
+	}
+
+	return false, nil
+}
+
+// purgeWorkspace removes the source code from the application path.
+func (a Application) purgeWorkspace() error {
+	a.Logger.Header("Removing source code")
+	if err := a.unlinkCache(); err != nil {
+		return err
+	}
+
+	includeDirs, iset := a.ArtifactResolver.ConfigurationResolver.Resolve("BP_INCLUDE_FILES")
+	if includeDirs != "" {
+		if err := logic.Include(a.ApplicationPath, includeDirs); err != nil {
+			return fmt.Errorf("unable to perform source-removal 'include' \n%w", err)
+		}
+	}
+	excludeDirs, eset := a.ArtifactResolver.ConfigurationResolver.Resolve("BP_EXCLUDE_FILES")
+	if excludeDirs != "" {
+		if err := logic.Exclude(a.ApplicationPath, excludeDirs); err != nil {
+			return fmt.Errorf("unable to perform source-removal 'exclude' \n%w", err)
+		}
+	}
+	// if the source remvoval env vars are all unset and the default values are all empty
+	// fall back to the legacy behavior
+	if excludeDirs == "" && includeDirs == "" && !iset && !eset {
+		rules, err := a.keepRules()
+		if err != nil {
+			return err
+		}
+
+		if err := a.purge(a.ApplicationPath, rules, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkSourceRemoval checks that the globs of $BP_INCLUDE_FILES and $BP_EXCLUDE_FILES are well-formed, so that a
+// malformed glob fails the contribution before any files are removed rather than silently matching nothing.
+func (a Application) checkSourceRemoval() error {
+	var invalid []string
+	for _, name := range []string{"BP_INCLUDE_FILES", "BP_EXCLUDE_FILES"} {
+		s, _ := a.ArtifactResolver.ConfigurationResolver.Resolve(name)
+		for _, g := range invalidGlobs(s) {
+			invalid = append(invalid, fmt.Sprintf("$%s contains malformed glob %q", name, g))
+		}
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid source removal configuration, expected a colon separated list of globs:\n%s",
+			strings.Join(invalid, "\n"))
+	}
+
+	return nil
+}
+
+// verifyCache removes the JARs in the cache that do not match their SHA1, and their .sha1 files, so that the build
+// downloads them again rather than using corrupted dependencies.
+func (a Application) verifyCache() error {
+	var corrupted []string
+	for _, c := range a.caches() {
+		files, err := c.Verify()
+		if err != nil {
+			return fmt.Errorf("unable to verify cache\n%w", err)
+		}
+		corrupted = append(corrupted, files...)
+	}
+
+	for _, file := range corrupted {
+		a.Logger.Bodyf("%s %s does not match its SHA1, removing it to be downloaded again",
+			color.YellowString("Warning:"), file)
+		for _, f := range []string{file, file + ".sha1"} {
+			if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("unable to remove corrupted dependency %s\n%w", f, err)
+			}
+		}
+	}
+
+	if len(corrupted) == 0 {
+		a.Logger.Body("Verified cached dependencies")
+	}
+
+	return nil
+}
+
+// checkEmptyCache applies the EmptyCachePolicy once a clean build has completed.
+func (a Application) checkEmptyCache() error {
+	if a.EmptyCachePolicy == "" || a.EmptyCachePolicy == EmptyCacheIgnore {
+		return nil
+	}
+
+	empty, err := a.cachesEmpty()
+	if err != nil {
+		return err
+	} else if !empty {
+		return nil
+	}
+
+	var paths []string
+	for _, c := range a.caches() {
+		paths = append(paths, c.Path)
+	}
+	path := strings.Join(paths, ", ")
+
+	if a.EmptyCachePolicy == EmptyCacheFail {
+		return fmt.Errorf("clean build left cache %s empty", path)
+	}
+
+	a.Logger.Bodyf("%s, no dependencies were resolved by a clean build", color.YellowString("Cache %s is empty", path))
+	return nil
+}
+
+// caches returns the caches used by the build, the Caches if any are set and otherwise the Cache.
+func (a Application) caches() []Cache {
+	if len(a.Caches.Caches) > 0 {
+		return a.Caches.Caches
+	}
+	return []Cache{a.Cache}
+}
+
+// cachesEmpty determines whether all of the caches are empty, as they are before a clean build.
+func (a Application) cachesEmpty() (bool, error) {
+	for _, c := range a.caches() {
+		if empty, err := c.Empty(); err != nil || !empty {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// pruneCaches prunes each of the caches, returning true if all of them were removed.
+func (a Application) pruneCaches() (bool, error) {
+	pruned := true
+	for _, c := range a.caches() {
+		p, err := c.Prune()
+		if err != nil {
+			return false, err
+		}
+		pruned = pruned && p
+	}
+	return pruned, nil
+}
+
+// cachesBOMEntry returns the build dependencies BOM entry of the caches, and the name of the layer they are
+// contributed to.
+func (a Application) cachesBOMEntry() (libcnb.BOMEntry, string, error) {
+	if len(a.Caches.Caches) > 0 {
+		entry, err := a.Caches.AsBOMEntry()
+		return entry, a.Caches.Name(), err
+	}
+
+	entry, err := a.Cache.AsBOMEntry()
+	return entry, a.Cache.Name(), err
+}
+
+// scanCacheLicenses scans the licenses of the dependencies of each of the caches.
+func (a Application) scanCacheLicenses() ([]DependencyLicense, error) {
+	licenses := []DependencyLicense{}
+	for _, c := range a.caches() {
+		d, err := c.Dependencies()
+		if err != nil {
+			return nil, err
+		}
+
+		l, err := ScanLicenses(c.Path, d)
+		if err != nil {
+			return nil, err
+		}
+		licenses = append(licenses, l...)
+	}
+	return licenses, nil
+}
+
+func (Application) Name() string {
+	return "application"
+}
+
+// persist copies the resolved artifacts into the layer.  A single file artifact is persisted as application.zip.
+func (a Application) persist(layer libcnb.Layer, artifacts []string) ([]ResolvedArtifact, error) {
+	if a.ArtifactMode == ArtifactModeFile {
+		if len(artifacts) != 1 {
+			return nil, fmt.Errorf("artifact mode %s requires a single artifact, found %s", a.ArtifactMode, artifacts)
+		}
+		if fileInfo, err := os.Stat(artifacts[0]); err != nil {
+			return nil, fmt.Errorf("unable to resolve artifact %s\n%w", artifacts[0], err)
+		} else if fileInfo.IsDir() {
+			return nil, fmt.Errorf("artifact mode %s requires a file artifact, found directory %s", a.ArtifactMode, artifacts[0])
+		}
+	}
+
+	s, _ := a.ArtifactResolver.ConfigurationResolver.Resolve("BP_BUILD_ARTIFACT_STRIP")
+	strip, err := shellwords.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse BP_BUILD_ARTIFACT_STRIP %s\n%w", s, err)
+	}
+
+	var resolved []ResolvedArtifact
+
+	for _, artifact := range artifacts {
+		fileInfo, err := os.Stat(artifact)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve artifact %s\n%w", artifact, err)
+		}
+
+		var dest string
+		exploded := false
+		if fileInfo.IsDir() {
+			dest = filepath.Join(layer.Path, filepath.Base(artifact))
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				return nil, fmt.Errorf("unable to create directory %s\n%w", dest, err)
+			}
+			if err := a.copier().CopyDir(artifact, dest); err != nil {
+				return nil, fmt.Errorf("unable to copy the directory\n%w", err)
+			}
+		} else {
+			dest = filepath.Join(layer.Path, fileInfo.Name())
+			if len(artifacts) == 1 {
+				if explode, err := a.explode(artifact); err != nil {
+					return nil, err
+				} else if explode {
+					dest, exploded = filepath.Join(layer.Path, a.artifactName()), true
+				}
+			}
+			if len(strip) > 0 && isZip(artifact) {
+				stripped, err := a.stripArtifact(artifact, dest, strip)
+				if err != nil {
+					return nil, fmt.Errorf("unable to strip %s\n%w", artifact, err)
+				}
+				a.Logger.Bodyf("Stripped %d entries from %s", len(stripped), fileInfo.Name())
+				a.Logger.Debugf("Stripped entries: %s", stripped)
+			} else if err := a.copier().CopyFile(artifact, dest); err != nil {
+				return nil, fmt.Errorf("unable to copy the file %s to %s\n%w", artifact, dest, err)
+			}
+		}
+
+		r, err := a.describePersisted(layer, artifact, fileInfo, dest, exploded)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, r)
+	}
+
+	return resolved, nil
+}
+
+// describePersisted describes the artifact persisted to dest in the layer, after normalizing its line endings,
+// renaming it by its content, uploading it and recording its class path as configured.  An exploded artifact keeps
+// its name.
+func (a Application) describePersisted(layer libcnb.Layer, artifact string, fileInfo os.FileInfo, dest string, exploded bool) (ResolvedArtifact, error) {
+	if a.NormalizeLineEndings {
+		extensions := a.TextExtensions
+		if len(extensions) == 0 {
+			extensions = DefaultTextExtensions
+		}
+
+		if err := normalizeLineEndings(dest, fileInfo.Name(), extensions); err != nil {
+			return ResolvedArtifact{}, fmt.Errorf("unable to normalize line endings of %s\n%w", artifact, err)
+		}
+	}
+
+	r, err := describeArtifact(fileInfo.Name(), layer.Path, dest)
+	if err != nil {
+		return ResolvedArtifact{}, fmt.Errorf("unable to describe artifact %s\n%w", artifact, err)
+	}
+
+	if a.ContentAddressable && !fileInfo.IsDir() && !exploded {
+		name := filepath.Join(filepath.Dir(r.Path), r.SHA256+filepath.Ext(fileInfo.Name()))
+		if err := os.Rename(dest, filepath.Join(layer.Path, name)); err != nil {
+			return ResolvedArtifact{}, fmt.Errorf("unable to rename %s to %s\n%w", dest, name, err)
+		}
+		r.Path = name
+	}
+
+	if a.ArtifactUploader != nil && !fileInfo.IsDir() {
+		if err := a.upload(fileInfo.Name(), filepath.Join(layer.Path, r.Path)); err != nil {
+			return ResolvedArtifact{}, err
+		}
+	}
+
+	if a.RecordClassPath && !fileInfo.IsDir() && isZip(artifact) {
+		if r.ClassPath, err = a.classPath(artifact, r); err != nil {
+			return ResolvedArtifact{}, fmt.Errorf("unable to record class path of %s\n%w", artifact, err)
+		}
+	}
+
+	return r, nil
+}
+
+// upload uploads the persisted artifact at path with the ArtifactUploader.
+func (a Application) upload(name string, path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	a.Logger.Bodyf("Uploading %s", name)
+	if err := a.ArtifactUploader.Upload(name, in); err != nil {
+		return fmt.Errorf("unable to upload %s\n%w", name, err)
+	}
+
+	return nil
+}
+
+// explode determines whether a single file artifact should be persisted as the artifact name, to be extracted on
+// restore unless an ArtifactName is set.
+func (a Application) explode(artifact string) (bool, error) {
+	if a.artifactName() != "application.zip" {
+		return true, nil
+	}
+
+	if a.ArtifactMode != ArtifactModeDirectory {
+		ok, err := restorable(artifact)
+		if err != nil {
+			return false, fmt.Errorf("unable to detect content type of %s\n%w", artifact, err)
+		}
+		return ok, nil
+	}
+
+	if !a.ExplodeArtifact || !isZip(artifact) {
+		return false, nil
+	}
+
+	t, err := DetectArtifactType(artifact)
+	if err != nil {
+		return false, fmt.Errorf("unable to detect artifact type of %s\n%w", artifact, err)
+	}
+
+	return t == ExecutableJar, nil
+}
+
+// classPath returns the Class-Path manifest entries of a plain JAR artifact, resolved against the directory within
+// the application path that the artifact is restored to.
+func (a Application) classPath(artifact string, resolved ResolvedArtifact) ([]string, error) {
+	if t, err := DetectArtifactType(artifact); err != nil {
+		return nil, fmt.Errorf("unable to detect artifact type of %s\n%w", artifact, err)
+	} else if t != PlainJar {
+		return nil, nil
+	}
+
+	entries, err := manifestClassPath(artifact)
+	if err != nil {
+		return nil, err
+	}
+
+	var cp []string
+	for _, e := range entries {
+		cp = append(cp, filepath.Join(a.restorePath(), filepath.Dir(resolved.Path), filepath.FromSlash(e)))
+	}
+
+	return cp, nil
 }
 
-func (a Application) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
-	a.LayerContributor.Logger = a.Logger
+// restore restores the artifacts persisted in the layer to the application path.
+func (a Application) restore(layer libcnb.Layer) error {
+	file := filepath.Join(layer.Path, a.artifactName())
 
-	layer, err := a.LayerContributor.Contribute(layer, func() (libcnb.Layer, error) {
-		// Build
-		a.Logger.Bodyf("Executing %s %s", filepath.Base(a.Command), strings.Join(a.Arguments, " "))
-		if err := a.Executor.Execute(effect.Execution{
-			Command: a.Command,
-			Args:    a.Arguments,
-			Dir:     a.ApplicationPath,
-			Stdout:  bard.NewWriter(a.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
-			Stderr:  bard.NewWriter(a.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
-		}); err != nil {
-			return libcnb.Layer{}, fmt.Errorf("error running build\n%w", err)
+	if a.ArtifactMode == ArtifactModeDirectory && !a.ExplodeArtifact {
+		return a.restoreDirectory(layer)
+	}
+
+	if _, err := os.Stat(file); err == nil && a.verbatim() {
+		return a.restoreVerbatim(file)
+	} else if err == nil {
+		return a.restoreFile(file)
+	} else if os.IsNotExist(err) {
+		return a.restoreDirectory(layer)
+	} else {
+		return fmt.Errorf("unable to restore artifacts\n%w", err)
+	}
+}
+
+func (a Application) restoreFile(file string) error {
+	a.Logger.Header("Restoring application artifact")
+	in, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("unable to open %s\n%w", file, err)
+	}
+	defer in.Close()
+
+	t, err := DetectContentType(file)
+	if err != nil {
+		return fmt.Errorf("unable to detect content type of %s\n%w", file, err)
+	}
+
+	switch t {
+	case ContentTypeZip:
+		err = crush.ExtractZip(in, a.restorePath(), 0)
+	case ContentTypeTar, ContentTypeGzip, ContentTypeXz, ContentTypeBzip2:
+		err = crush.Extract(in, a.restorePath(), 0)
+	default:
+		return fmt.Errorf("unable to extract %s, unsupported content type %s", file, t)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to extract %s\n%w", file, err)
+	}
+
+	return nil
+}
+
+// restoreVerbatim copies a single file artifact to the restore path without extracting it.
+func (a Application) restoreVerbatim(file string) error {
+	a.Logger.Header("Restoring application artifact")
+	if err := a.copier().CopyFile(file, filepath.Join(a.restorePath(), filepath.Base(file))); err != nil {
+		return fmt.Errorf("unable to restore %s\n%w", file, err)
+	}
+
+	return nil
+}
+
+func (a Application) restoreDirectory(layer libcnb.Layer) error {
+	a.Logger.Header("Restoring multiple artifacts")
+	if err := a.copier().CopyDir(layer.Path, a.restorePath()); err != nil {
+		return fmt.Errorf("unable to restore multiple artifacts\n%w", err)
+	}
+
+	// the license inventory and the build log are not artifacts
+	names := []string{LicenseScanFileName}
+	if a.LogToFile {
+		names = append(names, BuildLogFileName)
+	}
+	for _, name := range names {
+		if _, err := os.Stat(filepath.Join(layer.Path, name)); err == nil {
+			if err := os.Remove(filepath.Join(a.restorePath(), name)); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("unable to remove %s\n%w", name, err)
+			}
 		}
+	}
 
-		// In some cases, process output does not end with a clean line of output
-		// This resets the cursor to the beginningo of the next line so indentation lines up
-		a.Logger.Info()
+	return a.restoreNames(recordedArtifacts(layer.Metadata[ResolvedArtifactsMetadataKey]))
+}
 
-		// Persist Artifacts
-		artifacts, err := a.ArtifactResolver.ResolveMany(a.ApplicationPath)
-		if err != nil {
-			return libcnb.Layer{}, fmt.Errorf("unable to resolve artifacts\n%w", err)
+// restoreNames renames the restored artifacts that were persisted under a content-addressable name to their original
+// names.
+func (a Application) restoreNames(artifacts []ResolvedArtifact) error {
+	var renamed []string
+	for _, r := range artifacts {
+		if filepath.Base(r.Path) == r.Name {
+			continue
 		}
-		a.Logger.Debugf("Found artifacts: %s", artifacts)
 
-		if len(artifacts) == 1 {
-			artifact := artifacts[0]
+		from := filepath.Join(a.restorePath(), r.Path)
+		if _, err := os.Stat(from); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("unable to stat %s\n%w", from, err)
+		}
 
-			fileInfo, err := os.Stat(artifact)
-			if err != nil {
-				return libcnb.Layer{}, fmt.Errorf("unable to resolve artifact %s\n%w", artifact, err)
-			}
+		if err := a.copier().CopyFile(from, filepath.Join(a.restorePath(), filepath.Dir(r.Path), r.Name)); err != nil {
+			return fmt.Errorf("unable to restore %s as %s\n%w", r.Path, r.Name, err)
+		}
+		renamed = append(renamed, from)
+	}
 
-			if fileInfo.IsDir() {
-				if err := copyDirectory(artifact, filepath.Join(layer.Path, filepath.Base(artifact))); err != nil {
-					return libcnb.Layer{}, fmt.Errorf("unable to copy the directory\n%w", err)
-				}
-			} else {
-				file := filepath.Join(layer.Path, "application.zip")
-				if err := copyFile(artifact, file); err != nil {
-					return libcnb.Layer{}, fmt.Errorf("unable to copy the file %s to %s\n%w", artifact, file, err)
-				}
-			}
-		} else {
-			for _, artifact := range artifacts {
-				fileInfo, err := os.Stat(artifact)
-				if err != nil {
-					return libcnb.Layer{}, fmt.Errorf("unable to resolve artifact %s\n%w", artifact, err)
-				}
+	for _, file := range renamed {
+		if err := os.RemoveAll(file); err != nil {
+			return fmt.Errorf("unable to remove %s\n%w", file, err)
+		}
+	}
 
-				if fileInfo.IsDir() {
-					if err := copyDirectory(artifact, filepath.Join(layer.Path, filepath.Base(artifact))); err != nil {
-						return libcnb.Layer{}, fmt.Errorf("unable to copy a directory\n%w", err)
-					}
-				} else {
-					dest := filepath.Join(layer.Path, fileInfo.Name())
-					if err := copyFile(artifact, dest); err != nil {
-						return libcnb.Layer{}, fmt.Errorf("unable to copy a file %s to %s\n%w", artifact, dest, err)
-					}
-				}
+	return nil
+}
+
+// recordedArtifacts returns the artifacts recorded in the layer metadata, which have been decoded from TOML if the
+// layer was restored from the cache.
+func recordedArtifacts(metadata interface{}) []ResolvedArtifact {
+	switch m := metadata.(type) {
+	case []ResolvedArtifact:
+		return m
+	case []map[string]interface{}:
+		var artifacts []ResolvedArtifact
+		for _, e := range m {
+			name, _ := e["name"].(string)
+			path, _ := e["path"].(string)
+			process, _ := e["process"].(string)
+			artifacts = append(artifacts, ResolvedArtifact{Name: name, Path: path, Process: process})
+		}
+		return artifacts
+	case []interface{}:
+		var artifacts []map[string]interface{}
+		for _, e := range m {
+			if e, ok := e.(map[string]interface{}); ok {
+				artifacts = append(artifacts, e)
 			}
 		}
+		return recordedArtifacts(artifacts)
+	default:
+		return nil
+	}
+}
+
+// verifyRestore checks that the restore left the expected files in the application path.
+func (a Application) verifyRestore() error {
+	if a.VerifyRestorePattern != "" {
+		matches, err := filepath.Glob(filepath.Join(a.restorePath(), a.VerifyRestorePattern))
+		if err != nil {
+			return fmt.Errorf("unable to find files with %s\n%w", a.VerifyRestorePattern, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("no files matching %s found in %s after restoring artifacts", a.VerifyRestorePattern, a.restorePath())
+		}
+		return nil
+	}
 
-		return layer, nil
-	})
+	if !a.VerifyRestore {
+		return nil
+	}
+
+	found := false
+	if err := filepath.Walk(a.restorePath(), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.IsDir() {
+			found = true
+			return filepath.SkipAll
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("unable to walk %s\n%w", a.restorePath(), err)
+	}
+
+	if !found {
+		return fmt.Errorf("no files found in %s after restoring artifacts", a.restorePath())
+	}
+
+	return nil
+}
+
+// describeArtifact creates a ResolvedArtifact for an artifact persisted to path within the layer.
+func describeArtifact(name string, layerPath string, path string) (ResolvedArtifact, error) {
+	rel, err := filepath.Rel(layerPath, path)
 	if err != nil {
-		return libcnb.Layer{}, fmt.Errorf("unable to contribute application layer\n%w", err)
+		return ResolvedArtifact{}, fmt.Errorf("unable to find relative path of %s\n%w", path, err)
 	}
 
-	// Create SBOM
-	if err := a.SBOMScanner.ScanBuild(a.ApplicationPath, libcnb.CycloneDXJSON, libcnb.SyftJSON); err != nil {
-		return libcnb.Layer{}, fmt.Errorf("unable to create Build SBoM \n%w", err)
+	r := ResolvedArtifact{Name: name, Path: rel}
+
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return ResolvedArtifact{}, fmt.Errorf("unable to stat %s\n%w", path, err)
 	}
 
-	if !sherpa.ResolveBool("BP_BOM_LABEL_DISABLED") {
-		entry, err := a.Cache.AsBOMEntry()
+	if !fileInfo.IsDir() {
+		in, err := os.Open(path)
 		if err != nil {
-			return libcnb.Layer{}, fmt.Errorf("unable to generate build dependencies\n%w", err)
+			return ResolvedArtifact{}, fmt.Errorf("unable to open %s\n%w", path, err)
+		}
+		defer in.Close()
+
+		s := sha256.New()
+		if r.Size, err = io.Copy(s, in); err != nil {
+			return ResolvedArtifact{}, fmt.Errorf("unable to hash %s\n%w", path, err)
 		}
-		entry.Metadata["layer"] = a.Cache.Name()
-		a.BOM.Entries = append(a.BOM.Entries, entry)
+		r.SHA256 = hex.EncodeToString(s.Sum(nil))
+
+		return r, nil
 	}
 
-	// Purge Workspace
-	a.Logger.Header("Removing source code")
-	includeDirs, iset := a.ArtifactResolver.ConfigurationResolver.Resolve("BP_INCLUDE_FILES")
-	if includeDirs != "" {
-		if err := logic.Include(a.ApplicationPath, includeDirs); err != nil {
-			return libcnb.Layer{}, fmt.Errorf("unable to perform source-removal 'include' \n%w", err)
+	if err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
 		}
+		if info.Mode().IsRegular() {
+			r.Size += info.Size()
+		}
+		return nil
+	}); err != nil {
+		return ResolvedArtifact{}, fmt.Errorf("unable to walk %s\n%w", path, err)
 	}
-	excludeDirs, eset := a.ArtifactResolver.ConfigurationResolver.Resolve("BP_EXCLUDE_FILES")
-	if excludeDirs != "" {
-		if err := logic.Exclude(a.ApplicationPath, excludeDirs); err != nil {
-			return libcnb.Layer{}, fmt.Errorf("unable to perform source-removal 'exclude' \n%w", err)
+
+	if r.SHA256, err = sherpa.NewFileListingHash(path); err != nil {
+		return ResolvedArtifact{}, fmt.Errorf("unable to hash %s\n%w", path, err)
+	}
+
+	return r, nil
+}
+
+// seed writes the SeedFiles into the application path.
+func (a Application) seed() error {
+	for path, content := range a.SeedFiles {
+		file := filepath.Join(a.ApplicationPath, path)
+		if !within(a.ApplicationPath, file) {
+			return fmt.Errorf("seed file %s is outside of %s", path, a.ApplicationPath)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return fmt.Errorf("unable to create directory %s\n%w", filepath.Dir(file), err)
+		}
+
+		a.Logger.Debugf("Seeding %s", file)
+		if err := os.WriteFile(file, content, 0644); err != nil {
+			return fmt.Errorf("unable to write %s\n%w", file, err)
 		}
 	}
-	// if the source remvoval env vars are all unset and the default values are all empty
-	// fall back to the legacy behavior
-	if excludeDirs == "" && includeDirs == "" && !iset && !eset {
-		cs, err := ioutil.ReadDir(a.ApplicationPath)
-		if err != nil {
-			return libcnb.Layer{}, fmt.Errorf("unable to list children of %s\n%w", a.ApplicationPath, err)
+
+	return nil
+}
+
+// AsBOMEntry returns a build BOM entry that records how the application was built in layer: the command, its
+// arguments, and the pattern that the artifacts were resolved with.
+func (a Application) AsBOMEntry(layer libcnb.Layer) libcnb.BOMEntry {
+	arguments := a.Arguments
+	if arguments == nil {
+		arguments = []string{}
+	}
+
+	return libcnb.BOMEntry{
+		Name: "build-command",
+		Metadata: map[string]interface{}{
+			"layer":            layer.Name,
+			"command":          a.Command,
+			"arguments":        arguments,
+			"artifact-pattern": a.ArtifactResolver.Pattern(),
+		},
+		Build: true,
+	}
+}
+
+// addBOMEntry adds an entry to the BOM, replacing any entry with the same name and layer added by a previous
+// contribution so that repeated contributions do not duplicate entries.
+func (a Application) addBOMEntry(entry libcnb.BOMEntry) {
+	for i, e := range a.BOM.Entries {
+		if e.Name == entry.Name && e.Metadata["layer"] == entry.Metadata["layer"] {
+			a.BOM.Entries[i] = entry
+			return
 		}
-		for _, c := range cs {
-			file := filepath.Join(a.ApplicationPath, c.Name())
-			if err := os.RemoveAll(file); err != nil {
-				return libcnb.Layer{}, fmt.Errorf("unable to remove %s\n%w", file, err)
-			}
+	}
+
+	a.BOM.Entries = append(a.BOM.Entries, entry)
+}
+
+// record records a metric with the MetricsSink, if one is set.
+func (a Application) record(name string, value float64, tags map[string]string) {
+	if a.MetricsSink != nil {
+		a.MetricsSink.Record(name, value, tags)
+	}
+}
+
+// scanBuild runs the build SBOM scan, limiting its concurrency when a parallelism is configured.
+func (a Application) scanBuild(scanDir string, formats ...libcnb.SBOMFormat) error {
+	parallelism := a.SBOMParallelism
+	if s, ok := a.ArtifactResolver.ConfigurationResolver.Resolve("BP_BUILD_SBOM_PARALLELISM"); ok {
+		p, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || p < 1 {
+			return fmt.Errorf("unable to parse BP_BUILD_SBOM_PARALLELISM %s, must be a positive integer", s)
 		}
+		parallelism = p
 	}
-	// Restore compiled artifacts
-	file := filepath.Join(layer.Path, "application.zip")
-	if _, err := os.Stat(file); err == nil {
-		a.Logger.Header("Restoring application artifact")
-		in, err := os.Open(file)
+
+	if parallelism < 1 {
+		return a.SBOMScanner.ScanBuild(scanDir, formats...)
+	}
+
+	if s, ok := a.SBOMScanner.(ParallelBuildSBOMScanner); ok {
+		return s.ScanBuildWithParallelism(parallelism, scanDir, formats...)
+	}
+
+	a.Logger.Bodyf("Limiting build SBOM scan to %d workers", parallelism)
+	for _, k := range []string{"SYFT_PARALLELISM", "GOMAXPROCS"} {
+		restore, err := setenv(k, strconv.Itoa(parallelism))
 		if err != nil {
-			return libcnb.Layer{}, fmt.Errorf("unable to open %s\n%w", file, err)
+			return err
 		}
-		defer in.Close()
+		defer restore()
+	}
+
+	return a.SBOMScanner.ScanBuild(scanDir, formats...)
+}
+
+// setenv sets an environment variable and returns a function that restores its previous value.
+func setenv(key string, value string) (func(), error) {
+	previous, ok := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		return nil, fmt.Errorf("unable to set $%s\n%w", key, err)
+	}
+
+	return func() {
+		if ok {
+			_ = os.Setenv(key, previous)
+		} else {
+			_ = os.Unsetenv(key)
+		}
+	}, nil
+}
 
-		if err := crush.ExtractZip(in, a.ApplicationPath, 0); err != nil {
-			return libcnb.Layer{}, fmt.Errorf("unable to extract %s\n%w", file, err)
+// withoutCacheLink runs f with the cache symlinks temporarily removed when they are within the application path, so
+// that the build SBOM scan does not include the cached dependencies, and then restores the links.
+func (a Application) withoutCacheLink(f func() error) error {
+	targets := map[string]string{}
+	for _, c := range a.caches() {
+		if !a.cacheLinkInApplication(c) {
+			continue
 		}
-	} else if err != nil && os.IsNotExist(err) {
-		a.Logger.Header("Restoring multiple artifacts")
-		err := copyDirectory(layer.Path, a.ApplicationPath)
+
+		target, err := os.Readlink(c.Path)
 		if err != nil {
-			return libcnb.Layer{}, fmt.Errorf("unable to restore multiple artifacts\n%w", err)
+			return fmt.Errorf("unable to read link %s\n%w", c.Path, err)
 		}
-	} else {
-		return libcnb.Layer{}, fmt.Errorf("unable to restore artifacts\n%w", err)
+
+		if err := os.Remove(c.Path); err != nil {
+			return fmt.Errorf("unable to unlink cache %s\n%w", c.Path, err)
+		}
+		targets[c.Path] = target
 	}
 
-	return layer, nil
+	fErr := f()
+
+	for path, target := range targets {
+		if err := os.Symlink(target, path); err != nil {
+			return fmt.Errorf("unable to link cache from %s to %s\n%w", target, path, err)
+		}
+	}
+
+	return fErr
 }
 
-func (Application) Name() string {
-	return "application"
+// cacheLinkInApplication determines whether the cache is a symlink within the application path.
+func (a Application) cacheLinkInApplication(cache Cache) bool {
+	if cache.Path == "" {
+		return false
+	}
+
+	rel, err := filepath.Rel(a.ApplicationPath, cache.Path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return false
+	}
+
+	fileInfo, err := os.Lstat(cache.Path)
+	return err == nil && fileInfo.Mode()&os.ModeSymlink != 0
+}
+
+// checkPaths ensures that the layer path does not overlap the application path or the RestorePath.  Purging the
+// workspace and restoring the artifacts would otherwise delete the layer, and resetting the layer the application.
+func (a Application) checkPaths(layer libcnb.Layer) error {
+	if overlaps(layer.Path, a.ApplicationPath) {
+		return fmt.Errorf("invalid configuration, layer path %s and application path %s must not contain one another",
+			layer.Path, a.ApplicationPath)
+	}
+
+	if overlaps(layer.Path, a.RestorePath) {
+		return fmt.Errorf("invalid configuration, layer path %s and restore path %s must not contain one another",
+			layer.Path, a.RestorePath)
+	}
+
+	return nil
+}
+
+// overlaps determines whether either of a and b is, or is within, the other.  An empty path overlaps nothing.
+func overlaps(a string, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+
+	return within(a, b) || within(b, a)
+}
+
+// within determines whether path is, or is within, parent.
+func within(parent string, path string) bool {
+	rel, err := filepath.Rel(parent, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// unlinkCache removes the cache symlink, without following it, when it is within the application path.  Some build
+// tools keep their cache in the project directory, and removing the link before the workspace is purged ensures the
+// purge never descends into the cache layer.
+func (a Application) unlinkCache() error {
+	for _, c := range a.caches() {
+		if !a.cacheLinkInApplication(c) {
+			continue
+		}
+
+		a.Logger.Bodyf("Unlinking cache %s", c.Path)
+		if err := os.Remove(c.Path); err != nil {
+			return fmt.Errorf("unable to unlink cache %s\n%w", c.Path, err)
+		}
+	}
+
+	return nil
+}
+
+// keepRules returns the rules, in order of increasing precedence, of the files kept when the workspace is purged.
+func (a Application) keepRules() (keepRules, error) {
+	globs := append(append([]string{}, a.PreserveSubdirs...), a.KeepFiles...)
+
+	file, err := readKeepFile(a.ApplicationPath)
+	if err != nil {
+		return nil, err
+	}
+	globs = append(globs, file...)
+
+	if s, _ := a.ArtifactResolver.ConfigurationResolver.Resolve("BP_KEEP_FILES"); s != "" {
+		globs = append(globs, filepath.SplitList(s)...)
+	}
+
+	return newKeepRules(globs), nil
+}
+
+// purge removes the children of path that are not kept, descending into directories that contain kept files.
+// inherited is whether path itself is kept.
+func (a Application) purge(path string, rules keepRules, inherited bool) error {
+	cs, err := ioutil.ReadDir(path)
+	if err != nil {
+		return fmt.Errorf("unable to list children of %s\n%w", path, err)
+	}
+
+	for _, c := range cs {
+		file := filepath.Join(path, c.Name())
+
+		rel, err := filepath.Rel(a.ApplicationPath, file)
+		if err != nil {
+			return fmt.Errorf("unable to find relative path of %s\n%w", file, err)
+		}
+
+		keep, descend := rules.match(rel, inherited)
+		if keep && !inherited {
+			a.Logger.Bodyf("Keeping %s", rel)
+		}
+
+		if c.IsDir() && descend {
+			if err := a.purge(file, rules, keep); err != nil {
+				return err
+			}
+
+			if cs, err := os.ReadDir(file); err != nil {
+				return fmt.Errorf("unable to list children of %s\n%w", file, err)
+			} else if len(cs) == 0 && !keep {
+				if err := os.Remove(file); err != nil {
+					return fmt.Errorf("unable to remove %s\n%w", file, err)
+				}
+			}
+			continue
+		}
+
+		if keep {
+			continue
+		}
+
+		if err := removeAll(file); err != nil {
+			return fmt.Errorf("unable to remove %s\n%w", file, err)
+		}
+	}
+
+	return nil
+}
+
+// removeAll removes path and any children it contains.  If removal fails because a directory is not writable, the
+// directories are made writable and removal is retried.
+func removeAll(path string) error {
+	err := os.RemoveAll(path)
+	if err == nil || !os.IsPermission(err) {
+		return err
+	}
+
+	if err := filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return os.Chmod(path, info.Mode().Perm()|0700)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("unable to make %s writable\n%w", path, err)
+	}
+
+	return os.RemoveAll(path)
 }
 
 func copyDirectory(from, to string) error {
@@ -200,12 +1789,16 @@ func copyDirectory(from, to string) error {
 		sourcePath := filepath.Join(from, file.Name())
 		destPath := filepath.Join(to, file.Name())
 
-		fileInfo, err := os.Stat(sourcePath)
+		fileInfo, err := os.Lstat(sourcePath)
 		if err != nil {
 			return err
 		}
 
-		if fileInfo.IsDir() {
+		if fileInfo.Mode()&os.ModeSymlink != 0 {
+			if err := copySymlink(sourcePath, destPath); err != nil {
+				return err
+			}
+		} else if fileInfo.IsDir() {
 			if err := copyDirectory(sourcePath, destPath); err != nil {
 				return err
 			}
@@ -219,6 +1812,28 @@ func copyDirectory(from, to string) error {
 	return nil
 }
 
+// copySymlink recreates the symlink from, with the same, possibly relative, target, at to.
+func copySymlink(from string, to string) error {
+	target, err := os.Readlink(from)
+	if err != nil {
+		return fmt.Errorf("unable to read link %s\n%w", from, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(to), 0755); err != nil {
+		return fmt.Errorf("unable to create directory %s\n%w", filepath.Dir(to), err)
+	}
+
+	if err := os.Remove(to); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to remove %s\n%w", to, err)
+	}
+
+	if err := os.Symlink(target, to); err != nil {
+		return fmt.Errorf("unable to link %s to %s\n%w", to, target, err)
+	}
+
+	return nil
+}
+
 func copyFile(from string, to string) error {
 	in, err := os.Open(from)
 	if err != nil {
@@ -226,9 +1841,18 @@ func copyFile(from string, to string) error {
 	}
 	defer in.Close()
 
+	fileInfo, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("unable to stat %s\n%w", from, err)
+	}
+
 	if err := sherpa.CopyFile(in, to); err != nil {
 		return fmt.Errorf("unable to copy %s to %s\n%w", from, to, err)
 	}
 
+	if err := os.Chmod(to, fileInfo.Mode()); err != nil {
+		return fmt.Errorf("unable to set mode of %s\n%w", to, err)
+	}
+
 	return nil
 }
@@ -17,12 +17,25 @@
 package libbs_test
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	gocontext "context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/buildpacks/libcnb"
 	. "github.com/onsi/gomega"
@@ -38,6 +51,170 @@ import (
 	"github.com/paketo-buildpacks/libbs"
 )
 
+type fakeSBOMScanner struct {
+	err      error
+	formats  [][]libcnb.SBOMFormat
+	scanDirs []string
+}
+
+func (f *fakeSBOMScanner) ScanBuild(scanDir string, formats ...libcnb.SBOMFormat) error {
+	f.scanDirs = append(f.scanDirs, scanDir)
+	f.formats = append(f.formats, formats)
+	return f.err
+}
+
+type fakeParallelSBOMScanner struct {
+	fakeSBOMScanner
+	parallelism []int
+}
+
+func (f *fakeParallelSBOMScanner) ScanBuildWithParallelism(parallelism int, scanDir string, formats ...libcnb.SBOMFormat) error {
+	f.parallelism = append(f.parallelism, parallelism)
+	return f.ScanBuild(scanDir, formats...)
+}
+
+type fileSBOMScanner struct {
+	files []string
+}
+
+func (f *fileSBOMScanner) ScanBuild(scanDir string, _ ...libcnb.SBOMFormat) error {
+	return filepath.Walk(scanDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if info, err = os.Stat(path); err != nil {
+				return err
+			} else if info.IsDir() {
+				return filepath.Walk(path+string(filepath.Separator), func(path string, info os.FileInfo, err error) error {
+					if err == nil && !info.IsDir() {
+						f.files = append(f.files, path)
+					}
+					return err
+				})
+			}
+		}
+
+		if !info.IsDir() {
+			f.files = append(f.files, path)
+		}
+		return nil
+	})
+}
+
+type metric struct {
+	name  string
+	value float64
+	tags  map[string]string
+}
+
+type recordingMetricsSink struct {
+	metrics []metric
+}
+
+func (r *recordingMetricsSink) Record(name string, value float64, tags map[string]string) {
+	r.metrics = append(r.metrics, metric{name: name, value: value, tags: tags})
+}
+
+type envSBOMScanner struct {
+	env map[string]string
+}
+
+func (e *envSBOMScanner) ScanBuild(string, ...libcnb.SBOMFormat) error {
+	e.env = map[string]string{"SYFT_PARALLELISM": os.Getenv("SYFT_PARALLELISM"), "GOMAXPROCS": os.Getenv("GOMAXPROCS")}
+	return nil
+}
+
+// shufflingSBOMScanner writes a CycloneDX SBOM whose components are in a different order, and whose timestamp and
+// serial number differ, on each scan.
+type shufflingSBOMScanner struct {
+	layers libcnb.Layers
+	calls  int
+}
+
+func (s *shufflingSBOMScanner) ScanBuild(string, ...libcnb.SBOMFormat) error {
+	s.calls++
+
+	components := []string{`{"name":"test-a","version":"1.1.1"}`, `{"name":"test-b","version":"2.2.2"}`}
+	if s.calls%2 == 0 {
+		components[0], components[1] = components[1], components[0]
+	}
+
+	return os.WriteFile(s.layers.BuildSBOMPath(libcnb.CycloneDXJSON), []byte(fmt.Sprintf(
+		`{"specVersion":"1.4","serialNumber":"urn:uuid:%d","metadata":{"timestamp":"2022-01-0%dT00:00:00Z","tools":["test-tool"]},"components":[%s]}`,
+		s.calls, s.calls, strings.Join(components, ","))), 0644)
+}
+
+// blockingContextExecutor is a ContextExecutor whose executions finish immediately if done, and otherwise once their
+// context is done.
+type blockingContextExecutor struct {
+	done bool
+}
+
+func (b blockingContextExecutor) Execute(execution effect.Execution) error {
+	return b.ExecuteContext(gocontext.Background(), execution)
+}
+
+func (b blockingContextExecutor) ExecuteContext(ctx gocontext.Context, _ effect.Execution) error {
+	if b.done {
+		return nil
+	}
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// funcContextExecutor is a ContextExecutor that runs each execution with a function.
+type funcContextExecutor func(ctx gocontext.Context, execution effect.Execution) error
+
+func (f funcContextExecutor) Execute(execution effect.Execution) error {
+	return f(gocontext.Background(), execution)
+}
+
+func (f funcContextExecutor) ExecuteContext(ctx gocontext.Context, execution effect.Execution) error {
+	return f(ctx, execution)
+}
+
+type copyCall struct {
+	From string
+	To   string
+	Dir  bool
+}
+
+type recordingCopier struct {
+	copies []copyCall
+}
+
+func (r *recordingCopier) CopyFile(from string, to string) error {
+	r.copies = append(r.copies, copyCall{From: from, To: to})
+	return libbs.DefaultCopier{}.CopyFile(from, to)
+}
+
+func (r *recordingCopier) CopyDir(from string, to string) error {
+	r.copies = append(r.copies, copyCall{From: from, To: to, Dir: true})
+	return libbs.DefaultCopier{}.CopyDir(from, to)
+}
+
+type fakeArtifactUploader struct {
+	err     error
+	uploads map[string][]byte
+}
+
+func (f *fakeArtifactUploader) Upload(name string, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if f.uploads == nil {
+		f.uploads = map[string][]byte{}
+	}
+	f.uploads[name] = b
+
+	return f.err
+}
+
 func testApplication(t *testing.T, context spec.G, it spec.S) {
 	var (
 		Expect = NewWithT(t).Expect
@@ -133,7 +310,7 @@ func testApplication(t *testing.T, context spec.G, it spec.S) {
 		Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).To(BeARegularFile())
 
 		sbomScanner.AssertCalled(t, "ScanBuild", ctx.Application.Path, libcnb.CycloneDXJSON, libcnb.SyftJSON)
-		Expect(bom.Entries).To(HaveLen(1))
+		Expect(bom.Entries).To(HaveLen(2))
 		Expect(bom.Entries).To(Equal([]libcnb.BOMEntry{
 			{
 				Name: "build-dependencies",
@@ -146,6 +323,19 @@ func testApplication(t *testing.T, context spec.G, it spec.S) {
 							SHA256:  "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
 						},
 					},
+					"cache-size-bytes": int64(0),
+					"cache-file-count": 1,
+				},
+				Launch: false,
+				Build:  true,
+			},
+			{
+				Name: "build-command",
+				Metadata: map[string]interface{}{
+					"layer":            "test-layer",
+					"command":          "test-command",
+					"arguments":        []string{"test-argument"},
+					"artifact-pattern": "*",
 				},
 				Launch: false,
 				Build:  true,
@@ -321,6 +511,33 @@ func testApplication(t *testing.T, context spec.G, it spec.S) {
 					Expect(filepath.Join(ctx.Application.Path, "random-link")).To(BeAnExistingFile())
 				})
 
+				it("fails with a malformed glob before removing any files", func() {
+					artifactResolver := libbs.ArtifactResolver{
+						ConfigurationResolver: libpak.ConfigurationResolver{
+							Configurations: []libpak.BuildpackConfiguration{
+								{Default: "target/native-sources"},
+								{Name: "BP_INCLUDE_FILES", Default: "random-*:random-["},
+								{Name: "BP_EXCLUDE_FILES", Default: "random-link"},
+							},
+						},
+					}
+					application.ArtifactResolver = artifactResolver
+
+					application.Logger = bard.NewLogger(ioutil.Discard)
+					executor.On("Execute", mock.Anything).Return(nil)
+
+					layer, err := ctx.Layers.Layer("test-layer")
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = application.Contribute(layer)
+					Expect(err).To(MatchError(ContainSubstring(`$BP_INCLUDE_FILES contains malformed glob "random-["`)))
+					Expect(err).NotTo(MatchError(ContainSubstring("random-link")))
+
+					executor.AssertNotCalled(t, "Execute", mock.Anything)
+					Expect(filepath.Join(ctx.Application.Path, "random-file")).To(BeAnExistingFile())
+					Expect(filepath.Join(ctx.Application.Path, "random-link")).To(BeAnExistingFile())
+				})
+
 				it("removes the excluded files", func() {
 					artifactResolver := libbs.ArtifactResolver{
 						ConfigurationResolver: libpak.ConfigurationResolver{
@@ -499,4 +716,3020 @@ func testApplication(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 	})
+
+	context("PreRemoveInspector", func() {
+		it.Before(func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "source-file"), []byte{}, 0644)).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "*.jar"}},
+				},
+			}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it("sees the workspace before it is purged", func() {
+			var seen []string
+			application.PreRemoveInspector = func(appPath string) error {
+				cs, err := os.ReadDir(appPath)
+				Expect(err).NotTo(HaveOccurred())
+				for _, c := range cs {
+					seen = append(seen, c.Name())
+				}
+				return nil
+			}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(seen).To(ConsistOf("source-file", "stub-application.jar"))
+			Expect(filepath.Join(ctx.Application.Path, "source-file")).NotTo(BeAnExistingFile())
+		})
+
+		it("does not purge the workspace if inspection fails", func() {
+			application.PreRemoveInspector = func(appPath string) error {
+				return fmt.Errorf("test-error")
+			}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("test-error")))
+
+			Expect(filepath.Join(ctx.Application.Path, "source-file")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "stub-application.jar")).To(BeARegularFile())
+		})
+	})
+
+	context("OutputBufferSize", func() {
+		it("writes whole lines to the log", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			out := &bytes.Buffer{}
+			application.Logger = bard.NewLogger(out)
+			application.OutputBufferSize = 1024
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				e := args.Get(0).(effect.Execution)
+				for _, s := range []string{"test-", "line", "\n"} {
+					_, err := e.Stdout.Write([]byte(s))
+					Expect(err).NotTo(HaveOccurred())
+				}
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out.String()).To(ContainSubstring("      test-line\n"))
+		})
+	})
+
+	context("repeated contribution", func() {
+		it("restores from the layer on the second call", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(cache.Path, "test-file-1.1.1.jar"), []byte{}, 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+			Expect(filepath.Join(layer.Path, "application.zip")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "stub-application.jar")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).To(BeARegularFile())
+			Expect(bom.Entries).To(HaveLen(2))
+		})
+	})
+
+	context("Caches", func() {
+		var m2, gradle string
+
+		it.Before(func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), []byte{}, 0644)).To(Succeed())
+
+			m2, gradle = filepath.Join(cache.Path, "m2"), filepath.Join(cache.Path, "gradle")
+			application.Caches = libbs.NewCaches(bard.NewLogger(ioutil.Discard), m2, gradle)
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it("lists the dependencies of all of the caches", func() {
+			for _, dir := range []string{m2, gradle} {
+				Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(dir, fmt.Sprintf("test-%s-1.1.1.jar", filepath.Base(dir))), []byte{}, 0644)).
+					To(Succeed())
+			}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(bom.Entries[0].Name).To(Equal("build-dependencies"))
+			Expect(bom.Entries[0].Metadata["layer"]).To(Equal("cache"))
+			Expect(bom.Entries[0].Metadata["dependencies"]).To(ConsistOf(
+				libjvm.MavenJAR{Name: "test-m2", Version: "1.1.1", SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+				libjvm.MavenJAR{Name: "test-gradle", Version: "1.1.1", SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+			))
+			Expect(bom.Entries[0].Metadata["cache-file-count"]).To(Equal(2))
+		})
+
+		it("applies the EmptyCachePolicy to all of the caches", func() {
+			application.EmptyCachePolicy = libbs.EmptyCacheFail
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("clean build left cache %s, %s empty", m2, gradle))))
+		})
+
+		it("does not apply the EmptyCachePolicy when any of the caches is populated", func() {
+			application.EmptyCachePolicy = libbs.EmptyCacheFail
+			executor.ExpectedCalls = nil
+			executor.On("Execute", mock.Anything).Run(func(mock.Arguments) {
+				Expect(os.MkdirAll(gradle, 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(gradle, "test-file"), []byte{}, 0644)).To(Succeed())
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	context("SeedFiles", func() {
+		it.Before(func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "*.jar"}},
+				},
+			}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+		})
+
+		it("writes seed files before the build", func() {
+			application.SeedFiles = map[string][]byte{
+				"test-seed":                          []byte("test-content-1"),
+				filepath.Join("config", "test-seed"): []byte("test-content-2"),
+			}
+
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				dir := args.Get(0).(effect.Execution).Dir
+				Expect(os.ReadFile(filepath.Join(dir, "test-seed"))).To(Equal([]byte("test-content-1")))
+				Expect(os.ReadFile(filepath.Join(dir, "config", "test-seed"))).To(Equal([]byte("test-content-2")))
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+			Expect(filepath.Join(ctx.Application.Path, "test-seed")).NotTo(BeAnExistingFile())
+		})
+
+		it("rejects seed files outside of the application path", func() {
+			application.SeedFiles = map[string][]byte{
+				filepath.Join("..", "test-seed"): []byte("test-content"),
+			}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("is outside of")))
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		it("writes seed files whose names start with dots", func() {
+			application.SeedFiles = map[string][]byte{
+				filepath.Join("..cache", "test-seed"): []byte("test-content"),
+			}
+
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				dir := args.Get(0).(effect.Execution).Dir
+				Expect(os.ReadFile(filepath.Join(dir, "..cache", "test-seed"))).To(Equal([]byte("test-content")))
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+		})
+	})
+
+	context("resolved artifacts", func() {
+		it("records resolved artifacts in the layer metadata", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			sum := sha256.Sum256(b)
+			Expect(layer.Metadata[libbs.ResolvedArtifactsMetadataKey]).To(Equal([]libbs.ResolvedArtifact{
+				{
+					Name:   "stub-application.jar",
+					Path:   "application.zip",
+					Size:   int64(len(b)),
+					SHA256: hex.EncodeToString(sum[:]),
+				},
+			}))
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+			Expect(layer.Metadata).To(HaveKey(libbs.ResolvedArtifactsMetadataKey))
+		})
+	})
+
+	context("RebuildDecider", func() {
+		var decided []map[string]interface{}
+
+		it.Before(func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			decided = nil
+			application.LayerContributor.ExpectedMetadata = map[string]interface{}{"test-key": "test-value"}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it("forces a rebuild when the decider returns true", func() {
+			application.RebuildDecider = func(previous map[string]interface{}) (bool, error) {
+				decided = append(decided, previous)
+				return true, nil
+			}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(decided).To(BeEmpty())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNumberOfCalls(t, "Execute", 2)
+			Expect(decided).To(Equal([]map[string]interface{}{{"test-key": "test-value"}}))
+		})
+
+		it("reuses the cached layer when the decider returns false", func() {
+			application.RebuildDecider = func(previous map[string]interface{}) (bool, error) {
+				decided = append(decided, previous)
+				return false, nil
+			}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+			Expect(decided).To(HaveLen(1))
+		})
+
+		it("fails when the decider fails", func() {
+			application.RebuildDecider = func(map[string]interface{}) (bool, error) {
+				return false, fmt.Errorf("test-error")
+			}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError("unable to decide whether to rebuild\ntest-error"))
+		})
+	})
+
+	context("symlinks", func() {
+		it("preserves relative symlinks in a directory artifact", func() {
+			lib := filepath.Join(ctx.Application.Path, "target", "lib")
+			Expect(os.MkdirAll(lib, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(lib, "libtest.so.1"), []byte("test-library"), 0644)).To(Succeed())
+			Expect(os.Symlink("libtest.so.1", filepath.Join(lib, "libtest.so"))).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "target/lib"}},
+				},
+			}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, dir := range []string{filepath.Join(layer.Path, "lib"), filepath.Join(ctx.Application.Path, "lib")} {
+				fileInfo, err := os.Lstat(filepath.Join(dir, "libtest.so"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fileInfo.Mode() & os.ModeSymlink).To(Equal(os.ModeSymlink))
+				Expect(os.Readlink(filepath.Join(dir, "libtest.so"))).To(Equal("libtest.so.1"))
+				Expect(os.ReadFile(filepath.Join(dir, "libtest.so"))).To(Equal([]byte("test-library")))
+			}
+		})
+	})
+
+	context("ArtifactMode", func() {
+		it.Before(func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target", "app"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "app", "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		context("directory", func() {
+			it.Before(func() {
+				application.ArtifactMode = libbs.ArtifactModeDirectory
+			})
+
+			it("restores a directory artifact", func() {
+				application.ArtifactResolver = libbs.ArtifactResolver{
+					ConfigurationResolver: libpak.ConfigurationResolver{
+						Configurations: []libpak.BuildpackConfiguration{{Default: "target/app"}},
+					},
+				}
+
+				layer, err := ctx.Layers.Layer("test-layer")
+				Expect(err).NotTo(HaveOccurred())
+
+				layer, err = application.Contribute(layer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layer.Path, "application.zip")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(ctx.Application.Path, "app", "stub-application.jar")).To(BeARegularFile())
+				Expect(filepath.Join(ctx.Application.Path, "target")).NotTo(BeAnExistingFile())
+			})
+
+			it("does not create application.zip for a single file artifact", func() {
+				application.ArtifactResolver = libbs.ArtifactResolver{
+					ConfigurationResolver: libpak.ConfigurationResolver{
+						Configurations: []libpak.BuildpackConfiguration{{Default: "target/app/*.jar"}},
+					},
+				}
+
+				layer, err := ctx.Layers.Layer("test-layer")
+				Expect(err).NotTo(HaveOccurred())
+
+				layer, err = application.Contribute(layer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layer.Path, "application.zip")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(ctx.Application.Path, "stub-application.jar")).To(BeARegularFile())
+				Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).NotTo(BeAnExistingFile())
+			})
+
+			context("ExplodeArtifact", func() {
+				it.Before(func() {
+					application.ExplodeArtifact = true
+					application.ArtifactResolver = libbs.ArtifactResolver{
+						ConfigurationResolver: libpak.ConfigurationResolver{
+							Configurations: []libpak.BuildpackConfiguration{{Default: "target/app/*.jar"}},
+						},
+					}
+				})
+
+				it("extracts an executable jar", func() {
+					Expect(os.Remove(filepath.Join(ctx.Application.Path, "target", "app", "stub-application.jar"))).To(Succeed())
+
+					out, err := os.Create(filepath.Join(ctx.Application.Path, "target", "app", "test-application.jar"))
+					Expect(err).NotTo(HaveOccurred())
+					z := zip.NewWriter(out)
+					for name, content := range map[string]string{
+						"META-INF/MANIFEST.MF":          "Main-Class: org.springframework.boot.loader.JarLauncher\n",
+						"BOOT-INF/classes/Main.class":   "test-class",
+						"BOOT-INF/lib/test-dep-1.0.jar": "test-dep",
+					} {
+						w, err := z.Create(name)
+						Expect(err).NotTo(HaveOccurred())
+						_, err = w.Write([]byte(content))
+						Expect(err).NotTo(HaveOccurred())
+					}
+					Expect(z.Close()).To(Succeed())
+					Expect(out.Close()).To(Succeed())
+
+					layer, err := ctx.Layers.Layer("test-layer")
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = application.Contribute(layer)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(filepath.Join(ctx.Application.Path, "BOOT-INF", "classes", "Main.class")).To(BeARegularFile())
+					Expect(filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "test-dep-1.0.jar")).To(BeARegularFile())
+					Expect(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF")).To(BeARegularFile())
+					Expect(filepath.Join(ctx.Application.Path, "test-application.jar")).NotTo(BeAnExistingFile())
+				})
+
+				it("does not extract a plain jar", func() {
+					Expect(os.Remove(filepath.Join(ctx.Application.Path, "target", "app", "stub-application.jar"))).To(Succeed())
+
+					out, err := os.Create(filepath.Join(ctx.Application.Path, "target", "app", "test-library.jar"))
+					Expect(err).NotTo(HaveOccurred())
+					z := zip.NewWriter(out)
+					_, err = z.Create("test/Library.class")
+					Expect(err).NotTo(HaveOccurred())
+					Expect(z.Close()).To(Succeed())
+					Expect(out.Close()).To(Succeed())
+
+					layer, err := ctx.Layers.Layer("test-layer")
+					Expect(err).NotTo(HaveOccurred())
+
+					_, err = application.Contribute(layer)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(filepath.Join(ctx.Application.Path, "test-library.jar")).To(BeARegularFile())
+					Expect(filepath.Join(ctx.Application.Path, "test")).NotTo(BeAnExistingFile())
+				})
+			})
+		})
+
+		context("file", func() {
+			it.Before(func() {
+				application.ArtifactMode = libbs.ArtifactModeFile
+			})
+
+			it("fails for a directory artifact", func() {
+				application.ArtifactResolver = libbs.ArtifactResolver{
+					ConfigurationResolver: libpak.ConfigurationResolver{
+						Configurations: []libpak.BuildpackConfiguration{{Default: "target/app"}},
+					},
+				}
+
+				layer, err := ctx.Layers.Layer("test-layer")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = application.Contribute(layer)
+				Expect(err).To(MatchError(ContainSubstring("artifact mode file requires a file artifact")))
+			})
+		})
+	})
+
+	context("BuildScanPattern", func() {
+		it("logs a summary of build scans", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			out := &bytes.Buffer{}
+			application.Logger = bard.NewLogger(out)
+			application.BuildScanPattern = libbs.GradleBuildScanPattern
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				e := args.Get(0).(effect.Execution)
+				_, err := e.Stdout.Write([]byte("Publishing build scan...\nhttps://gradle.com/s/abcdef123\n"))
+				Expect(err).NotTo(HaveOccurred())
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out.String()).To(MatchRegexp(`Build scans\n.*https://gradle\.com/s/abcdef123`))
+		})
+	})
+
+	context("MergeOutput", func() {
+		it("does not interleave stdout and stderr mid-line", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			out := &bytes.Buffer{}
+			application.Logger = bard.NewLogger(out)
+			application.MergeOutput = true
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				e := args.Get(0).(effect.Execution)
+
+				wg := sync.WaitGroup{}
+				for _, w := range []struct {
+					name   string
+					writer io.Writer
+				}{{"stdout", e.Stdout}, {"stderr", e.Stderr}} {
+					wg.Add(1)
+					go func(name string, writer io.Writer) {
+						defer wg.Done()
+						for i := 0; i < 100; i++ {
+							for _, s := range []string{name, "-", "line", "\n"} {
+								_, _ = writer.Write([]byte(s))
+							}
+						}
+					}(w.name, w.writer)
+				}
+				wg.Wait()
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			count := 0
+			for _, line := range strings.Split(out.String(), "\n") {
+				if strings.Contains(line, "-line") {
+					Expect(line).To(Or(Equal("      stdout-line"), Equal("      stderr-line")))
+					count++
+				}
+			}
+			Expect(count).To(Equal(200))
+		})
+	})
+
+	context("BP_BUILD_ARTIFACT_STRIP", func() {
+		it.Before(func() {
+			out, err := os.Create(filepath.Join(ctx.Application.Path, "test-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+
+			z := zip.NewWriter(out)
+			for name, content := range map[string]string{
+				"META-INF/MANIFEST.MF":           "Main-Class: test.Main\n",
+				"BOOT-INF/classes/Main.class":    "test-class",
+				"BOOT-INF/lib/test-dep-1.0.jar":  "test-dep",
+				"BOOT-INF/lib/other-dep-1.0.jar": "other-dep",
+				"cache/test-file":                "test-cache",
+			} {
+				w, err := z.Create(name)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = w.Write([]byte(content))
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(z.Close()).To(Succeed())
+			Expect(out.Close()).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{
+						{Default: "*.jar"},
+						{Name: "BP_BUILD_ARTIFACT_STRIP", Default: "BOOT-INF/lib/test-* cache"},
+					},
+				},
+			}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it("strips matching entries from the artifact", func() {
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(libbs.JARInterestingFileDetector{}.Interesting(filepath.Join(layer.Path, "application.zip"))).To(BeTrue())
+
+			Expect(filepath.Join(ctx.Application.Path, "META-INF", "MANIFEST.MF")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "BOOT-INF", "classes", "Main.class")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "other-dep-1.0.jar")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "BOOT-INF", "lib", "test-dep-1.0.jar")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "cache")).NotTo(BeAnExistingFile())
+		})
+
+		it("keeps the mode and launch script of a fully executable JAR", func() {
+			script := "#!/bin/sh\nexec java -jar \"$0\" \"$@\"\n"
+			b := bytes.NewBufferString(script)
+			z := zip.NewWriter(b)
+			for name, content := range map[string]string{
+				"META-INF/MANIFEST.MF":          "Main-Class: test.Main\n",
+				"BOOT-INF/lib/test-dep-1.0.jar": "test-dep",
+			} {
+				w, err := z.Create(name)
+				Expect(err).NotTo(HaveOccurred())
+				_, err = w.Write([]byte(content))
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(z.Close()).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "test-application.jar"), b.Bytes(), 0755)).To(Succeed())
+			Expect(os.Chmod(filepath.Join(ctx.Application.Path, "test-application.jar"), 0755)).To(Succeed())
+
+			copier := &recordingCopier{}
+			application.Copier = copier
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			artifact := filepath.Join(layer.Path, "test-application.jar")
+			Expect(copier.copies[0].To).To(Equal(artifact))
+
+			fileInfo, err := os.Stat(artifact)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fileInfo.Mode().Perm()).To(Equal(os.FileMode(0755)))
+
+			content, err := os.ReadFile(artifact)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(content)).To(HavePrefix(script))
+
+			in, err := zip.OpenReader(artifact)
+			Expect(err).NotTo(HaveOccurred())
+			defer in.Close()
+			Expect(in.File).To(HaveLen(1))
+			Expect(in.File[0].Name).To(Equal("META-INF/MANIFEST.MF"))
+			r, err := in.File[0].Open()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(io.ReadAll(r)).To(Equal([]byte("Main-Class: test.Main\n")))
+		})
+	})
+
+	context("NormalizeLineEndings", func() {
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target", "config"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "config", "application.properties"),
+				[]byte("key-1=value-1\r\nkey-2=value-2\r\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "config", "test.properties"),
+				[]byte("\x00binary\r\n"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "config", "test.bat"),
+				[]byte("echo test\r\n"), 0644)).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "target/config"}},
+				},
+			}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it("normalizes text files and leaves other files untouched", func() {
+			application.NormalizeLineEndings = true
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "config", "application.properties"))).
+				To(Equal([]byte("key-1=value-1\nkey-2=value-2\n")))
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "config", "test.properties"))).
+				To(Equal([]byte("\x00binary\r\n")))
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "config", "test.bat"))).
+				To(Equal([]byte("echo test\r\n")))
+		})
+
+		it("does not normalize by default", func() {
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "config", "application.properties"))).
+				To(Equal([]byte("key-1=value-1\r\nkey-2=value-2\r\n")))
+		})
+	})
+
+	context("BuildLogPath", func() {
+		it.Before(func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			application.BuildLogPath = filepath.Join(ctx.Layers.Path, "logs", "build.log")
+		})
+
+		it("writes build output to the file", func() {
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				_, _ = args.Get(0).(effect.Execution).Stdout.Write([]byte("test-output\n"))
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.ReadFile(application.BuildLogPath)).To(Equal([]byte("test-output\n")))
+		})
+
+		it("writes build output to the file when the build fails", func() {
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				_, _ = args.Get(0).(effect.Execution).Stderr.Write([]byte("test-error\n"))
+			}).Return(fmt.Errorf("test-failure"))
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(HaveOccurred())
+
+			Expect(os.ReadFile(application.BuildLogPath)).To(Equal([]byte("test-error\n")))
+		})
+
+		it("caps the file at BuildLogMaxSize", func() {
+			application.BuildLogMaxSize = 16
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				e := args.Get(0).(effect.Execution)
+				for i := 0; i < 100; i++ {
+					_, _ = e.Stdout.Write([]byte("test-output\n"))
+				}
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.ReadFile(application.BuildLogPath)).
+				To(Equal([]byte("test-output\ntest\n[build log truncated after 16 bytes]\n")))
+		})
+
+		context("LogToFile", func() {
+			it.Before(func() {
+				application.BuildLogPath = ""
+				application.LogToFile = true
+			})
+
+			it("writes build output to the file in the layer", func() {
+				executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+					_, _ = args.Get(0).(effect.Execution).Stdout.Write([]byte("test-output\n"))
+				}).Return(nil)
+
+				layer, err := ctx.Layers.Layer("test-layer")
+				Expect(err).NotTo(HaveOccurred())
+
+				layer, err = application.Contribute(layer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(os.ReadFile(filepath.Join(layer.Path, libbs.BuildLogFileName))).To(Equal([]byte("test-output\n")))
+			})
+
+			it("writes build output to the file in the layer when the build fails", func() {
+				executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+					_, _ = args.Get(0).(effect.Execution).Stderr.Write([]byte("test-error\n"))
+				}).Return(fmt.Errorf("test-failure"))
+
+				layer, err := ctx.Layers.Layer("test-layer")
+				Expect(err).NotTo(HaveOccurred())
+
+				_, err = application.Contribute(layer)
+				Expect(err).To(HaveOccurred())
+
+				Expect(os.ReadFile(filepath.Join(layer.Path, libbs.BuildLogFileName))).To(Equal([]byte("test-error\n")))
+			})
+
+			it("does not restore the file with multiple artifacts", func() {
+				application.ArtifactMode = libbs.ArtifactModeDirectory
+				executor.On("Execute", mock.Anything).Return(nil)
+
+				layer, err := ctx.Layers.Layer("test-layer")
+				Expect(err).NotTo(HaveOccurred())
+
+				layer, err = application.Contribute(layer)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(filepath.Join(layer.Path, libbs.BuildLogFileName)).To(BeARegularFile())
+				Expect(filepath.Join(ctx.Application.Path, libbs.BuildLogFileName)).NotTo(BeAnExistingFile())
+			})
+		})
+	})
+
+	context("ContributeWithOutput", func() {
+		it.Before(func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+		})
+
+		it("returns the combined build output", func() {
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				e := args.Get(0).(effect.Execution)
+				_, _ = e.Stdout.Write([]byte("test-output\n"))
+				_, _ = e.Stderr.Write([]byte("test-error\n"))
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, output, err := application.ContributeWithOutput(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(output).To(Equal("test-output\ntest-error\n"))
+			Expect(filepath.Join(layer.Path, "application.zip")).To(BeARegularFile())
+		})
+
+		it("returns the build output when the build fails", func() {
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				_, _ = args.Get(0).(effect.Execution).Stderr.Write([]byte("test-error\n"))
+			}).Return(fmt.Errorf("test-failure"))
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, output, err := application.ContributeWithOutput(layer)
+			Expect(err).To(HaveOccurred())
+
+			Expect(output).To(Equal("test-error\n"))
+		})
+
+		it("caps the output at CapturedOutputMaxSize", func() {
+			application.CapturedOutputMaxSize = 16
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				e := args.Get(0).(effect.Execution)
+				for i := 0; i < 100; i++ {
+					_, _ = e.Stdout.Write([]byte("test-output\n"))
+				}
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, output, err := application.ContributeWithOutput(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(output).To(Equal("test-output\ntest\n[build log truncated after 16 bytes]\n"))
+		})
+
+		it("returns no output when the layer is restored from cache", func() {
+			application.LayerContributor.ExpectedMetadata = map[string]interface{}{"test-key": "test-value"}
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				_, _ = args.Get(0).(effect.Execution).Stdout.Write([]byte("test-output\n"))
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			_, output, err := application.ContributeWithOutput(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(output).To(BeEmpty())
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+		})
+	})
+
+	context("BP_BUILD_VERIFY_WRAPPER", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_BUILD_VERIFY_WRAPPER", "true")).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "gradle", "wrapper"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "gradle", "wrapper", "gradle-wrapper.properties"),
+				[]byte("distributionSha256Sum=test-checksum\n"), 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_BUILD_VERIFY_WRAPPER")).To(Succeed())
+		})
+
+		it("fails before building with an invalid wrapper", func() {
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("unable to verify build tool wrapper\ndistributionSha256Sum test-checksum")))
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		it("verifies the wrapper JAR against the configured checksums", func() {
+			Expect(os.Setenv("BP_BUILD_WRAPPER_SHA256", strings.Repeat("0", 64))).To(Succeed())
+			defer os.Unsetenv("BP_BUILD_WRAPPER_SHA256")
+
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "gradle", "wrapper", "gradle-wrapper.properties"),
+				[]byte(fmt.Sprintf("distributionSha256Sum=%s\n", strings.Repeat("0", 64))), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "gradle", "wrapper", "gradle-wrapper.jar"),
+				[]byte("test-wrapper"), 0644)).To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("SHA256 of gradle/wrapper/gradle-wrapper.jar is")))
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+	})
+
+	context("Environment", func() {
+		it.Before(func() {
+			Expect(os.Setenv("TEST_INHERITED", "test-inherited")).To(Succeed())
+			Expect(os.Setenv("TEST_OPTS", "test-buildpack")).To(Succeed())
+
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("TEST_INHERITED")).To(Succeed())
+			Expect(os.Unsetenv("TEST_OPTS")).To(Succeed())
+		})
+
+		it("inherits the environment without an Environment", func() {
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(executor.Calls[0].Arguments[0].(effect.Execution).Env).To(BeNil())
+		})
+
+		it("runs the build with the merged environment", func() {
+			application.Environment = map[string]string{"TEST_OPTS": "test-build", "TEST_ADDED": "test-added"}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			env := executor.Calls[0].Arguments[0].(effect.Execution).Env
+			Expect(env).To(ContainElements("TEST_INHERITED=test-inherited", "TEST_OPTS=test-build", "TEST_ADDED=test-added"))
+			Expect(env).NotTo(ContainElement("TEST_OPTS=test-buildpack"))
+		})
+	})
+
+	context("BP_BUILD_FORBID_SNAPSHOTS", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_BUILD_FORBID_SNAPSHOTS", "true")).To(Succeed())
+
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(cache.Path, "test-file-1.1.1.jar"), []byte{}, 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_BUILD_FORBID_SNAPSHOTS")).To(Succeed())
+		})
+
+		it("contributes layer without SNAPSHOT dependencies", func() {
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("fails with SNAPSHOT dependencies", func() {
+			Expect(os.MkdirAll(filepath.Join(cache.Path, "test", "other-file", "2.0-SNAPSHOT"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(cache.Path, "test", "other-file", "2.0-SNAPSHOT", "other-file-2.0-SNAPSHOT.jar"),
+				[]byte{}, 0644)).To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("other-file:2.0-SNAPSHOT")))
+			Expect(err).NotTo(MatchError(ContainSubstring("test-file")))
+		})
+	})
+
+	context("read-only source", func() {
+		it("removes read-only files and directories", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "read-only", "nested"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "read-only", "nested", "test-file"), []byte{}, 0444)).
+				To(Succeed())
+			Expect(os.Chmod(filepath.Join(ctx.Application.Path, "read-only", "nested"), 0555)).To(Succeed())
+			Expect(os.Chmod(filepath.Join(ctx.Application.Path, "read-only"), 0555)).To(Succeed())
+
+			application.ArtifactResolver.ConfigurationResolver.Configurations = []libpak.BuildpackConfiguration{{Default: "*.jar"}}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "read-only")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).To(BeARegularFile())
+		})
+	})
+
+	context("read-only application path", func() {
+		var restorePath string
+
+		it.Before(func() {
+			var err error
+			restorePath, err = ioutil.TempDir("", "application-restore")
+			Expect(err).NotTo(HaveOccurred())
+
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.ReadOnlyDetector = func(path string) (bool, error) {
+				return path == ctx.Application.Path, nil
+			}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(restorePath)).To(Succeed())
+		})
+
+		it("restores the artifacts to the restore path without removing source code", func() {
+			application.RestorePath = restorePath
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, "application.zip")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "stub-application.jar")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(restorePath, "fixture-marker")).To(BeARegularFile())
+		})
+
+		it("skips the purge and restore with a warning without a restore path", func() {
+			out := &bytes.Buffer{}
+			application.Logger = bard.NewLogger(out)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, "application.zip")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "stub-application.jar")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).NotTo(BeAnExistingFile())
+			Expect(out.String()).To(ContainSubstring(fmt.Sprintf("Application path %s is read-only, not removing source code",
+				ctx.Application.Path)))
+		})
+
+		it("restores to the restore path from a writable application path", func() {
+			application.ReadOnlyDetector = nil
+			application.RestorePath = restorePath
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "stub-application.jar")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(restorePath, "fixture-marker")).To(BeARegularFile())
+		})
+
+		it("fails when the detector fails", func() {
+			application.ReadOnlyDetector = func(string) (bool, error) {
+				return false, fmt.Errorf("test-error")
+			}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(fmt.Sprintf("unable to determine whether %s is read-only\ntest-error", ctx.Application.Path)))
+		})
+	})
+
+	context("RecordClassPath", func() {
+		it("records the resolved Class-Path of a plain jar", func() {
+			out, err := os.Create(filepath.Join(ctx.Application.Path, "test-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			z := zip.NewWriter(out)
+			w, err := z.Create("META-INF/MANIFEST.MF")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = w.Write([]byte("Manifest-Version: 1.0\r\nClass-Path: lib/test-dep-1.0.jar lib/other-d\r\n ep-1.0.jar lib/third%20dep.jar\r\n\r\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(z.Close()).To(Succeed())
+			Expect(out.Close()).To(Succeed())
+
+			application.ArtifactMode = libbs.ArtifactModeDirectory
+			application.RecordClassPath = true
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			resolved := layer.Metadata[libbs.ResolvedArtifactsMetadataKey].([]libbs.ResolvedArtifact)
+			Expect(resolved).To(HaveLen(1))
+			Expect(resolved[0].ClassPath).To(Equal([]string{
+				filepath.Join(ctx.Application.Path, "lib", "test-dep-1.0.jar"),
+				filepath.Join(ctx.Application.Path, "lib", "other-dep-1.0.jar"),
+				filepath.Join(ctx.Application.Path, "lib", "third dep.jar"),
+			}))
+		})
+
+		it("does not record the Class-Path of an executable jar", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-executable.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-executable.jar"), b, 0644)).To(Succeed())
+
+			application.RecordClassPath = true
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			resolved := layer.Metadata[libbs.ResolvedArtifactsMetadataKey].([]libbs.ResolvedArtifact)
+			Expect(resolved[0].ClassPath).To(BeEmpty())
+		})
+	})
+
+	context("BP_BUILD_MEMORY_LIMIT", func() {
+		var cgroupPath string
+
+		it.Before(func() {
+			if runtime.GOOS != "linux" {
+				t.Skip("memory limits are only supported on Linux")
+			}
+
+			var err error
+			cgroupPath, err = ioutil.TempDir("", "application-cgroup")
+			Expect(err).NotTo(HaveOccurred())
+
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			Expect(os.Setenv("BP_BUILD_MEMORY_LIMIT", "512M")).To(Succeed())
+			application.CgroupPath = cgroupPath
+			application.Logger = bard.NewLogger(ioutil.Discard)
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_BUILD_MEMORY_LIMIT")).To(Succeed())
+			Expect(os.RemoveAll(cgroupPath)).To(Succeed())
+		})
+
+		it("runs the build in a memory limited cgroup", func() {
+			cgroup := filepath.Join(cgroupPath, fmt.Sprintf("libbs-build-%d", os.Getpid()))
+			executor.On("Execute", mock.Anything).Run(func(mock.Arguments) {
+				Expect(os.ReadFile(filepath.Join(cgroup, "memory.max"))).To(Equal([]byte("536870912")))
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Command).To(Equal("sh"))
+			Expect(e.Args[2:]).To(Equal([]string{filepath.Join(cgroup, "cgroup.procs"), "test-command", "test-argument"}))
+			Expect(cgroup).NotTo(BeAnExistingFile())
+		})
+
+		it("reports a build killed by the cgroup for running out of memory", func() {
+			cgroup := filepath.Join(cgroupPath, fmt.Sprintf("libbs-build-%d", os.Getpid()))
+			executor.On("Execute", mock.Anything).Run(func(mock.Arguments) {
+				Expect(os.WriteFile(filepath.Join(cgroup, "memory.events"), []byte("oom 1\noom_kill 1\n"), 0644)).To(Succeed())
+			}).Return(fmt.Errorf("test-error"))
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("build was killed, likely for running out of memory, increase the " +
+				"memory available to the build, e.g. with $BP_BUILD_MEMORY_LIMIT, or reduce its parallelism\ntest-error")))
+		})
+
+		it("reports a build killed with SIGKILL with a limit", func() {
+			killed := exec.Command("sh", "-c", "kill -9 $$").Run()
+			Expect(killed).To(HaveOccurred())
+			executor.On("Execute", mock.Anything).Return(killed)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("build was killed, likely for running out of memory")))
+		})
+
+		it("does not report a build killed with SIGKILL without a limit as running out of memory", func() {
+			Expect(os.Unsetenv("BP_BUILD_MEMORY_LIMIT")).To(Succeed())
+			killed := exec.Command("sh", "-c", "kill -9 $$").Run()
+			Expect(killed).To(HaveOccurred())
+			executor.On("Execute", mock.Anything).Return(killed)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("error running build\nsignal: killed")))
+			Expect(executor.Calls[0].Arguments[0].(effect.Execution).Command).To(Equal("test-command"))
+		})
+
+		it("reports a build that exited with 137", func() {
+			exited := exec.Command("sh", "-c", "exit 137").Run()
+			Expect(exited).To(HaveOccurred())
+			executor.On("Execute", mock.Anything).Return(exited)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("build was killed, likely for running out of memory")))
+		})
+
+		it("fails with an unparseable limit", func() {
+			Expect(os.Setenv("BP_BUILD_MEMORY_LIMIT", "lots")).To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("unable to parse BP_BUILD_MEMORY_LIMIT lots")))
+		})
+
+		it("fails with a limit that overflows", func() {
+			Expect(os.Setenv("BP_BUILD_MEMORY_LIMIT", "9999999999G")).To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("unable to parse BP_BUILD_MEMORY_LIMIT 9999999999G")))
+		})
+
+		it("applies the limit to the build", func() {
+			application.CgroupPath = ""
+			if b, err := os.ReadFile("/proc/self/cgroup"); err != nil || !strings.HasPrefix(string(b), "0::") {
+				t.Skip("cgroup v2 is not available")
+			}
+
+			out := &bytes.Buffer{}
+			application.Logger = bard.NewLogger(out)
+			application.Executor = effect.NewExecutor()
+			application.Command = "cat"
+			application.Arguments = []string{"/proc/self/cgroup"}
+			application.BuildLogPath = filepath.Join(ctx.Layers.Path, "build.log")
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			if !strings.Contains(out.String(), "Limiting build memory to 512M") {
+				t.Skip("cgroup cannot be created")
+			}
+			Expect(os.ReadFile(application.BuildLogPath)).To(ContainSubstring(fmt.Sprintf("libbs-build-%d", os.Getpid())))
+		})
+	})
+
+	context("Nice", func() {
+		it.Before(func() {
+			if runtime.GOOS != "linux" {
+				t.Skip("niceness is only supported on Linux")
+			}
+
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.Nice = 5
+		})
+
+		it("runs the build command itself", func() {
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Command).To(Equal("test-command"))
+			Expect(e.Args).To(Equal([]string{"test-argument"}))
+		})
+
+		it("warns when the niceness cannot be applied", func() {
+			if os.Geteuid() == 0 {
+				t.Skip("the niceness can always be lowered with privileges")
+			}
+
+			out := &bytes.Buffer{}
+			application.Logger = bard.NewLogger(out)
+			application.Nice = -5
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(out.String()).To(ContainSubstring("Ignoring niceness -5, unable to set niceness"))
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+		})
+
+		it("applies the niceness to the build", func() {
+			if _, err := exec.LookPath("nice"); err != nil {
+				t.Skip("nice is not available")
+			}
+
+			base := &bytes.Buffer{}
+			Expect(effect.NewExecutor().Execute(effect.Execution{Command: "nice", Stdout: base})).To(Succeed())
+			niceness, err := strconv.Atoi(strings.TrimSpace(base.String()))
+			Expect(err).NotTo(HaveOccurred())
+
+			out := &bytes.Buffer{}
+			application.Logger = bard.NewLogger(out)
+			application.Executor = effect.NewExecutor()
+			application.Command = "nice"
+			application.Arguments = nil
+			application.BuildLogPath = filepath.Join(ctx.Layers.Path, "build.log")
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			b, err := os.ReadFile(application.BuildLogPath)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(strings.TrimSpace(string(b))).To(Equal(strconv.Itoa(min(niceness+5, 19))))
+		})
+	})
+
+	context("git submodule source", func() {
+		it("removes submodule directories", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "lib", "src"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "lib", ".git"), []byte("gitdir: ../.git/modules/lib\n"), 0644)).
+				To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "lib", "src", "Lib.java"), []byte{}, 0644)).To(Succeed())
+
+			application.ArtifactResolver.ConfigurationResolver.Configurations = []libpak.BuildpackConfiguration{{Default: "*.jar"}}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "lib")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).To(BeARegularFile())
+		})
+	})
+
+	context("VerifyRestore", func() {
+		it.Before(func() {
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it("passes when a file is restored", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.VerifyRestore = true
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("fails when no file is restored", func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target", "empty"), 0755)).To(Succeed())
+
+			application.ArtifactResolver.ConfigurationResolver.Configurations = []libpak.BuildpackConfiguration{{Default: "target/empty"}}
+			application.VerifyRestore = true
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(HavePrefix("no files found in")))
+		})
+
+		it("passes when the pattern matches", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.VerifyRestorePattern = "META-INF/MANIFEST.MF"
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("fails when the pattern does not match", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.VerifyRestorePattern = "BOOT-INF/lib/*.jar"
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(HavePrefix("no files matching BOOT-INF/lib/*.jar found in")))
+		})
+	})
+
+	context("EmptyCachePolicy", func() {
+		it.Before(func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+		})
+
+		it("fails if a clean build leaves the cache empty", func() {
+			application.EmptyCachePolicy = libbs.EmptyCacheFail
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("clean build left cache %s empty", cache.Path))))
+		})
+
+		it("warns if a clean build leaves the cache empty", func() {
+			out := &bytes.Buffer{}
+			application.Logger = bard.NewLogger(out)
+			application.EmptyCachePolicy = libbs.EmptyCacheWarn
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out.String()).To(ContainSubstring("no dependencies were resolved by a clean build"))
+		})
+
+		it("passes if a clean build populates the cache", func() {
+			application.EmptyCachePolicy = libbs.EmptyCacheFail
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				Expect(ioutil.WriteFile(filepath.Join(cache.Path, "test-file-1.1.1.jar"), []byte{}, 0644)).To(Succeed())
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("ignores the cache by default", func() {
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	context("custom SBOM scanner", func() {
+		it("scans the application with any BuildSBOMScanner", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			scanner := &fakeSBOMScanner{}
+			application.SBOMScanner = scanner
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(scanner.scanDirs).To(Equal([]string{ctx.Application.Path}))
+			Expect(scanner.formats).To(Equal([][]libcnb.SBOMFormat{{libcnb.CycloneDXJSON, libcnb.SyftJSON}}))
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).To(BeARegularFile())
+		})
+
+		it("fails if the scanner fails", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.SBOMScanner = &fakeSBOMScanner{err: fmt.Errorf("test-error")}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("test-error")))
+		})
+	})
+
+	context("WarningPatterns", func() {
+		it("logs a deduplicated summary of warnings", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			out := &bytes.Buffer{}
+			application.Logger = bard.NewLogger(out)
+			application.WarningPatterns = libbs.DefaultWarningPatterns
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				e := args.Get(0).(effect.Execution)
+				_, _ = e.Stdout.Write([]byte("[INFO] Compiling 3 source files\n[WARNING] test.Main uses a deprecated API\n"))
+				_, _ = e.Stderr.Write([]byte("Warning: test-warning\n"))
+				_, _ = e.Stdout.Write([]byte("[WARNING] test.Main uses a deprecated API\n[INFO] BUILD SUCCESS\n"))
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(strings.Count(out.String(), "[WARNING] test.Main uses a deprecated API")).To(Equal(3))
+			Expect(out.String()).To(ContainSubstring("[INFO] BUILD SUCCESS"))
+			Expect(out.String()).To(MatchRegexp(`Warnings \(2\)\n.*\[WARNING\] test\.Main uses a deprecated API.*\n.*Warning: test-warning`))
+		})
+	})
+
+	context("cache inside the application path", func() {
+		it("unlinks the cache without removing its content", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			cacheLayer := filepath.Join(ctx.Layers.Path, "cache")
+			Expect(os.MkdirAll(filepath.Join(cacheLayer, "repository"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(cacheLayer, "repository", "test-file-1.1.1.jar"), []byte{}, 0644)).To(Succeed())
+			Expect(os.Symlink(cacheLayer, filepath.Join(ctx.Application.Path, ".m2"))).To(Succeed())
+
+			application.Cache.Path = filepath.Join(ctx.Application.Path, ".m2")
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, ".m2")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(cacheLayer, "repository", "test-file-1.1.1.jar")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).To(BeARegularFile())
+		})
+
+		it("excludes the cache from the build SBOM scan", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			cacheLayer := filepath.Join(ctx.Layers.Path, "cache")
+			Expect(os.MkdirAll(filepath.Join(cacheLayer, "repository"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(cacheLayer, "repository", "test-file-1.1.1.jar"), []byte{}, 0644)).To(Succeed())
+			Expect(os.Symlink(cacheLayer, filepath.Join(ctx.Application.Path, ".m2"))).To(Succeed())
+
+			scanner := &fileSBOMScanner{}
+			application.SBOMScanner = scanner
+			application.Cache.Path = filepath.Join(ctx.Application.Path, ".m2")
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(scanner.files).To(ContainElement(filepath.Join(ctx.Application.Path, "stub-application.jar")))
+			Expect(scanner.files).NotTo(ContainElement(ContainSubstring("test-file-1.1.1.jar")))
+			Expect(bom.Entries).To(HaveLen(2))
+			Expect(bom.Entries[0].Metadata["dependencies"]).To(HaveLen(1))
+		})
+	})
+
+	context("DeterministicSBOM", func() {
+		var scanner *shufflingSBOMScanner
+
+		it.Before(func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			scanner = &shufflingSBOMScanner{layers: ctx.Layers}
+			application.SBOMScanner = scanner
+		})
+
+		scan := func() []byte {
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			b, err := os.ReadFile(ctx.Layers.BuildSBOMPath(libcnb.CycloneDXJSON))
+			Expect(err).NotTo(HaveOccurred())
+			return b
+		}
+
+		it("produces identical SBOMs for identical input", func() {
+			application.DeterministicSBOM = true
+
+			first, second := scan(), scan()
+
+			Expect(scanner.calls).To(Equal(2))
+			Expect(second).To(Equal(first))
+			Expect(string(first)).To(Equal(`{"components":[{"name":"test-a","version":"1.1.1"},{"name":"test-b","version":"2.2.2"}],` +
+				`"metadata":{"tools":["test-tool"]},"specVersion":"1.4"}` + "\n"))
+		})
+
+		it("does not normalize SBOMs by default", func() {
+			first, second := scan(), scan()
+
+			Expect(second).NotTo(Equal(first))
+		})
+	})
+
+	context("SBOMParallelism", func() {
+		it.Before(func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_BUILD_SBOM_PARALLELISM")).To(Succeed())
+		})
+
+		it("passes the parallelism to a parallel scanner", func() {
+			scanner := &fakeParallelSBOMScanner{}
+			application.SBOMScanner = scanner
+			application.SBOMParallelism = 2
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(scanner.parallelism).To(Equal([]int{2}))
+			Expect(scanner.scanDirs).To(Equal([]string{ctx.Application.Path}))
+		})
+
+		it("prefers $BP_BUILD_SBOM_PARALLELISM", func() {
+			Expect(os.Setenv("BP_BUILD_SBOM_PARALLELISM", "3")).To(Succeed())
+			scanner := &fakeParallelSBOMScanner{}
+			application.SBOMScanner = scanner
+			application.SBOMParallelism = 2
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(scanner.parallelism).To(Equal([]int{3}))
+		})
+
+		it("bounds other scanners through the environment", func() {
+			Expect(os.Setenv("BP_BUILD_SBOM_PARALLELISM", "4")).To(Succeed())
+			scanner := &envSBOMScanner{}
+			application.SBOMScanner = scanner
+			previous, hasPrevious := os.LookupEnv("GOMAXPROCS")
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(scanner.env).To(Equal(map[string]string{"SYFT_PARALLELISM": "4", "GOMAXPROCS": "4"}))
+			_, ok := os.LookupEnv("SYFT_PARALLELISM")
+			Expect(ok).To(BeFalse())
+			current, ok := os.LookupEnv("GOMAXPROCS")
+			Expect(ok).To(Equal(hasPrevious))
+			Expect(current).To(Equal(previous))
+		})
+
+		it("does not limit the scan by default", func() {
+			scanner := &fakeParallelSBOMScanner{}
+			application.SBOMScanner = scanner
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(scanner.parallelism).To(BeEmpty())
+			Expect(scanner.scanDirs).To(Equal([]string{ctx.Application.Path}))
+		})
+
+		it("fails with an invalid $BP_BUILD_SBOM_PARALLELISM", func() {
+			Expect(os.Setenv("BP_BUILD_SBOM_PARALLELISM", "many")).To(Succeed())
+			application.SBOMScanner = &fakeParallelSBOMScanner{}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("unable to parse BP_BUILD_SBOM_PARALLELISM many")))
+		})
+	})
+
+	context("MetricsSink", func() {
+		it("records metrics for a contribution", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(cache.Path, "test-file-1.1.1.jar"), []byte{}, 0644)).To(Succeed())
+
+			sink := &recordingMetricsSink{}
+			application.MetricsSink = sink
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sink.metrics).To(HaveLen(4))
+			Expect(sink.metrics[0].name).To(Equal(libbs.MetricBuildDuration))
+			Expect(sink.metrics[0].value).To(BeNumerically(">=", 0))
+			Expect(sink.metrics[0].tags).To(Equal(map[string]string{"command": "test-command"}))
+			Expect(sink.metrics[1:]).To(Equal([]metric{
+				{name: libbs.MetricArtifactSize, value: float64(len(b)), tags: map[string]string{"artifact": "stub-application.jar"}},
+				{name: libbs.MetricCacheHit, value: 0},
+				{name: libbs.MetricDependencies, value: 1, tags: map[string]string{"layer": "cache"}},
+			}))
+
+			sink.metrics = nil
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(sink.metrics).To(Equal([]metric{
+				{name: libbs.MetricCacheHit, value: 1},
+				{name: libbs.MetricDependencies, value: 1, tags: map[string]string{"layer": "cache"}},
+			}))
+		})
+	})
+
+	context("compressed tarball artifact", func() {
+		it.Before(func() {
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it("restores a tar.gz artifact", func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "dist"), 0755)).To(Succeed())
+			out, err := os.Create(filepath.Join(ctx.Application.Path, "dist", "test-app.tar.gz"))
+			Expect(err).NotTo(HaveOccurred())
+			gz := gzip.NewWriter(out)
+			tw := tar.NewWriter(gz)
+			Expect(tw.WriteHeader(&tar.Header{Name: "bin/test-app", Mode: 0755, Size: 4, Typeflag: tar.TypeReg})).To(Succeed())
+			_, err = tw.Write([]byte("test"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tw.Close()).To(Succeed())
+			Expect(gz.Close()).To(Succeed())
+			Expect(out.Close()).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "dist/*.tar.gz"}},
+				},
+			}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, "application.zip")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "dist")).NotTo(BeAnExistingFile())
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "bin", "test-app"))).To(Equal([]byte("test")))
+
+			Expect(os.RemoveAll(filepath.Join(ctx.Application.Path, "bin"))).To(Succeed())
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "bin", "test-app"))).To(Equal([]byte("test")))
+		})
+
+		it("restores a gzip compressed tarball by its content", func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "dist"), 0755)).To(Succeed())
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-distribution.tgz"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "dist", "stub-distribution.gz"), b, 0644)).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "dist/*"}},
+				},
+			}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "stub-distribution", "bin", "stub-distribution")).To(BeARegularFile())
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "stub-distribution", "lib", "stub.txt"))).
+				To(Equal([]byte("stub\n")))
+		})
+
+		it("copies a single artifact that is not an archive", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "test-app"), []byte("test"), 0755)).To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, "application.zip")).NotTo(BeAnExistingFile())
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "test-app"))).To(Equal([]byte("test")))
+		})
+
+		it("preserves the mode of a copied artifact", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "test-app"), []byte("test"), 0755)).To(Succeed())
+			Expect(os.Chmod(filepath.Join(ctx.Application.Path, "test-app"), 0755)).To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, file := range []string{filepath.Join(layer.Path, "test-app"), filepath.Join(ctx.Application.Path, "test-app")} {
+				fileInfo, err := os.Stat(file)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fileInfo.Mode().Perm()).To(Equal(os.FileMode(0755)))
+			}
+		})
+	})
+
+	context("PreserveSubdirs", func() {
+		it.Before(func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "stub-application.jar"), b, 0644)).To(Succeed())
+
+			for _, d := range []string{
+				filepath.Join("src", "main", "resources", "static"),
+				filepath.Join("src", "main", "resources", "templates"),
+				filepath.Join("src", "main", "java"),
+				filepath.Join("docs", "site"),
+			} {
+				Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, d), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(ctx.Application.Path, d, "test-file"), []byte{}, 0644)).To(Succeed())
+			}
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "src", "main", "test-file"), []byte{}, 0644)).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "target/*.jar"}},
+				},
+			}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it("keeps preserved subdirectories and removes their siblings", func() {
+			application.PreserveSubdirs = []string{"src/main/resources/static", "docs"}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "src", "main", "resources", "static", "test-file")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "docs", "site", "test-file")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "src", "main", "resources", "templates")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "src", "main", "java")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "src", "main", "test-file")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "target")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).To(BeARegularFile())
+		})
+
+		it("matches globs", func() {
+			application.PreserveSubdirs = []string{"src/*/resources"}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "src", "main", "resources", "static", "test-file")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "src", "main", "resources", "templates", "test-file")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "src", "main", "java")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "docs")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	context("ArtifactPathFile", func() {
+		it.Before(func() {
+			application.ArtifactPathFile = filepath.Join(ctx.Layers.Path, libbs.ArtifactPathFileName)
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it("writes the restore path of an extracted artifact", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.ReadFile(application.ArtifactPathFile)).To(Equal([]byte(ctx.Application.Path + "\n")))
+		})
+
+		it("writes the restored path of each artifact", func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "test-1.txt"), []byte("test-1"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "test-2.txt"), []byte("test-2"), 0644)).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "target/*"}},
+				},
+			}
+			application.ContentAddressable = true
+			application.LayerContributor.ExpectedMetadata = map[string]interface{}{"test-key": "test-value"}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			expected := []byte(fmt.Sprintf("%s\n%s\n",
+				filepath.Join(ctx.Application.Path, "test-1.txt"), filepath.Join(ctx.Application.Path, "test-2.txt")))
+			Expect(os.ReadFile(application.ArtifactPathFile)).To(Equal(expected))
+
+			Expect(os.Remove(application.ArtifactPathFile)).To(Succeed())
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+			Expect(os.ReadFile(application.ArtifactPathFile)).To(Equal(expected))
+			for _, line := range strings.Fields(string(expected)) {
+				Expect(line).To(BeARegularFile())
+			}
+		})
+	})
+
+	context("ArtifactUploader", func() {
+		var uploader *fakeArtifactUploader
+
+		it.Before(func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target", "test-directory"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "test-1.txt"), []byte("test-1"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "test-2.txt"), []byte("test-2"), 0644)).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "target/*"}},
+				},
+			}
+			uploader = &fakeArtifactUploader{}
+			application.ArtifactUploader = uploader
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it("uploads each file artifact", func() {
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(uploader.uploads).To(Equal(map[string][]byte{
+				"test-1.txt": []byte("test-1"),
+				"test-2.txt": []byte("test-2"),
+			}))
+		})
+
+		it("uploads content addressable artifacts with their original names", func() {
+			application.ContentAddressable = true
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(uploader.uploads).To(HaveKeyWithValue("test-1.txt", []byte("test-1")))
+		})
+
+		it("fails if an upload fails", func() {
+			uploader.err = fmt.Errorf("test-error")
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("unable to upload test-1.txt\ntest-error")))
+		})
+	})
+
+	context("VerifyReproducible", func() {
+		var dirs []string
+
+		it.Before(func() {
+			dirs = nil
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "test-source"), []byte("test-source"), 0644)).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "target/*"}},
+				},
+			}
+			application.VerifyReproducible = true
+			application.Logger = bard.NewLogger(ioutil.Discard)
+		})
+
+		build := func(content func(n int) string) *mock.Call {
+			return executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				e := args.Get(0).(effect.Execution)
+				dirs = append(dirs, e.Dir)
+
+				Expect(filepath.Join(e.Dir, "test-source")).To(BeARegularFile())
+				Expect(os.MkdirAll(filepath.Join(e.Dir, "target"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(e.Dir, "target", "test.txt"), []byte(content(len(dirs))), 0644)).To(Succeed())
+			}).Return(nil)
+		}
+
+		it("contributes a deterministic build", func() {
+			build(func(int) string { return "test-artifact" })
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dirs).To(HaveLen(2))
+			Expect(dirs[0]).To(Equal(ctx.Application.Path))
+			Expect(dirs[1]).NotTo(Equal(ctx.Application.Path))
+			Expect(dirs[1]).NotTo(BeAnExistingFile())
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "test.txt"))).To(Equal([]byte("test-artifact")))
+		})
+
+		it("fails a non-deterministic build", func() {
+			build(func(n int) string { return fmt.Sprintf("test-artifact-%d", n) })
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+
+			first, second := sha256.Sum256([]byte("test-artifact-1")), sha256.Sum256([]byte("test-artifact-2"))
+			Expect(err).To(MatchError(ContainSubstring(fmt.Sprintf("build is not reproducible, artifacts differ:\ntarget/test.txt: %s != %s",
+				hex.EncodeToString(first[:]), hex.EncodeToString(second[:])))))
+			Expect(filepath.Join(ctx.Application.Path, "test-source")).To(BeARegularFile())
+		})
+
+		it("fails if the second build produces other artifacts", func() {
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				e := args.Get(0).(effect.Execution)
+				dirs = append(dirs, e.Dir)
+
+				name := "test-1.txt"
+				if len(dirs) > 1 {
+					name = "test-2.txt"
+				}
+				Expect(os.MkdirAll(filepath.Join(e.Dir, "target"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(e.Dir, "target", name), []byte("test-artifact"), 0644)).To(Succeed())
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("artifacts differ:\n" +
+				"target/test-1.txt: missing from the second build\n" +
+				"target/test-2.txt: missing from the first build")))
+		})
+
+		it("retries the second build", func() {
+			application.Retries = 1
+			application.RetryBackoff = time.Millisecond
+			build(func(int) string { return "test-artifact" }).Once()
+			executor.On("Execute", mock.Anything).Return(fmt.Errorf("test-error")).Once()
+			build(func(int) string { return "test-artifact" }).Once()
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNumberOfCalls(t, "Execute", 3)
+		})
+
+		it("applies the Timeout to the second build", func() {
+			application.Timeout = 10 * time.Millisecond
+			application.Executor = funcContextExecutor(func(ctx gocontext.Context, e effect.Execution) error {
+				dirs = append(dirs, e.Dir)
+				if len(dirs) > 1 {
+					<-ctx.Done()
+					return ctx.Err()
+				}
+
+				Expect(os.MkdirAll(filepath.Join(e.Dir, "target"), 0755)).To(Succeed())
+				return os.WriteFile(filepath.Join(e.Dir, "target", "test.txt"), []byte("test-artifact"), 0644)
+			})
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("reproducibility build failed\nbuild timed out after 10ms")))
+			Expect(dirs).To(HaveLen(2))
+		})
+	})
+
+	context("Copier", func() {
+		it("copies the artifacts with the copier", func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "test-1.txt"), []byte("test-1"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "test-2.txt"), []byte("test-2"), 0644)).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "target/*"}},
+				},
+			}
+			copier := &recordingCopier{}
+			application.Copier = copier
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(copier.copies).To(Equal([]copyCall{
+				{From: filepath.Join(ctx.Application.Path, "target", "test-1.txt"), To: filepath.Join(layer.Path, "test-1.txt")},
+				{From: filepath.Join(ctx.Application.Path, "target", "test-2.txt"), To: filepath.Join(layer.Path, "test-2.txt")},
+				{From: layer.Path, To: ctx.Application.Path, Dir: true},
+			}))
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "test-1.txt"))).To(Equal([]byte("test-1")))
+		})
+	})
+
+	context("ContentAddressable", func() {
+		it("persists artifacts by content and restores their names", func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "test-1.jar"), []byte("test-jar"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "test-2.jar"), []byte("test-jar"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "test.txt"), []byte("test-text"), 0644)).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "target/*"}},
+				},
+			}
+			application.ContentAddressable = true
+			application.LayerContributor.ExpectedMetadata = map[string]interface{}{"test-key": "test-value"}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			jar, text := sha256.Sum256([]byte("test-jar")), sha256.Sum256([]byte("test-text"))
+			jarName, textName := hex.EncodeToString(jar[:])+".jar", hex.EncodeToString(text[:])+".txt"
+
+			resolved := layer.Metadata[libbs.ResolvedArtifactsMetadataKey].([]libbs.ResolvedArtifact)
+			Expect(resolved).To(HaveLen(3))
+			Expect(resolved[0].Path).To(Equal(jarName))
+			Expect(resolved[1].Path).To(Equal(jarName))
+			Expect(resolved[2].Path).To(Equal(textName))
+
+			cs, err := os.ReadDir(layer.Path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cs).To(HaveLen(2))
+			Expect(filepath.Join(layer.Path, jarName)).To(BeARegularFile())
+			Expect(filepath.Join(layer.Path, textName)).To(BeARegularFile())
+
+			for _, name := range []string{"test-1.jar", "test-2.jar"} {
+				Expect(os.ReadFile(filepath.Join(ctx.Application.Path, name))).To(Equal([]byte("test-jar")))
+			}
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "test.txt"))).To(Equal([]byte("test-text")))
+			Expect(filepath.Join(ctx.Application.Path, jarName)).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, textName)).NotTo(BeAnExistingFile())
+
+			// metadata read from a restored layer is decoded from TOML
+			var decoded []map[string]interface{}
+			for _, r := range resolved {
+				decoded = append(decoded, map[string]interface{}{"name": r.Name, "path": r.Path, "size": r.Size, "sha256": r.SHA256})
+			}
+			layer.Metadata[libbs.ResolvedArtifactsMetadataKey] = decoded
+			for _, name := range []string{"test-1.jar", "test-2.jar", "test.txt"} {
+				Expect(os.Remove(filepath.Join(ctx.Application.Path, name))).To(Succeed())
+			}
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+			for _, name := range []string{"test-1.jar", "test-2.jar"} {
+				Expect(os.ReadFile(filepath.Join(ctx.Application.Path, name))).To(Equal([]byte("test-jar")))
+			}
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "test.txt"))).To(Equal([]byte("test-text")))
+			Expect(filepath.Join(ctx.Application.Path, jarName)).NotTo(BeAnExistingFile())
+		})
+	})
+
+	context("KeepSources", func() {
+		var jar []byte
+
+		it.Before(func() {
+			var err error
+			jar, err = os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "stub-application.jar"), jar, 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "src"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "src", "Main.java"), []byte{}, 0644)).To(Succeed())
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "target/*.jar"}},
+				},
+			}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_KEEP_SOURCES")).To(Succeed())
+		})
+
+		it("keeps the source code and persists the artifact", func() {
+			application.KeepSources = true
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "src", "Main.java")).To(BeARegularFile())
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "target", "stub-application.jar"))).To(Equal(jar))
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(layer.Path, "application.zip")).To(BeARegularFile())
+		})
+
+		it("keeps the source code when $BP_KEEP_SOURCES is true", func() {
+			Expect(os.Setenv("BP_KEEP_SOURCES", "true")).To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "src", "Main.java")).To(BeARegularFile())
+			Expect(filepath.Join(layer.Path, "application.zip")).To(BeARegularFile())
+		})
+	})
+
+	context("KeepFiles", func() {
+		it.Before(func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "target"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "target", "stub-application.jar"), b, 0644)).To(Succeed())
+
+			for _, f := range []string{
+				filepath.Join("static", "index.html"),
+				filepath.Join("docs", "guide", "index.html"),
+				filepath.Join("docs", "api", "index.html"),
+				filepath.Join("config", "application.yml"),
+				filepath.Join("config", "secret.yml"),
+				filepath.Join("src", "Main.java"),
+			} {
+				Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, filepath.Dir(f)), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(ctx.Application.Path, f), []byte{}, 0644)).To(Succeed())
+			}
+
+			application.ArtifactResolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "target/*.jar"}},
+				},
+			}
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_KEEP_FILES")).To(Succeed())
+		})
+
+		it("keeps files", func() {
+			application.KeepFiles = []string{"static", "config/*.yml"}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "static", "index.html")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "config", "application.yml")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "config", "secret.yml")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "docs")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "src")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "target")).NotTo(BeAnExistingFile())
+		})
+
+		it("combines KeepFiles, the keep file and $BP_KEEP_FILES in order of precedence", func() {
+			application.KeepFiles = []string{"static", "docs"}
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, libbs.KeepFileName),
+				[]byte("# test-comment\n!docs\nconfig/*.yml\n"), 0644)).To(Succeed())
+			Expect(os.Setenv("BP_KEEP_FILES", "docs/api:!config/secret.yml")).To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "static", "index.html")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "docs", "api", "index.html")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "docs", "guide")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "config", "application.yml")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "config", "secret.yml")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, libbs.KeepFileName)).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "src")).NotTo(BeAnExistingFile())
+		})
+
+		it("keeps a directory except for negated files", func() {
+			application.KeepFiles = []string{"config", "!config/secret.yml"}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "config", "application.yml")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "config", "secret.yml")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	context("BP_BUILD_LICENSE_SCAN", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("BP_BUILD_LICENSE_SCAN")).To(Succeed())
+		})
+
+		it("writes the licenses of the build dependencies to the layer", func() {
+			Expect(os.Setenv("BP_BUILD_LICENSE_SCAN", "true")).To(Succeed())
+
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(cache.Path, "test-file-1.1.1.jar"), []byte{}, 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.ReadFile(filepath.Join(layer.Path, libbs.LicenseScanFileName))).To(MatchJSON(`[
+				{
+					"name": "test-file",
+					"version": "1.1.1",
+					"sha256": "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+					"licenses": [],
+					"license-files": []
+				}
+			]`))
+			Expect(filepath.Join(ctx.Application.Path, libbs.LicenseScanFileName)).NotTo(BeAnExistingFile())
+		})
+
+		it("does not scan licenses by default", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, libbs.LicenseScanFileName)).NotTo(BeAnExistingFile())
+		})
+	})
+
+	context("Timeout", func() {
+		it.Before(func() {
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			application.Timeout = 10 * time.Millisecond
+		})
+
+		it("fails when the build does not finish in time", func() {
+			application.Executor = blockingContextExecutor{}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("build timed out after 10ms")))
+			Expect(err).To(MatchError(ContainSubstring("context deadline exceeded")))
+			Expect(layer.Metadata).To(BeEmpty())
+		})
+
+		it("succeeds when the build finishes in time", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), []byte{}, 0644)).To(Succeed())
+			application.Timeout = time.Minute
+			application.Executor = blockingContextExecutor{done: true}
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it("fails when the executor does not support cancellation", func() {
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("does not support cancellation")))
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+	})
+
+	context("Retries", func() {
+		it.Before(func() {
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			application.Retries = 2
+			application.RetryBackoff = time.Millisecond
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), []byte{}, 0644)).To(Succeed())
+		})
+
+		it("re-runs a build that fails transiently", func() {
+			executor.On("Execute", mock.Anything).Return(fmt.Errorf("test-error")).Once()
+			executor.On("Execute", mock.Anything).Return(nil).Once()
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNumberOfCalls(t, "Execute", 2)
+			Expect(executor.Calls[0].Arguments[0].(effect.Execution).Command).To(Equal("test-command"))
+			Expect(executor.Calls[1].Arguments[0].(effect.Execution).Args).
+				To(Equal(executor.Calls[0].Arguments[0].(effect.Execution).Args))
+			Expect(layer.Metadata).NotTo(BeEmpty())
+		})
+
+		it("fails once the retries are exhausted", func() {
+			executor.On("Execute", mock.Anything).Return(fmt.Errorf("test-error"))
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("test-error")))
+
+			executor.AssertNumberOfCalls(t, "Execute", 3)
+		})
+
+		it("re-runs a build killed with SIGKILL without a memory limit", func() {
+			killed := exec.Command("sh", "-c", "kill -9 $$").Run()
+			Expect(killed).To(HaveOccurred())
+			executor.On("Execute", mock.Anything).Return(killed).Once()
+			executor.On("Execute", mock.Anything).Return(nil).Once()
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNumberOfCalls(t, "Execute", 2)
+		})
+	})
+
+	context("overlapping paths", func() {
+		it.Before(func() {
+			application.Logger = bard.NewLogger(ioutil.Discard)
+		})
+
+		it("fails when the layer is within the application path", func() {
+			layer := libcnb.Layer{Path: filepath.Join(ctx.Application.Path, "test-layer")}
+
+			_, err := application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("invalid configuration, layer path")))
+			Expect(err).To(MatchError(ContainSubstring("application path")))
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		it("fails when the application path is within the layer", func() {
+			layer := libcnb.Layer{Path: filepath.Dir(ctx.Application.Path)}
+
+			_, err := application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("must not contain one another")))
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		it("fails when the restore path is within the layer", func() {
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+			application.RestorePath = filepath.Join(layer.Path, "restore")
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("restore path")))
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		it("allows sibling paths with a common prefix", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), []byte{}, 0644)).To(Succeed())
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			application.RestorePath = ctx.Application.Path + "-restore"
+			Expect(os.MkdirAll(application.RestorePath, 0755)).To(Succeed())
+			defer os.RemoveAll(application.RestorePath)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	context("ArtifactName", func() {
+		it.Before(func() {
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			application.ArtifactName = "test-artifact"
+			executor.On("Execute", mock.Anything).Return(nil)
+		})
+
+		it("extracts a zip artifact on restore", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+			application.ExtractOnRestore = true
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, "test-artifact")).To(BeARegularFile())
+			Expect(filepath.Join(layer.Path, "application.zip")).NotTo(BeAnExistingFile())
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "test-artifact")).NotTo(BeAnExistingFile())
+		})
+
+		it("copies a plain binary whole on restore", func() {
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "test-binary"), []byte("\x7fELFtest-content"), 0755)).
+				To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, "test-artifact")).To(BeARegularFile())
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "test-artifact"))).To(Equal([]byte("\x7fELFtest-content")))
+			Expect(filepath.Join(ctx.Application.Path, "test-binary")).NotTo(BeAnExistingFile())
+		})
+
+		it("copies a jar whole on restore", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+			application.ArtifactName = "application.jar"
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "application.jar"))).To(Equal(b))
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).NotTo(BeAnExistingFile())
+		})
+	})
+
+	context("AsBOMEntry", func() {
+		it("records the build command, arguments, and artifact pattern", func() {
+			application.Command = "mvnw"
+			application.Arguments = []string{"package", "-DskipTests"}
+			application.ArtifactResolver.ConfigurationResolver.Configurations[0].Default = "target/*.jar"
+
+			Expect(application.AsBOMEntry(libcnb.Layer{Name: "test-layer"})).To(Equal(libcnb.BOMEntry{
+				Name: "build-command",
+				Metadata: map[string]interface{}{
+					"layer":            "test-layer",
+					"command":          "mvnw",
+					"arguments":        []string{"package", "-DskipTests"},
+					"artifact-pattern": "target/*.jar",
+				},
+				Build: true,
+			}))
+		})
+	})
+
+	context("ArtifactFromStdout", func() {
+		it.Before(func() {
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			application.ArtifactFromStdout = true
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				e := args.Get(0).(effect.Execution)
+				_, err := e.Stdout.Write([]byte("test-artifact-content"))
+				Expect(err).NotTo(HaveOccurred())
+			}).Return(nil)
+		})
+
+		it("persists standard output as the artifact", func() {
+			application.StdoutArtifactName = "test-artifact"
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.ReadFile(filepath.Join(layer.Path, "test-artifact"))).To(Equal([]byte("test-artifact-content")))
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "test-artifact"))).To(Equal([]byte("test-artifact-content")))
+			Expect(layer.Metadata[libbs.ResolvedArtifactsMetadataKey]).To(HaveLen(1))
+		})
+
+		it("uses the default artifact name", func() {
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(layer.Path, libbs.DefaultStdoutArtifactName)).To(BeARegularFile())
+		})
+
+		it("fails when verifying reproducibility", func() {
+			application.VerifyReproducible = true
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("unable to verify that an artifact written to standard output is reproducible")))
+		})
+	})
+
+	context("native-image output directory", func() {
+		it("restores the native executable with its auxiliary files", func() {
+			dir := filepath.Join(ctx.Application.Path, "build", "native", "nativeCompile")
+			Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "test-elf"), []byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00}, 0755)).
+				To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "libawt.so"), []byte("test-library"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "resource-config.json"), []byte("{}"), 0644)).To(Succeed())
+
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			application.ArtifactResolver.ConfigurationResolver.Configurations[0].Default = "build/native/nativeCompile"
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(filepath.Join(ctx.Application.Path, "nativeCompile", "test-elf")).To(BeARegularFile())
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "nativeCompile", "libawt.so"))).To(Equal([]byte("test-library")))
+			Expect(filepath.Join(ctx.Application.Path, "nativeCompile", "resource-config.json")).To(BeARegularFile())
+		})
+	})
+
+	context("$BP_BUILD_PREBUILT_ARTIFACT", func() {
+		it.Before(func() {
+			application.Logger = bard.NewLogger(ioutil.Discard)
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_BUILD_PREBUILT_ARTIFACT")).To(Succeed())
+		})
+
+		it("persists the prebuilt artifact without building", func() {
+			Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, "dist"), 0755)).To(Succeed())
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "dist", "prebuilt.jar"), b, 0644)).To(Succeed())
+			Expect(os.Setenv("BP_BUILD_PREBUILT_ARTIFACT", "dist/prebuilt.jar")).To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+			Expect(filepath.Join(layer.Path, "application.zip")).To(BeARegularFile())
+			Expect(filepath.Join(ctx.Application.Path, "fixture-marker")).To(BeARegularFile())
+			Expect(layer.Metadata[libbs.ResolvedArtifactsMetadataKey]).To(HaveLen(1))
+		})
+
+		it("persists an absolute prebuilt artifact", func() {
+			dir, err := ioutil.TempDir("", "prebuilt")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(dir)
+			Expect(os.WriteFile(filepath.Join(dir, "test-binary"), []byte("test-content"), 0755)).To(Succeed())
+			Expect(os.Setenv("BP_BUILD_PREBUILT_ARTIFACT", filepath.Join(dir, "test-binary"))).To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+			Expect(os.ReadFile(filepath.Join(layer.Path, "test-binary"))).To(Equal([]byte("test-content")))
+			Expect(os.ReadFile(filepath.Join(ctx.Application.Path, "test-binary"))).To(Equal([]byte("test-content")))
+		})
+
+		it("fails when the prebuilt artifact does not exist", func() {
+			Expect(os.Setenv("BP_BUILD_PREBUILT_ARTIFACT", "dist/missing.jar")).To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("unable to find prebuilt artifact")))
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+	})
+
+	context("ProcessArtifactPatterns", func() {
+		it.Before(func() {
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			application.ProcessArtifactPatterns = map[string]string{
+				"web":    "web/target/*.jar",
+				"worker": "worker/target/*.jar",
+			}
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-executable.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			for _, p := range []string{"web", "worker"} {
+				Expect(os.MkdirAll(filepath.Join(ctx.Application.Path, p, "target"), 0755)).To(Succeed())
+				Expect(os.WriteFile(filepath.Join(ctx.Application.Path, p, "target", p+"-1.0.0.jar"), b, 0644)).To(Succeed())
+			}
+		})
+
+		it("persists and records an artifact for each process", func() {
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			resolved := layer.Metadata[libbs.ResolvedArtifactsMetadataKey].([]libbs.ResolvedArtifact)
+			Expect(resolved).To(HaveLen(2))
+			Expect(resolved[0].Process).To(Equal("web"))
+			Expect(resolved[0].Path).To(Equal(filepath.Join("web", "web-1.0.0.jar")))
+			Expect(resolved[1].Process).To(Equal("worker"))
+			Expect(resolved[1].Path).To(Equal(filepath.Join("worker", "worker-1.0.0.jar")))
+
+			Expect(filepath.Join(layer.Path, "web", "web-1.0.0.jar")).To(BeARegularFile())
+			Expect(filepath.Join(layer.Path, "worker", "worker-1.0.0.jar")).To(BeARegularFile())
+			Expect(application.ProcessArtifacts(layer)).To(Equal(map[string]string{
+				"web":    filepath.Join(ctx.Application.Path, "web", "web-1.0.0.jar"),
+				"worker": filepath.Join(ctx.Application.Path, "worker", "worker-1.0.0.jar"),
+			}))
+			for _, p := range application.ProcessArtifacts(layer) {
+				Expect(p).To(BeARegularFile())
+			}
+		})
+
+		it("handles the artifact of each process like a single artifact", func() {
+			uploader := &fakeArtifactUploader{}
+			application.ArtifactUploader = uploader
+			application.ContentAddressable = true
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-executable.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			sum := sha256.Sum256(b)
+			name := hex.EncodeToString(sum[:]) + ".jar"
+
+			resolved := layer.Metadata[libbs.ResolvedArtifactsMetadataKey].([]libbs.ResolvedArtifact)
+			Expect(resolved[0].Path).To(Equal(filepath.Join("web", name)))
+			Expect(resolved[1].Path).To(Equal(filepath.Join("worker", name)))
+			Expect(filepath.Join(layer.Path, "web", name)).To(BeARegularFile())
+			Expect(uploader.uploads).To(Equal(map[string][]byte{"web-1.0.0.jar": b, "worker-1.0.0.jar": b}))
+
+			Expect(application.ProcessArtifacts(layer)).To(Equal(map[string]string{
+				"web":    filepath.Join(ctx.Application.Path, "web", "web-1.0.0.jar"),
+				"worker": filepath.Join(ctx.Application.Path, "worker", "worker-1.0.0.jar"),
+			}))
+			for _, p := range application.ProcessArtifacts(layer) {
+				Expect(p).To(BeARegularFile())
+			}
+			Expect(filepath.Join(ctx.Application.Path, "web", name)).NotTo(BeAnExistingFile())
+		})
+
+		it("records the processes in metadata decoded from TOML", func() {
+			layer := libcnb.Layer{Metadata: map[string]interface{}{
+				libbs.ResolvedArtifactsMetadataKey: []map[string]interface{}{
+					{"name": "web-1.0.0.jar", "path": "web/web-1.0.0.jar", "process": "web"},
+					{"name": "test.txt", "path": "test.txt"},
+				},
+			}}
+
+			Expect(application.ProcessArtifacts(layer)).To(Equal(map[string]string{
+				"web": filepath.Join(ctx.Application.Path, "web", "web-1.0.0.jar"),
+			}))
+		})
+
+		it("fails when a process does not resolve a single artifact", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-executable.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "worker", "target", "other-1.0.0.jar"), b, 0644)).
+				To(Succeed())
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("unable to find single built artifact for process worker in worker/target/*.jar")))
+		})
+	})
+
+	context("BP_BUILD_VERIFY_CACHE", func() {
+		it.Before(func() {
+			Expect(os.Setenv("BP_BUILD_VERIFY_CACHE", "true")).To(Succeed())
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), []byte{}, 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(cache.Path, "test-file-1.1.1.jar"), []byte("corrupted"), 0644)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(cache.Path, "test-file-1.1.1.jar.sha1"),
+				[]byte("a94a8fe5ccb19ba61c4c0873d391e987982fbbd3"), 0644)).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("BP_BUILD_VERIFY_CACHE")).To(Succeed())
+		})
+
+		it("removes a corrupted JAR before building", func() {
+			executor.On("Execute", mock.Anything).Run(func(mock.Arguments) {
+				Expect(filepath.Join(cache.Path, "test-file-1.1.1.jar")).NotTo(BeAnExistingFile())
+				Expect(filepath.Join(cache.Path, "test-file-1.1.1.jar.sha1")).NotTo(BeAnExistingFile())
+			}).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+		})
+
+		it("keeps a valid JAR", func() {
+			Expect(os.WriteFile(filepath.Join(cache.Path, "test-file-1.1.1.jar"), []byte("test"), 0644)).To(Succeed())
+			executor.On("Execute", mock.Anything).Return(nil)
+
+			layer, err := ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filepath.Join(cache.Path, "test-file-1.1.1.jar")).To(BeARegularFile())
+		})
+	})
 }
@@ -0,0 +1,311 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/buildpacks/libcnb"
+)
+
+// ArtifactType is the type of a built artifact.
+type ArtifactType string
+
+const (
+	// PlainJar is a JAR without a Main-Class manifest entry.
+	PlainJar ArtifactType = "plain-jar"
+
+	// ExecutableJar is a JAR with a Main-Class manifest entry.
+	ExecutableJar ArtifactType = "executable-jar"
+
+	// War is a web application archive with a WEB-INF/ directory.
+	War ArtifactType = "war"
+
+	// Directory is a directory.
+	Directory ArtifactType = "directory"
+
+	// NativeBinary is an ELF, Mach-O, or PE executable.
+	NativeBinary ArtifactType = "native-binary"
+)
+
+// DetectArtifactType determines the type of the artifact at path.
+func DetectArtifactType(path string) (ArtifactType, error) {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to stat %s\n%w", path, err)
+	}
+
+	if fileInfo.IsDir() {
+		return Directory, nil
+	}
+
+	if ok, err := isNativeBinary(path); err != nil {
+		return "", err
+	} else if ok {
+		return NativeBinary, nil
+	}
+
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to determine type of %s\n%w", path, err)
+	}
+	defer z.Close()
+
+	t := PlainJar
+	for _, f := range z.File {
+		if f.Name == "WEB-INF/" && f.FileInfo().IsDir() {
+			return War, nil
+		}
+
+		if f.Name == "META-INF/MANIFEST.MF" {
+			p, err := manifest(f)
+			if err != nil {
+				return "", fmt.Errorf("unable to investigate entry %s/%s\n%w", path, f.Name, err)
+			}
+
+			if _, ok := p.Get("Main-Class"); ok {
+				t = ExecutableJar
+			}
+		}
+	}
+
+	return t, nil
+}
+
+// AssertArtifactType returns an error if the artifact at path is not of the expected type.
+func AssertArtifactType(path string, expected ArtifactType) error {
+	actual, err := DetectArtifactType(path)
+	if err != nil {
+		return fmt.Errorf("unable to detect artifact type of %s\n%w", path, err)
+	}
+
+	if actual != expected {
+		return fmt.Errorf("expected %s to be of type %s, but was %s", path, expected, actual)
+	}
+
+	return nil
+}
+
+// StreamArtifact resolves the single artifact of the application and copies it to w without persisting it, e.g. to
+// upload or hash it.  A directory artifact is streamed as a tar of its contents.
+func (a Application) StreamArtifact(w io.Writer) error {
+	artifact, err := a.ArtifactResolver.Resolve(a.ApplicationPath)
+	if err != nil {
+		return fmt.Errorf("unable to resolve artifact\n%w", err)
+	}
+	defer a.ArtifactResolver.Cleanup()
+
+	fileInfo, err := os.Stat(artifact)
+	if err != nil {
+		return fmt.Errorf("unable to stat %s\n%w", artifact, err)
+	}
+
+	if fileInfo.IsDir() {
+		return writeTar(w, artifact)
+	}
+
+	in, err := os.Open(artifact)
+	if err != nil {
+		return fmt.Errorf("unable to open %s\n%w", artifact, err)
+	}
+	defer in.Close()
+
+	if _, err := io.Copy(w, in); err != nil {
+		return fmt.Errorf("unable to stream %s\n%w", artifact, err)
+	}
+
+	return nil
+}
+
+// writeTar writes the contents of the directory at root to w as a tar, with paths relative to root.
+func writeTar(w io.Writer, root string) error {
+	tw := tar.NewWriter(w)
+
+	if err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return fmt.Errorf("unable to determine relative path of %s\n%w", path, err)
+		}
+		if rel == "." {
+			return nil
+		}
+
+		link := ""
+		if info.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return fmt.Errorf("unable to read link %s\n%w", path, err)
+			}
+		}
+
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return fmt.Errorf("unable to create tar header for %s\n%w", path, err)
+		}
+		header.Name = filepath.ToSlash(rel)
+		if info.IsDir() {
+			header.Name += "/"
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("unable to write tar header for %s\n%w", path, err)
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		in, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("unable to open %s\n%w", path, err)
+		}
+		defer in.Close()
+
+		if _, err := io.Copy(tw, in); err != nil {
+			return fmt.Errorf("unable to write %s to tar\n%w", path, err)
+		}
+
+		return nil
+	}); err != nil {
+		return fmt.Errorf("unable to stream %s\n%w", root, err)
+	}
+
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("unable to close tar of %s\n%w", root, err)
+	}
+
+	return nil
+}
+
+// ArtifactName returns the Maven standard file name, artifactId-version[-classifier].ext, of an artifact with the
+// given coordinates.  The groupId identifies the artifact but, following Maven, is not part of its file name.  The
+// extension defaults to jar.
+func ArtifactName(groupId string, artifactId string, version string, classifier string, ext string) string {
+	ext = strings.TrimPrefix(ext, ".")
+	if ext == "" {
+		ext = "jar"
+	}
+
+	name := fmt.Sprintf("%s-%s", artifactId, version)
+	if classifier != "" {
+		name = fmt.Sprintf("%s-%s", name, classifier)
+	}
+
+	return fmt.Sprintf("%s.%s", name, ext)
+}
+
+// ServletRunner is the JAR, relative to the working directory, that SuggestProcess runs a WAR with.
+var ServletRunner = "webapp-runner.jar"
+
+// SuggestProcess suggests a default web process that launches the artifact at path: java -jar for an executable JAR, a
+// ServletRunner for a WAR, and the artifact itself for a native binary.  Returns an error for an artifact that is not
+// runnable, such as a plain JAR or a directory.
+func SuggestProcess(artifact string) (libcnb.Process, error) {
+	t, err := DetectArtifactType(artifact)
+	if err != nil {
+		return libcnb.Process{}, fmt.Errorf("unable to detect artifact type of %s\n%w", artifact, err)
+	}
+
+	p := libcnb.Process{Type: "web", Default: true}
+	switch t {
+	case ExecutableJar:
+		p.Command, p.Arguments = "java", []string{"-jar", artifact}
+	case War:
+		p.Command, p.Arguments = "java", []string{"-jar", ServletRunner, artifact}
+	case NativeBinary:
+		p.Command, p.Direct = artifact, true
+	default:
+		return libcnb.Process{}, fmt.Errorf("unable to suggest a process for %s of type %s, it is not runnable", artifact, t)
+	}
+
+	return p, nil
+}
+
+// manifestClassPath returns the entries of the Class-Path attribute of the manifest of the JAR at path.
+func manifestClassPath(path string) ([]string, error) {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer z.Close()
+
+	for _, f := range z.File {
+		if f.Name != "META-INF/MANIFEST.MF" {
+			continue
+		}
+
+		in, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("unable to open %s/%s\n%w", path, f.Name, err)
+		}
+		defer in.Close()
+
+		b, err := io.ReadAll(in)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read %s/%s\n%w", path, f.Name, err)
+		}
+
+		// manifest lines longer than 72 bytes are continued on lines that begin with a single space
+		s := strings.ReplaceAll(strings.ReplaceAll(string(b), "\r\n", "\n"), "\n ", "")
+
+		var cp []string
+		for _, line := range strings.Split(s, "\n") {
+			if k, v, ok := strings.Cut(line, ":"); ok && strings.EqualFold(k, "Class-Path") {
+				for _, e := range strings.Fields(v) {
+					if u, err := url.PathUnescape(e); err == nil {
+						e = u
+					}
+					cp = append(cp, e)
+				}
+			}
+		}
+
+		return cp, nil
+	}
+
+	return nil, nil
+}
+
+// isNativeBinary determines whether the file at path begins with the magic bytes of a native executable.
+func isNativeBinary(path string) (bool, error) {
+	t, err := DetectContentType(path)
+	if err != nil {
+		return false, err
+	}
+
+	return containsContentType(NativeContentTypes, t), nil
+}
+
+// isZip determines whether the file at path is a readable zip archive.
+func isZip(path string) bool {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return false
+	}
+	_ = z.Close()
+	return true
+}
@@ -0,0 +1,253 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libbs"
+)
+
+func testArtifact(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		var err error
+
+		path, err = ioutil.TempDir("", "artifact")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(path)).To(Succeed())
+	})
+
+	context("AssertArtifactType", func() {
+		it("passes for a plain JAR", func() {
+			Expect(libbs.AssertArtifactType(filepath.Join("testdata", "stub-application.jar"), libbs.PlainJar)).To(Succeed())
+		})
+
+		it("passes for an executable JAR", func() {
+			Expect(libbs.AssertArtifactType(filepath.Join("testdata", "stub-executable.jar"), libbs.ExecutableJar)).To(Succeed())
+		})
+
+		it("passes for a WAR", func() {
+			Expect(libbs.AssertArtifactType(filepath.Join("testdata", "stub-application.war"), libbs.War)).To(Succeed())
+		})
+
+		it("passes for a directory", func() {
+			Expect(libbs.AssertArtifactType(path, libbs.Directory)).To(Succeed())
+		})
+
+		it("passes for a native binary", func() {
+			file := filepath.Join(path, "test-binary")
+			Expect(ioutil.WriteFile(file, []byte("\x7fELF\x02\x01\x01"), 0755)).To(Succeed())
+
+			Expect(libbs.AssertArtifactType(file, libbs.NativeBinary)).To(Succeed())
+		})
+
+		it("fails for a mismatched type", func() {
+			file := filepath.Join("testdata", "stub-application.jar")
+
+			Expect(libbs.AssertArtifactType(file, libbs.ExecutableJar)).
+				To(MatchError("expected testdata/stub-application.jar to be of type executable-jar, but was plain-jar"))
+		})
+
+		it("fails for an unrecognized file", func() {
+			file := filepath.Join(path, "test-file")
+			Expect(ioutil.WriteFile(file, []byte("test-content"), 0644)).To(Succeed())
+
+			Expect(libbs.AssertArtifactType(file, libbs.PlainJar)).To(MatchError(HavePrefix("unable to detect artifact type")))
+		})
+	})
+
+	context("ArtifactName", func() {
+		it("creates a name without a classifier", func() {
+			Expect(libbs.ArtifactName("com.example", "test-artifact", "1.0.0", "", "jar")).To(Equal("test-artifact-1.0.0.jar"))
+		})
+
+		it("creates a name with a classifier", func() {
+			Expect(libbs.ArtifactName("com.example", "test-artifact", "1.0.0-SNAPSHOT", "exec", "jar")).
+				To(Equal("test-artifact-1.0.0-SNAPSHOT-exec.jar"))
+		})
+
+		it("creates a name with other extensions", func() {
+			Expect(libbs.ArtifactName("com.example", "test-artifact", "1.0.0", "", "war")).To(Equal("test-artifact-1.0.0.war"))
+			Expect(libbs.ArtifactName("com.example", "test-artifact", "1.0.0", "dist", ".tar.gz")).
+				To(Equal("test-artifact-1.0.0-dist.tar.gz"))
+		})
+
+		it("defaults the extension to jar", func() {
+			Expect(libbs.ArtifactName("com.example", "test-artifact", "1.0.0", "", "")).To(Equal("test-artifact-1.0.0.jar"))
+		})
+	})
+
+	context("SuggestProcess", func() {
+		it("runs an executable JAR with java -jar", func() {
+			file := filepath.Join("testdata", "stub-executable.jar")
+
+			Expect(libbs.SuggestProcess(file)).To(Equal(libcnb.Process{
+				Type:      "web",
+				Command:   "java",
+				Arguments: []string{"-jar", file},
+				Default:   true,
+			}))
+		})
+
+		it("runs a WAR with the servlet runner", func() {
+			file := filepath.Join("testdata", "stub-application.war")
+
+			Expect(libbs.SuggestProcess(file)).To(Equal(libcnb.Process{
+				Type:      "web",
+				Command:   "java",
+				Arguments: []string{"-jar", libbs.ServletRunner, file},
+				Default:   true,
+			}))
+		})
+
+		it("runs a native binary directly", func() {
+			file := filepath.Join(path, "test-binary")
+			Expect(ioutil.WriteFile(file, []byte("\x7fELF\x02\x01\x01"), 0755)).To(Succeed())
+
+			Expect(libbs.SuggestProcess(file)).To(Equal(libcnb.Process{
+				Type:    "web",
+				Command: file,
+				Direct:  true,
+				Default: true,
+			}))
+		})
+
+		it("fails for a plain JAR", func() {
+			_, err := libbs.SuggestProcess(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).To(MatchError("unable to suggest a process for testdata/stub-application.jar of type plain-jar, it is not runnable"))
+		})
+
+		it("fails for a directory", func() {
+			_, err := libbs.SuggestProcess(path)
+			Expect(err).To(MatchError(HaveSuffix("of type directory, it is not runnable")))
+		})
+
+		it("fails for an unrecognized file", func() {
+			file := filepath.Join(path, "test-file")
+			Expect(ioutil.WriteFile(file, []byte("test-content"), 0644)).To(Succeed())
+
+			_, err := libbs.SuggestProcess(file)
+			Expect(err).To(MatchError(HavePrefix("unable to detect artifact type")))
+		})
+	})
+
+	context("StreamArtifact", func() {
+		var application libbs.Application
+
+		it.Before(func() {
+			application = libbs.Application{
+				ApplicationPath: path,
+				ArtifactResolver: libbs.ArtifactResolver{
+					ConfigurationResolver: libpak.ConfigurationResolver{
+						Configurations: []libpak.BuildpackConfiguration{{Default: "target/*"}},
+					},
+				},
+			}
+			Expect(os.MkdirAll(filepath.Join(path, "target"), 0755)).To(Succeed())
+		})
+
+		it("streams a JAR", func() {
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.WriteFile(filepath.Join(path, "target", "stub-application.jar"), b, 0644)).To(Succeed())
+
+			out := &bytes.Buffer{}
+			Expect(application.StreamArtifact(out)).To(Succeed())
+
+			Expect(out.Bytes()).To(Equal(b))
+		})
+
+		it("streams a directory as a tar", func() {
+			Expect(os.MkdirAll(filepath.Join(path, "target", "app", "lib"), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(path, "target", "app", "lib", "test-file"), []byte("test-content"), 0644)).To(Succeed())
+
+			out := &bytes.Buffer{}
+			Expect(application.StreamArtifact(out)).To(Succeed())
+
+			contents := map[string]string{}
+			tr := tar.NewReader(out)
+			for {
+				header, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				Expect(err).NotTo(HaveOccurred())
+
+				b, err := io.ReadAll(tr)
+				Expect(err).NotTo(HaveOccurred())
+				contents[header.Name] = string(b)
+			}
+
+			Expect(contents).To(Equal(map[string]string{"lib/": "", "lib/test-file": "test-content"}))
+		})
+
+		it("removes an archive extracted to resolve the artifact", func() {
+			tmp, err := ioutil.TempDir("", "artifact-tmp")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(tmp)
+			Expect(os.Setenv("TMPDIR", tmp)).To(Succeed())
+			defer os.Unsetenv("TMPDIR")
+
+			b, err := os.ReadFile(filepath.Join("testdata", "stub-executable.jar"))
+			Expect(err).NotTo(HaveOccurred())
+
+			out, err := os.Create(filepath.Join(path, "target", "test-dist.zip"))
+			Expect(err).NotTo(HaveOccurred())
+			z := zip.NewWriter(out)
+			w, err := z.Create("test-dist/lib/stub-executable.jar")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = w.Write(b)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(z.Close()).To(Succeed())
+			Expect(out.Close()).To(Succeed())
+
+			application.ArtifactResolver.DescendIntoArchives = true
+			application.ArtifactResolver.InterestingFileDetector = libbs.JARInterestingFileDetector{}
+
+			streamed := &bytes.Buffer{}
+			Expect(application.StreamArtifact(streamed)).To(Succeed())
+
+			Expect(streamed.Bytes()).To(Equal(b))
+			Expect(filepath.Glob(filepath.Join(tmp, "artifact-archive*"))).To(BeEmpty())
+		})
+
+		it("fails without a single artifact", func() {
+			Expect(application.StreamArtifact(&bytes.Buffer{})).To(MatchError(HavePrefix("unable to resolve artifact")))
+		})
+	})
+}
@@ -17,18 +17,40 @@
 package libbs
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/paketo-buildpacks/libjvm"
 	"github.com/paketo-buildpacks/libpak/bard"
 )
 
+// WrapperCacheLayerName is the name of the layer that caches build tool wrapper distributions.
+const WrapperCacheLayerName = "wrapper-cache"
+
+// GradleWrapperDistributions and MavenWrapperDistributions are the directories, relative to the user's home directory,
+// that the Gradle and Maven wrappers download build tool distributions to.
+var (
+	GradleWrapperDistributions = filepath.Join(".gradle", "wrapper")
+	MavenWrapperDistributions  = filepath.Join(".m2", "wrapper")
+)
+
 type Cache struct {
 	Logger bard.Logger
 	Path   string
+
+	// LayerName is the name of the layer that the cache is contributed to.  Defaults to cache.
+	LayerName string
+
+	// Optional, if true, allows the cache layer to be removed by Prune when the build has not written anything to it,
+	// so that no cache layer is emitted.
+	Optional bool
 }
 
 func (c Cache) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
@@ -53,19 +75,302 @@ func (c Cache) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
 	return layer, nil
 }
 
-func (c *Cache) AsBOMEntry() (libcnb.BOMEntry, error) {
+// Prune removes the cache layer, and the link to it, if the cache is Optional and empty.  Returns true if the layer was
+// removed.
+func (c Cache) Prune() (bool, error) {
+	if !c.Optional {
+		return false, nil
+	}
+
+	if fileInfo, err := os.Lstat(c.Path); os.IsNotExist(err) {
+		return false, nil
+	} else if err != nil {
+		return false, fmt.Errorf("unable to stat %s\n%w", c.Path, err)
+	} else if fileInfo.Mode()&os.ModeSymlink == 0 {
+		return false, nil
+	}
+
+	layerPath, err := os.Readlink(c.Path)
+	if err != nil {
+		return false, fmt.Errorf("unable to read link %s\n%w", c.Path, err)
+	}
+
+	cs, err := os.ReadDir(layerPath)
+	if err != nil && !os.IsNotExist(err) {
+		return false, fmt.Errorf("unable to list children of %s\n%w", layerPath, err)
+	}
+	if len(cs) > 0 {
+		return false, nil
+	}
+
+	c.Logger.Bodyf("Removing empty cache %s", c.Path)
+	for _, file := range []string{c.Path, layerPath, fmt.Sprintf("%s.toml", layerPath)} {
+		if err := os.RemoveAll(file); err != nil {
+			return false, fmt.Errorf("unable to remove %s\n%w", file, err)
+		}
+	}
+
+	return true, nil
+}
+
+// Empty determines whether the cache contains no files or directories.  A cache that does not exist is empty.
+func (c Cache) Empty() (bool, error) {
+	cs, err := os.ReadDir(c.Path)
+	if os.IsNotExist(err) {
+		return true, nil
+	} else if err != nil {
+		return false, fmt.Errorf("unable to list children of %s\n%w", c.Path, err)
+	}
+
+	return len(cs) == 0, nil
+}
+
+// Verify verifies each JAR in the cache that has a sibling .sha1 file, as Maven writes, against its SHA1, returning
+// the paths of the JARs that do not match, which are likely corrupted.  A cache that does not exist is not verified.
+func (c Cache) Verify() ([]string, error) {
+	root, err := filepath.EvalSymlinks(c.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to resolve %s\n%w", c.Path, err)
+	}
+
+	var corrupted []string
+	if err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || !strings.HasSuffix(path, ".jar") {
+			return nil
+		}
+
+		b, err := os.ReadFile(path + ".sha1")
+		if os.IsNotExist(err) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("unable to read %s.sha1\n%w", path, err)
+		}
+
+		// the checksum may be followed by the name of the file
+		expected := strings.Fields(string(b))
+		if len(expected) == 0 {
+			return nil
+		}
+
+		actual, err := sha1File(path)
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(expected[0], actual) {
+			corrupted = append(corrupted, filepath.Join(c.Path, strings.TrimPrefix(path, root)))
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("unable to verify %s\n%w", c.Path, err)
+	}
+
+	return corrupted, nil
+}
+
+// sha1File returns the hex encoded SHA1 of the file at path.
+func sha1File(path string) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	s := sha1.New()
+	if _, err := io.Copy(s, in); err != nil {
+		return "", fmt.Errorf("unable to hash %s\n%w", path, err)
+	}
+
+	return hex.EncodeToString(s.Sum(nil)), nil
+}
+
+// Dependencies returns the Maven JARs in the cache, as listed in the build dependencies BOM entry.
+func (c Cache) Dependencies() ([]libjvm.MavenJAR, error) {
 	d, err := libjvm.NewMavenJARListing(c.Path)
 	if err != nil {
-		return libcnb.BOMEntry{}, fmt.Errorf("unable to generate dependencies from %s\n%w", c.Path, err)
+		return nil, fmt.Errorf("unable to generate dependencies from %s\n%w", c.Path, err)
+	}
+
+	return d, nil
+}
+
+// Usage returns the total size, in bytes, and the number of the regular files in the cache.  Symlinks, such as one
+// back into the layer, are not followed and files and directories that cannot be read are skipped, so that the usage
+// counts what it can.  A cache that does not exist is empty.
+func (c Cache) Usage() (int64, int, error) {
+	root, err := filepath.EvalSymlinks(c.Path)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	} else if err != nil {
+		return 0, 0, fmt.Errorf("unable to resolve %s\n%w", c.Path, err)
+	}
+
+	var (
+		size  int64
+		count int
+	)
+	if err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			c.Logger.Debugf("Skipping %s when computing cache usage: %s", path, err)
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			c.Logger.Debugf("Skipping %s when computing cache usage: %s", path, err)
+			return nil
+		}
+
+		size += info.Size()
+		count++
+		return nil
+	}); err != nil {
+		return 0, 0, fmt.Errorf("unable to walk %s\n%w", c.Path, err)
+	}
+
+	return size, count, nil
+}
+
+func (c *Cache) AsBOMEntry() (libcnb.BOMEntry, error) {
+	d, err := c.Dependencies()
+	if err != nil {
+		return libcnb.BOMEntry{}, err
+	}
+
+	size, count, err := c.Usage()
+	if err != nil {
+		return libcnb.BOMEntry{}, err
 	}
 
 	return libcnb.BOMEntry{
-		Name:     "build-dependencies",
-		Metadata: map[string]interface{}{"dependencies": d},
-		Build:    true,
+		Name: "build-dependencies",
+		Metadata: map[string]interface{}{
+			"dependencies":     d,
+			"cache-size-bytes": size,
+			"cache-file-count": count,
+		},
+		Build: true,
 	}, nil
 }
 
-func (Cache) Name() string {
+// snapshots returns the name and version of each SNAPSHOT dependency listed in a build dependencies BOM entry.
+func snapshots(entry libcnb.BOMEntry) []string {
+	d, _ := entry.Metadata["dependencies"].([]libjvm.MavenJAR)
+
+	var s []string
+	for _, j := range d {
+		if strings.Contains(j.Version, "-SNAPSHOT") {
+			s = append(s, fmt.Sprintf("%s:%s", j.Name, j.Version))
+		}
+	}
+
+	return s
+}
+
+func (c Cache) Name() string {
+	if c.LayerName != "" {
+		return c.LayerName
+	}
+	return "cache"
+}
+
+// NewWrapperCache creates a Cache that caches the build tool distributions downloaded by a wrapper to path, e.g.
+// ~/.gradle/wrapper, in its own layer so that it is retained when the dependency cache changes.
+func NewWrapperCache(path string, logger bard.Logger) Cache {
+	return Cache{Logger: logger, Path: path, LayerName: WrapperCacheLayerName}
+}
+
+// Caches are caches, such as ~/.m2 and ~/.gradle, that are contributed to a single layer.  Each cache's Path is
+// linked to a directory of the layer named for the last element of the Path, without a leading dot.  Set
+// Application.Caches for the build to use them in place of its Cache.
+type Caches struct {
+	Logger bard.Logger
+	Caches []Cache
+
+	// LayerName is the name of the layer that the caches are contributed to.  Defaults to cache.
+	LayerName string
+}
+
+// NewCaches creates Caches that cache each of paths, e.g. ~/.m2 and ~/.gradle, in a single layer.
+func NewCaches(logger bard.Logger, paths ...string) Caches {
+	c := Caches{Logger: logger}
+	for _, p := range paths {
+		c.Caches = append(c.Caches, Cache{Logger: logger, Path: p})
+	}
+	return c
+}
+
+func (c Caches) Contribute(layer libcnb.Layer) (libcnb.Layer, error) {
+	if err := os.MkdirAll(layer.Path, 0755); err != nil {
+		return libcnb.Layer{}, fmt.Errorf("unable to create layer directory %s\n%w", layer.Path, err)
+	}
+
+	dirs := map[string]string{}
+	for _, cache := range c.Caches {
+		dir := strings.TrimPrefix(filepath.Base(cache.Path), ".")
+		if p, ok := dirs[dir]; ok {
+			return libcnb.Layer{}, fmt.Errorf("caches %s and %s must have different names", p, cache.Path)
+		}
+		dirs[dir] = cache.Path
+
+		if _, err := cache.Contribute(libcnb.Layer{Path: filepath.Join(layer.Path, dir)}); err != nil {
+			return libcnb.Layer{}, err
+		}
+	}
+
+	layer.Cache = true
+	return layer, nil
+}
+
+// AsBOMEntry returns a build dependencies BOM entry that lists the Maven JARs of all of the caches.
+func (c Caches) AsBOMEntry() (libcnb.BOMEntry, error) {
+	var (
+		d     = []libjvm.MavenJAR{}
+		size  int64
+		count int
+	)
+	for _, cache := range c.Caches {
+		jars, err := cache.Dependencies()
+		if err != nil {
+			return libcnb.BOMEntry{}, err
+		}
+		d = append(d, jars...)
+
+		s, n, err := cache.Usage()
+		if err != nil {
+			return libcnb.BOMEntry{}, err
+		}
+		size, count = size+s, count+n
+	}
+
+	return libcnb.BOMEntry{
+		Name: "build-dependencies",
+		Metadata: map[string]interface{}{
+			"dependencies":     d,
+			"cache-size-bytes": size,
+			"cache-file-count": count,
+		},
+		Build: true,
+	}, nil
+}
+
+func (c Caches) Name() string {
+	if c.LayerName != "" {
+		return c.LayerName
+	}
 	return "cache"
 }
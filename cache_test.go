@@ -17,6 +17,7 @@
 package libbs_test
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -24,6 +25,8 @@ import (
 
 	"github.com/buildpacks/libcnb"
 	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libjvm"
+	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/sclevine/spec"
 
 	"github.com/paketo-buildpacks/libbs"
@@ -69,4 +72,213 @@ func testCache(t *testing.T, context spec.G, it spec.S) {
 
 		Expect(os.Readlink(file)).To(Equal(layer.Path))
 	})
+
+	context("Prune", func() {
+		var (
+			file  string
+			layer libcnb.Layer
+		)
+
+		it.Before(func() {
+			var err error
+
+			file = filepath.Join(path, "test")
+
+			layer, err = ctx.Layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = libbs.Cache{Path: file}.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(fmt.Sprintf("%s.toml", layer.Path), []byte("cache = true"), 0644)).To(Succeed())
+		})
+
+		it("removes an empty optional cache", func() {
+			Expect(libbs.Cache{Path: file, Optional: true}.Prune()).To(BeTrue())
+
+			Expect(file).NotTo(BeAnExistingFile())
+			Expect(layer.Path).NotTo(BeAnExistingFile())
+			Expect(fmt.Sprintf("%s.toml", layer.Path)).NotTo(BeAnExistingFile())
+		})
+
+		it("keeps a populated optional cache", func() {
+			Expect(ioutil.WriteFile(filepath.Join(file, "test-file"), []byte{}, 0644)).To(Succeed())
+
+			Expect(libbs.Cache{Path: file, Optional: true}.Prune()).To(BeFalse())
+
+			Expect(filepath.Join(layer.Path, "test-file")).To(BeARegularFile())
+			Expect(fmt.Sprintf("%s.toml", layer.Path)).To(BeARegularFile())
+		})
+
+		it("keeps an empty cache that is not optional", func() {
+			Expect(libbs.Cache{Path: file}.Prune()).To(BeFalse())
+
+			Expect(layer.Path).To(BeADirectory())
+			Expect(fmt.Sprintf("%s.toml", layer.Path)).To(BeARegularFile())
+		})
+	})
+
+	context("wrapper cache", func() {
+		it("uses the cache layer name by default", func() {
+			Expect(libbs.Cache{}.Name()).To(Equal("cache"))
+		})
+
+		it("contributes the wrapper distributions to a separate layer", func() {
+			dependencies := libbs.Cache{Path: filepath.Join(path, ".m2")}
+			wrapper := libbs.NewWrapperCache(filepath.Join(path, libbs.MavenWrapperDistributions), bard.Logger{})
+			Expect(wrapper.Name()).To(Equal(libbs.WrapperCacheLayerName))
+
+			dependenciesLayer, err := ctx.Layers.Layer(dependencies.Name())
+			Expect(err).NotTo(HaveOccurred())
+			dependenciesLayer, err = dependencies.Contribute(dependenciesLayer)
+			Expect(err).NotTo(HaveOccurred())
+
+			// the wrapper distributions are linked inside the dependency cache
+			wrapperLayer, err := ctx.Layers.Layer(wrapper.Name())
+			Expect(err).NotTo(HaveOccurred())
+			wrapperLayer, err = wrapper.Contribute(wrapperLayer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(dependenciesLayer.Path).NotTo(Equal(wrapperLayer.Path))
+			Expect(dependenciesLayer.Cache).To(BeTrue())
+			Expect(wrapperLayer.Cache).To(BeTrue())
+
+			Expect(os.MkdirAll(filepath.Join(path, ".m2", "wrapper", "dists", "apache-maven-3.9.6"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(path, ".m2", "repository"), 0755)).To(Succeed())
+
+			Expect(filepath.Join(wrapperLayer.Path, "dists", "apache-maven-3.9.6")).To(BeADirectory())
+			Expect(filepath.Join(dependenciesLayer.Path, "repository")).To(BeADirectory())
+			Expect(os.Readlink(filepath.Join(dependenciesLayer.Path, "wrapper"))).To(Equal(wrapperLayer.Path))
+		})
+	})
+
+	context("Dependencies", func() {
+		it("lists the Maven JARs in the BOM entry", func() {
+			Expect(os.MkdirAll(filepath.Join(path, "org", "test"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(path, "org", "test", "test-file-1.1.1.jar"), []byte{}, 0644)).To(Succeed())
+			cache := libbs.Cache{Path: path}
+
+			dependencies, err := cache.Dependencies()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dependencies).To(Equal([]libjvm.MavenJAR{
+				{
+					Name:    "test-file",
+					Version: "1.1.1",
+					SHA256:  "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+				},
+			}))
+
+			entry, err := cache.AsBOMEntry()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Metadata["dependencies"]).To(Equal(dependencies))
+		})
+	})
+
+	context("Usage", func() {
+		it("reports the size and number of files in the BOM entry", func() {
+			Expect(os.MkdirAll(filepath.Join(path, "org", "test"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(path, "org", "test", "test-file-1.1.1.jar"), []byte("test"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(path, "org", "test", "test-file-1.1.1.pom"), []byte("test-pom"), 0644)).To(Succeed())
+			Expect(os.Symlink(path, filepath.Join(path, "org", "test-loop"))).To(Succeed())
+			cache := libbs.Cache{Path: path}
+
+			entry, err := cache.AsBOMEntry()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Metadata["cache-size-bytes"]).To(Equal(int64(12)))
+			Expect(entry.Metadata["cache-file-count"]).To(Equal(2))
+		})
+
+		it("reports an empty cache if it does not exist", func() {
+			Expect(libbs.Cache{Path: filepath.Join(path, "missing")}.Usage()).To(BeZero())
+		})
+	})
+
+	context("Verify", func() {
+		var dir string
+
+		it.Before(func() {
+			dir = filepath.Join(path, "org", "test")
+			Expect(os.MkdirAll(dir, 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(dir, "test-file-1.1.1.jar"), []byte("test"), 0644)).To(Succeed())
+		})
+
+		it("passes a JAR that matches its SHA1", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "test-file-1.1.1.jar.sha1"),
+				[]byte("a94a8fe5ccb19ba61c4c0873d391e987982fbbd3  test-file-1.1.1.jar\n"), 0644)).To(Succeed())
+
+			Expect(libbs.Cache{Path: path}.Verify()).To(BeEmpty())
+		})
+
+		it("returns a corrupted JAR", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "test-file-1.1.1.jar.sha1"),
+				[]byte("0000000000000000000000000000000000000000"), 0644)).To(Succeed())
+
+			Expect(libbs.Cache{Path: path}.Verify()).To(Equal([]string{filepath.Join(dir, "test-file-1.1.1.jar")}))
+		})
+
+		it("skips a JAR without a SHA1", func() {
+			Expect(libbs.Cache{Path: path}.Verify()).To(BeEmpty())
+		})
+
+		it("verifies through the cache link", func() {
+			Expect(ioutil.WriteFile(filepath.Join(dir, "test-file-1.1.1.jar.sha1"),
+				[]byte("0000000000000000000000000000000000000000"), 0644)).To(Succeed())
+			link := filepath.Join(ctx.Layers.Path, "cache-link")
+			Expect(os.Symlink(path, link)).To(Succeed())
+
+			Expect(libbs.Cache{Path: link}.Verify()).
+				To(Equal([]string{filepath.Join(link, "org", "test", "test-file-1.1.1.jar")}))
+		})
+
+		it("does not verify a cache that does not exist", func() {
+			Expect(libbs.Cache{Path: filepath.Join(path, "missing")}.Verify()).To(BeEmpty())
+		})
+	})
+
+	context("Caches", func() {
+		it("links each cache to a directory of the layer", func() {
+			m2, gradle := filepath.Join(path, ".m2"), filepath.Join(path, ".gradle")
+			caches := libbs.NewCaches(bard.NewLogger(ioutil.Discard), m2, gradle)
+
+			layer, err := ctx.Layers.Layer(caches.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = caches.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(layer.Cache).To(BeTrue())
+			Expect(os.Readlink(m2)).To(Equal(filepath.Join(layer.Path, "m2")))
+			Expect(os.Readlink(gradle)).To(Equal(filepath.Join(layer.Path, "gradle")))
+			Expect(filepath.Join(layer.Path, "m2")).To(BeADirectory())
+			Expect(filepath.Join(layer.Path, "gradle")).To(BeADirectory())
+		})
+
+		it("fails when caches have the same name", func() {
+			caches := libbs.NewCaches(bard.NewLogger(ioutil.Discard),
+				filepath.Join(path, "a", ".m2"), filepath.Join(path, "b", "m2"))
+
+			layer, err := ctx.Layers.Layer(caches.Name())
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = caches.Contribute(layer)
+			Expect(err).To(MatchError(ContainSubstring("must have different names")))
+		})
+
+		it("merges the Maven JARs of the caches in the BOM entry", func() {
+			for _, dir := range []string{"m2", "gradle"} {
+				Expect(os.MkdirAll(filepath.Join(path, dir), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, dir, fmt.Sprintf("test-%s-1.1.1.jar", dir)), []byte{}, 0644)).
+					To(Succeed())
+			}
+			caches := libbs.NewCaches(bard.NewLogger(ioutil.Discard), filepath.Join(path, "m2"), filepath.Join(path, "gradle"))
+
+			entry, err := caches.AsBOMEntry()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entry.Name).To(Equal("build-dependencies"))
+			Expect(entry.Build).To(BeTrue())
+			Expect(entry.Metadata["dependencies"]).To(ConsistOf(
+				libjvm.MavenJAR{Name: "test-m2", Version: "1.1.1", SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+				libjvm.MavenJAR{Name: "test-gradle", Version: "1.1.1", SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"},
+			))
+		})
+	})
 }
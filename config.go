@@ -0,0 +1,144 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/BurntSushi/toml"
+	"github.com/paketo-buildpacks/libpak"
+)
+
+// ConfigFileName is the name of the file, in the root of the application path, that configures the build as an
+// alternative to environment variables.
+const ConfigFileName = ".libbs.toml"
+
+// Config is the configuration of the build read from the ConfigFileName file, e.g.
+//
+//	arguments = "--batch-mode package"
+//	artifact-pattern = "target/*.jar"
+//	module = "api"
+//	timeout = "10m"
+//
+//	[configuration]
+//	BP_INCLUDE_FILES = "static/*"
+//
+// Each value, other than the timeout, is the default value of a configuration key in place of the buildpack's default,
+// so that an environment variable of the same name takes precedence.
+type Config struct {
+
+	// Arguments are the arguments of the build command, the value of the buildpack's arguments configuration key.
+	Arguments string `toml:"arguments"`
+
+	// ArtifactPattern is the pattern of the built artifacts, the value of the ArtifactConfigurationKey.
+	ArtifactPattern string `toml:"artifact-pattern"`
+
+	// Module is the module of the built artifacts, the value of the ModuleConfigurationKey.
+	Module string `toml:"module"`
+
+	// Timeout is the maximum duration of the build, e.g. 10m.
+	Timeout string `toml:"timeout"`
+
+	// Configuration are the values of any other configuration keys, e.g. BP_INCLUDE_FILES.
+	Configuration map[string]string `toml:"configuration"`
+}
+
+// ConfigKeys are the configuration keys that the values of a Config are the values of.
+type ConfigKeys struct {
+
+	// Arguments is the configuration key of the arguments of the build command, e.g. BP_MAVEN_BUILD_ARGUMENTS.
+	Arguments string
+
+	// Artifact is the configuration key of the pattern of the built artifacts, e.g. BP_MAVEN_BUILT_ARTIFACT.
+	Artifact string
+
+	// Module is the configuration key of the module of the built artifacts, e.g. BP_MAVEN_BUILT_MODULE.
+	Module string
+}
+
+// LoadConfig reads the ConfigFileName file in applicationPath.  If there is no such file, an empty Config is returned.
+func LoadConfig(applicationPath string) (Config, error) {
+	file := filepath.Join(applicationPath, ConfigFileName)
+
+	var c Config
+	md, err := toml.DecodeFile(file, &c)
+	if os.IsNotExist(err) {
+		return Config{}, nil
+	} else if err != nil {
+		return Config{}, fmt.Errorf("unable to decode %s\n%w", file, err)
+	}
+
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return Config{}, fmt.Errorf("unable to decode %s, unknown keys: %s", file, undecoded)
+	}
+
+	return c, nil
+}
+
+// Values returns the values of the configuration keys supplied by the Config.  The values of the Arguments,
+// ArtifactPattern and Module are only returned if their keys are set, and take precedence over the same keys in the
+// Configuration.
+func (c Config) Values(keys ConfigKeys) map[string]string {
+	values := map[string]string{}
+	for k, v := range c.Configuration {
+		values[k] = v
+	}
+
+	for k, v := range map[string]string{
+		keys.Arguments: c.Arguments,
+		keys.Artifact:  c.ArtifactPattern,
+		keys.Module:    c.Module,
+	} {
+		if k != "" && v != "" {
+			values[k] = v
+		}
+	}
+
+	return values
+}
+
+// ConfigurationResolver returns a copy of resolver in which the Values of the Config are the defaults of their
+// configuration keys, replacing the defaults of the buildpack.  The environment is not changed, so that it still takes
+// precedence.
+func (c Config) ConfigurationResolver(resolver libpak.ConfigurationResolver, keys ConfigKeys) libpak.ConfigurationResolver {
+	values := c.Values(keys)
+
+	var configurations []libpak.BuildpackConfiguration
+	for _, b := range resolver.Configurations {
+		if v, ok := values[b.Name]; ok {
+			b.Default = v
+			delete(values, b.Name)
+		}
+		configurations = append(configurations, b)
+	}
+
+	var names []string
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	for _, k := range names {
+		configurations = append(configurations, libpak.BuildpackConfiguration{Name: k, Default: values[k]})
+	}
+
+	resolver.Configurations = configurations
+	return resolver
+}
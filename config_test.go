@@ -0,0 +1,149 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libbs"
+)
+
+func testConfig(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+		keys = libbs.ConfigKeys{
+			Arguments: "TEST_ARGUMENTS_KEY",
+			Artifact:  "TEST_ARTIFACT_KEY",
+			Module:    "TEST_MODULE_KEY",
+		}
+	)
+
+	it.Before(func() {
+		var err error
+
+		path, err = ioutil.TempDir("", "config")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(path)).To(Succeed())
+
+		for _, k := range []string{"TEST_ARGUMENTS_KEY", "TEST_ARTIFACT_KEY", "TEST_MODULE_KEY", "BP_INCLUDE_FILES"} {
+			Expect(os.Unsetenv(k)).To(Succeed())
+		}
+	})
+
+	context("LoadConfig", func() {
+		it("reads the configuration file", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, libbs.ConfigFileName), []byte(`
+arguments = "--batch-mode package"
+artifact-pattern = "target/*.jar"
+module = "api"
+timeout = "10m"
+
+[configuration]
+BP_INCLUDE_FILES = "static/*"
+`), 0644)).To(Succeed())
+
+			Expect(libbs.LoadConfig(path)).To(Equal(libbs.Config{
+				Arguments:       "--batch-mode package",
+				ArtifactPattern: "target/*.jar",
+				Module:          "api",
+				Timeout:         "10m",
+				Configuration:   map[string]string{"BP_INCLUDE_FILES": "static/*"},
+			}))
+		})
+
+		it("returns an empty configuration without a file", func() {
+			Expect(libbs.LoadConfig(path)).To(BeZero())
+		})
+
+		it("fails with an unknown key", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, libbs.ConfigFileName), []byte(`argument = "package"`), 0644)).
+				To(Succeed())
+
+			_, err := libbs.LoadConfig(path)
+			Expect(err).To(MatchError(ContainSubstring("unknown keys: [argument]")))
+		})
+
+		it("fails with a malformed file", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, libbs.ConfigFileName), []byte(`arguments = `), 0644)).To(Succeed())
+
+			_, err := libbs.LoadConfig(path)
+			Expect(err).To(MatchError(HavePrefix("unable to decode")))
+		})
+	})
+
+	context("Values", func() {
+		it("returns the values of the configuration keys", func() {
+			config := libbs.Config{
+				Arguments:     "package",
+				Module:        "api",
+				Configuration: map[string]string{"BP_INCLUDE_FILES": "static/*", "TEST_MODULE_KEY": "worker"},
+			}
+
+			Expect(config.Values(keys)).To(Equal(map[string]string{
+				"TEST_ARGUMENTS_KEY": "package",
+				"TEST_MODULE_KEY":    "api",
+				"BP_INCLUDE_FILES":   "static/*",
+			}))
+		})
+
+		it("ignores values without a key", func() {
+			Expect(libbs.Config{Arguments: "package"}.Values(libbs.ConfigKeys{})).To(BeEmpty())
+		})
+	})
+
+	context("ConfigurationResolver", func() {
+		it("replaces the defaults without changing the environment", func() {
+			Expect(os.Setenv("TEST_ARTIFACT_KEY", "build/*.jar")).To(Succeed())
+
+			resolver := libbs.Config{
+				Arguments:       "--batch-mode package",
+				ArtifactPattern: "target/*.jar",
+				Configuration:   map[string]string{"BP_INCLUDE_FILES": "static/*"},
+			}.ConfigurationResolver(libpak.ConfigurationResolver{
+				Configurations: []libpak.BuildpackConfiguration{
+					{Name: "TEST_ARGUMENTS_KEY", Default: "package"},
+					{Name: "TEST_ARTIFACT_KEY", Default: "*.jar"},
+					{Name: "TEST_OTHER_KEY", Default: "test-value"},
+				},
+			}, keys)
+
+			Expect(resolver.Resolve("TEST_ARGUMENTS_KEY")).To(Equal("--batch-mode package"))
+			v, ok := resolver.Resolve("TEST_ARTIFACT_KEY")
+			Expect(v).To(Equal("build/*.jar"))
+			Expect(ok).To(BeTrue())
+			Expect(resolver.Resolve("TEST_OTHER_KEY")).To(Equal("test-value"))
+			Expect(resolver.Resolve("BP_INCLUDE_FILES")).To(Equal("static/*"))
+
+			_, ok = os.LookupEnv("TEST_ARGUMENTS_KEY")
+			Expect(ok).To(BeFalse())
+			_, ok = os.LookupEnv("BP_INCLUDE_FILES")
+			Expect(ok).To(BeFalse())
+		})
+	})
+}
@@ -0,0 +1,219 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/paketo-buildpacks/libpak/crush"
+)
+
+// ContentType is the type of a file's content, as determined by its leading magic bytes.
+type ContentType string
+
+const (
+	// ContentTypeUnknown is the content type of a file that matches no known magic bytes.
+	ContentTypeUnknown ContentType = "unknown"
+
+	// ContentTypeZip is a zip archive, including JARs and WARs.
+	ContentTypeZip ContentType = "zip"
+
+	// ContentTypeGzip is a gzip compressed file.
+	ContentTypeGzip ContentType = "gzip"
+
+	// ContentTypeXz is an xz compressed file.
+	ContentTypeXz ContentType = "xz"
+
+	// ContentTypeBzip2 is a bzip2 compressed file.
+	ContentTypeBzip2 ContentType = "bzip2"
+
+	// ContentTypeTar is an uncompressed tar archive.
+	ContentTypeTar ContentType = "tar"
+
+	// ContentTypeELF is an ELF executable.
+	ContentTypeELF ContentType = "elf"
+
+	// ContentTypeMachO is a Mach-O executable.
+	ContentTypeMachO ContentType = "mach-o"
+
+	// ContentTypePE is a PE executable.
+	ContentTypePE ContentType = "pe"
+)
+
+// ArchiveContentTypes are the content types of archives and compressed files.
+var ArchiveContentTypes = []ContentType{ContentTypeZip, ContentTypeGzip, ContentTypeXz, ContentTypeBzip2, ContentTypeTar}
+
+// NativeContentTypes are the content types of native executables.
+var NativeContentTypes = []ContentType{ContentTypeELF, ContentTypeMachO, ContentTypePE}
+
+type magic struct {
+	contentType ContentType
+	offset      int
+	bytes       []byte
+}
+
+var magics = []magic{
+	{ContentTypeZip, 0, []byte{'P', 'K', 0x03, 0x04}},
+	{ContentTypeZip, 0, []byte{'P', 'K', 0x05, 0x06}},
+	{ContentTypeGzip, 0, []byte{0x1f, 0x8b}},
+	{ContentTypeXz, 0, []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}},
+	{ContentTypeBzip2, 0, []byte{'B', 'Z', 'h'}},
+	{ContentTypeTar, 257, []byte{'u', 's', 't', 'a', 'r'}},
+	{ContentTypeELF, 0, []byte{0x7f, 'E', 'L', 'F'}},
+	{ContentTypeMachO, 0, []byte{0xfe, 0xed, 0xfa, 0xce}},
+	{ContentTypeMachO, 0, []byte{0xfe, 0xed, 0xfa, 0xcf}},
+	{ContentTypeMachO, 0, []byte{0xce, 0xfa, 0xed, 0xfe}},
+	{ContentTypeMachO, 0, []byte{0xcf, 0xfa, 0xed, 0xfe}},
+	{ContentTypePE, 0, []byte{'M', 'Z'}},
+}
+
+// DetectContentType determines the content type of the file at path from its leading magic bytes.
+func DetectContentType(path string) (ContentType, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	b := make([]byte, 512)
+	n, err := io.ReadFull(in, b)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+	b = b[:n]
+
+	for _, m := range magics {
+		if len(b) >= m.offset && bytes.HasPrefix(b[m.offset:], m.bytes) {
+			return m.contentType, nil
+		}
+	}
+
+	return ContentTypeUnknown, nil
+}
+
+func containsContentType(candidates []ContentType, t ContentType) bool {
+	for _, c := range candidates {
+		if c == t {
+			return true
+		}
+	}
+	return false
+}
+
+// isArchive determines whether the file at path is an archive, such as a distribution zip or tarball, rather than a
+// JAR, WAR or EAR.
+func isArchive(path string) (bool, error) {
+	t, err := DetectContentType(path)
+	if err != nil {
+		return false, err
+	}
+
+	name := strings.ToLower(filepath.Base(path))
+	switch t {
+	case ContentTypeZip:
+		ext := filepath.Ext(name)
+		return ext != ".jar" && ext != ".war" && ext != ".ear", nil
+	case ContentTypeTar:
+		return true, nil
+	case ContentTypeGzip, ContentTypeXz, ContentTypeBzip2:
+		return compressedTarball(name), nil
+	default:
+		return false, nil
+	}
+}
+
+// restorable determines whether the file at path is an archive that can be extracted when restoring a single
+// artifact: a zip, including JARs, WARs and EARs, a tarball, or a compressed tarball.
+func restorable(path string) (bool, error) {
+	t, err := DetectContentType(path)
+	if err != nil {
+		return false, err
+	}
+
+	switch t {
+	case ContentTypeZip, ContentTypeTar:
+		return true, nil
+	case ContentTypeGzip:
+		if compressedTarball(strings.ToLower(filepath.Base(path))) {
+			return true, nil
+		}
+		return gzipTarball(path)
+	case ContentTypeXz, ContentTypeBzip2:
+		return compressedTarball(strings.ToLower(filepath.Base(path))), nil
+	default:
+		return false, nil
+	}
+}
+
+// gzipTarball determines whether the gzip compressed file at path is a tarball from its decompressed magic bytes.
+func gzipTarball(path string) (bool, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return false, nil
+	}
+	defer gz.Close()
+
+	b := make([]byte, 512)
+	n, err := io.ReadFull(gz, b)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return false, nil
+	}
+
+	return n > 257 && bytes.HasPrefix(b[257:n], []byte{'u', 's', 't', 'a', 'r'}), nil
+}
+
+// compressedTarball determines whether a lower case file name is that of a compressed tarball.
+func compressedTarball(name string) bool {
+	if strings.Contains(name, ".tar.") {
+		return true
+	}
+
+	for _, ext := range []string{".tgz", ".txz", ".tbz", ".tbz2"} {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// extract extracts the archive at path to destination.
+func extract(path string, destination string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	if err := crush.Extract(in, destination, 0); err != nil {
+		return fmt.Errorf("unable to extract %s\n%w", path, err)
+	}
+
+	return nil
+}
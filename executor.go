@@ -0,0 +1,183 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/heroku/color"
+
+	"github.com/paketo-buildpacks/libpak/effect"
+)
+
+// ContextExecutor is an effect.Executor that can stop an execution when a context is done, so that a Timeout can be
+// enforced.
+type ContextExecutor interface {
+	effect.Executor
+
+	// ExecuteContext executes the command described in the Execution, killing it once ctx is done.
+	ExecuteContext(ctx context.Context, execution effect.Execution) error
+}
+
+// GroupExecutor is a ContextExecutor that runs the command without a TTY in a process group of its own, so that the
+// whole group, including any daemons or forked compilers, is killed once the context is done.  Process groups are only
+// supported on Unix; elsewhere only the command itself is killed.
+type GroupExecutor struct{}
+
+func (g GroupExecutor) Execute(execution effect.Execution) error {
+	return g.ExecuteContext(context.Background(), execution)
+}
+
+func (GroupExecutor) ExecuteContext(ctx context.Context, execution effect.Execution) error {
+	cmd := exec.CommandContext(ctx, execution.Command, execution.Args...)
+
+	if execution.Dir != "" {
+		cmd.Dir = execution.Dir
+	}
+
+	if len(execution.Env) > 0 {
+		cmd.Env = execution.Env
+	}
+
+	cmd.Stdin = execution.Stdin
+	cmd.Stdout = execution.Stdout
+	cmd.Stderr = execution.Stderr
+
+	inProcessGroup(cmd)
+
+	return cmd.Run()
+}
+
+// execute executes the build with the Nice adjustment, if any, killing it once the Timeout, if any, has passed.  A
+// Timeout requires an Executor that is a ContextExecutor, as any other Executor cannot be killed.
+func (a Application) execute(execution effect.Execution) error {
+	if a.Nice == 0 {
+		return a.executeWithTimeout(execution)
+	}
+
+	return withNiceness(a.Nice, func(err error) {
+		a.Logger.Bodyf("%s Ignoring niceness %d, %s", color.YellowString("Warning:"), a.Nice, err)
+	}, func() error {
+		return a.executeWithTimeout(execution)
+	})
+}
+
+// executeWithTimeout executes the build, killing it once the Timeout, if any, has passed.
+func (a Application) executeWithTimeout(execution effect.Execution) error {
+	if a.Timeout <= 0 {
+		return a.Executor.Execute(execution)
+	}
+
+	e, err := a.contextExecutor()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout)
+	defer cancel()
+
+	err = e.ExecuteContext(ctx, execution)
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("build timed out after %s\n%w", a.Timeout, ctx.Err())
+	}
+
+	return err
+}
+
+// contextExecutor returns the Executor as a ContextExecutor, failing if it is not one and so cannot enforce the Timeout.
+func (a Application) contextExecutor() (ContextExecutor, error) {
+	e, ok := a.Executor.(ContextExecutor)
+	if !ok {
+		return nil, fmt.Errorf("unable to enforce build timeout of %s, executor %T does not support cancellation, "+
+			"use a ContextExecutor such as GroupExecutor", a.Timeout, a.Executor)
+	}
+
+	return e, nil
+}
+
+// retry executes the build like execute, re-running it after the RetryBackoff, up to Retries times, while it fails.  A
+// build that timed out or was killed for running out of memory is not retried.  A file that the standard output of
+// the build is written to is truncated before each retry, so that it only contains the output of the last attempt.
+func (a Application) retry(execution effect.Execution) error {
+	if a.Timeout > 0 {
+		if _, err := a.contextExecutor(); err != nil {
+			return err
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := a.execute(execution)
+		if err == nil || attempt > a.Retries || errors.Is(err, context.DeadlineExceeded) || a.killed(err) {
+			return err
+		}
+
+		a.Logger.Bodyf("%s Build failed, retrying in %s (%d of %d): %s",
+			color.YellowString("Warning:"), a.RetryBackoff, attempt, a.Retries, err)
+
+		if f, ok := execution.Stdout.(*os.File); ok && a.ArtifactFromStdout {
+			if err := f.Truncate(0); err != nil {
+				return fmt.Errorf("unable to truncate %s\n%w", f.Name(), err)
+			}
+			if _, err := f.Seek(0, io.SeekStart); err != nil {
+				return fmt.Errorf("unable to seek %s\n%w", f.Name(), err)
+			}
+		}
+
+		time.Sleep(a.RetryBackoff)
+	}
+}
+
+// runBuild runs the build command in dir, in a cgroup limited to $BP_BUILD_MEMORY_LIMIT if one is configured, with the
+// Retries and Timeout of the application.  A build that was killed for running out of memory fails with an error
+// recommending more memory or less parallelism.
+func (a Application) runBuild(dir string, stdout io.Writer, stderr io.Writer) error {
+	cgroup, err := a.memoryCgroup()
+	if err != nil {
+		return err
+	}
+	command, args := a.Command, a.Arguments
+	if cgroup != "" {
+		command, args = inCgroup(cgroup, command, args)
+	}
+
+	err = a.retry(effect.Execution{
+		Command: command,
+		Args:    args,
+		Dir:     dir,
+		Env:     a.environment(),
+		Stdout:  stdout,
+		Stderr:  stderr,
+	})
+	oom := cgroup != "" && removeCgroup(cgroup)
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return err
+	} else if err != nil && (oom || a.killed(err)) {
+		return fmt.Errorf("build was killed, likely for running out of memory, increase the memory "+
+			"available to the build, e.g. with $BP_BUILD_MEMORY_LIMIT, or reduce its parallelism\n%w", err)
+	} else if err != nil {
+		return fmt.Errorf("error running build\n%w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,73 @@
+//go:build unix
+
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs_test
+
+import (
+	"bytes"
+	gocontext "context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+
+	"github.com/paketo-buildpacks/libbs"
+)
+
+func TestExecutorUnix(t *testing.T) {
+	suite := spec.New("libbs/executor", spec.Report(report.Terminal{}))
+	suite("GroupExecutor", testGroupExecutorUnix)
+	suite.Run(t)
+}
+
+func testGroupExecutorUnix(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	it("executes a command", func() {
+		out := &bytes.Buffer{}
+
+		Expect(libbs.GroupExecutor{}.Execute(effect.Execution{
+			Command: "sh",
+			Args:    []string{"-c", "echo test-output"},
+			Stdout:  out,
+		})).To(Succeed())
+
+		Expect(out.String()).To(Equal("test-output\n"))
+	})
+
+	it("kills the process group when the context is done", func() {
+		ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		// the background sleep holds stdout open, so the execution only returns once it is killed too
+		start := time.Now()
+		err := libbs.GroupExecutor{}.ExecuteContext(ctx, effect.Execution{
+			Command: "sh",
+			Args:    []string{"-c", "sleep 30 & wait"},
+			Stdout:  &bytes.Buffer{},
+		})
+
+		Expect(err).To(HaveOccurred())
+		Expect(time.Since(start)).To(BeNumerically("<", 10*time.Second))
+	})
+}
@@ -8,24 +8,93 @@
 package libbs
 
 import (
-	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
 	"strings"
-
-	"github.com/paketo-buildpacks/libpak/sbom"
+	"time"
 
 	"github.com/buildpacks/libcnb"
 	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/paketo-buildpacks/libpak/effect"
 	"github.com/paketo-buildpacks/libpak/sherpa"
 )
 
+// DefaultLayerName is the name of the application layer contributor when no LayerName is set.
+const DefaultLayerName = "Compiled Application"
+
+// DefaultLayerTypes are the types of the application layer when no LayerTypes are set.
+var DefaultLayerTypes = libcnb.LayerTypes{Cache: true}
+
+// DefaultTrackedEnvironment are the environment variables that are tracked by default because they affect the build.
+var DefaultTrackedEnvironment = []string{"GRADLE_OPTS", "JAVA_TOOL_OPTIONS", "MAVEN_OPTS"}
+
+// sensitive matches the names and values of environment variables that must not be recorded in layer metadata.
+var sensitive = regexp.MustCompile(`(?i)passw(or)?d|secret|token|credential|api[_-]?key`)
+
 type ApplicationFactory struct {
 	Executor effect.Executor
+
+	// Environment are environment variables, such as MAVEN_OPTS, that the build is run with in addition to, and in
+	// preference to, the environment of the buildpack.  Their values are recorded in the expected metadata like those
+	// of the TrackedEnvironment.
+	Environment map[string]string
+
+	// TrackedEnvironment are the names of environment variables whose values are recorded in the expected metadata so
+	// that changing them invalidates the layer.  Sensitive values are recorded as a hash.
+	TrackedEnvironment []string
+
+	// ExcludeSubmodules, if true, excludes the content of the git submodules declared in .gitmodules from the file
+	// listing in the expected metadata, so that only changes outside of submodules invalidate the layer.  Submodule
+	// directories themselves remain in the listing.
+	ExcludeSubmodules bool
+
+	// LayerName is the name of the application layer contributor, shown in the layer header in the build output.
+	// Defaults to DefaultLayerName.
+	LayerName string
+
+	// LayerTypes are the types of the application layer, e.g. Launch as well as Cache when the layer should be
+	// available at launch.  Defaults to DefaultLayerTypes.
+	LayerTypes libcnb.LayerTypes
+
+	// VersionDetector, if set, detects the version of the tool recorded in the expected metadata.  Defaults to
+	// JavacVersionDetector, preferring the version in $JAVA_HOME/release when one exists.
+	VersionDetector *VersionDetector
+
+	// VersionProbes detect the versions of additional tools, such as KotlincVersionDetector or ScalaVersionDetector
+	// for a polyglot build, that are recorded in the expected metadata under their MetadataKey alongside the version
+	// detected by the VersionDetector.
+	VersionProbes []VersionDetector
+
+	// EagerMetadata, if true, computes the expected metadata, including the file listing of the application and the
+	// tool versions, when the application is created, returning any error from NewApplication.  Otherwise it is
+	// computed when the application is first contributed or Application.ResolveMetadata is called, so that creating an
+	// application, e.g. to inspect it at detect time, is cheap.
+	EagerMetadata bool
+
+	// Config, if set, is the configuration read from the ConfigFileName file of the application, e.g. by LoadConfig.
+	// Its values are the defaults of their configuration keys in the ConfigurationResolver of the application, so that
+	// the environment takes precedence, and its timeout is the Timeout of the application.
+	Config *Config
+
+	// ArgumentsConfigurationKey is the configuration key of the arguments of the build command, e.g.
+	// BP_MAVEN_BUILD_ARGUMENTS.  If the Config supplies the arguments, they are resolved from this key, preferring the
+	// environment, in place of the arguments that the application is created with.
+	ArgumentsConfigurationKey string
+
+	// Logger is the logger used to write to the console.
+	Logger bard.Logger
 }
 
 func NewApplicationFactory() *ApplicationFactory {
-	return &ApplicationFactory{Executor: effect.NewExecutor()}
+	return &ApplicationFactory{Executor: effect.NewExecutor(), TrackedEnvironment: DefaultTrackedEnvironment}
 }
 
 func (f *ApplicationFactory) NewApplication(
@@ -36,28 +105,75 @@ func (f *ApplicationFactory) NewApplication(
 	command string,
 	bom *libcnb.BOM,
 	applicationPath string,
-	bomScanner sbom.SBOMScanner,
+	bomScanner BuildSBOMScanner,
 ) (Application, error) {
 
+	var timeout time.Duration
+	if f.Config != nil {
+		keys := ConfigKeys{
+			Arguments: f.ArgumentsConfigurationKey,
+			Artifact:  artifactResolver.ArtifactConfigurationKey,
+			Module:    artifactResolver.ModuleConfigurationKey,
+		}
+		artifactResolver.ConfigurationResolver = f.Config.ConfigurationResolver(artifactResolver.ConfigurationResolver, keys)
+		for k := range f.Config.Values(keys) {
+			artifactResolver.configured = append(artifactResolver.configured, k)
+		}
+
+		if f.ArgumentsConfigurationKey != "" && f.Config.Arguments != "" {
+			var err error
+			if arguments, err = ResolveArguments(f.ArgumentsConfigurationKey, artifactResolver.ConfigurationResolver); err != nil {
+				return Application{}, err
+			}
+		}
+
+		if s := strings.TrimSpace(f.Config.Timeout); s != "" {
+			var err error
+			if timeout, err = time.ParseDuration(s); err != nil {
+				return Application{}, fmt.Errorf("unable to parse timeout %s in %s\n%w", s, ConfigFileName, err)
+			}
+		}
+	}
+
+	if err := ValidateConfiguration(artifactResolver); err != nil {
+		return Application{}, fmt.Errorf("failed to validate configuration\n%w", err)
+	}
+
 	app := Application{
 		ApplicationPath:  applicationPath,
 		Arguments:        arguments,
 		ArtifactResolver: artifactResolver,
 		Cache:            cache,
 		Command:          command,
+		Environment:      f.Environment,
 		Executor:         f.Executor,
 		BOM:              bom,
 		SBOMScanner:      bomScanner,
+		Timeout:          timeout,
 	}
 
-	expected, err := f.expectedMetadata(additionalMetadata, app)
-	if err != nil {
-		return Application{}, fmt.Errorf("failed to generate expected metadata\n%w", err)
+	name := f.LayerName
+	if name == "" {
+		name = DefaultLayerName
+	}
+
+	types := f.LayerTypes
+	if types == (libcnb.LayerTypes{}) {
+		types = DefaultLayerTypes
 	}
 
-	app.LayerContributor = libpak.NewLayerContributor("Compiled Application", expected, libcnb.LayerTypes{
-		Cache: true,
-	})
+	app.LayerContributor = libpak.NewLayerContributor(name, nil, types)
+
+	metadataApp := app
+	app.metadata = &lazyMetadata{compute: func() (map[string]interface{}, error) {
+		return f.expectedMetadata(additionalMetadata, metadataApp)
+	}}
+
+	if f.EagerMetadata {
+		if err := app.ResolveMetadata(); err != nil {
+			return Application{}, err
+		}
+	}
 
 	return app, nil
 }
@@ -70,14 +186,39 @@ func (f *ApplicationFactory) expectedMetadata(additionalMetadata map[string]inte
 		"artifact-pattern": app.ArtifactResolver.Pattern(),
 	}
 
-	metadata["files"], err = sherpa.NewFileListing(app.ApplicationPath)
+	files, err := sherpa.NewFileListing(app.ApplicationPath)
 	if err != nil {
 		return nil, fmt.Errorf("unable to create file listing for %s\n%w", app.ApplicationPath, err)
 	}
 
-	metadata["java-version"], err = f.javaVersion()
-	if err != nil {
-		return nil, fmt.Errorf("unable to determine java version\n%w", err)
+	if f.ExcludeSubmodules {
+		if files, err = f.excludeSubmodules(app.ApplicationPath, files); err != nil {
+			return nil, fmt.Errorf("unable to exclude submodules from file listing for %s\n%w", app.ApplicationPath, err)
+		}
+	}
+	metadata["files"] = files
+
+	if f.VersionDetector != nil {
+		metadata[f.VersionDetector.MetadataKey], err = f.VersionDetector.Detect(f.Executor)
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine %s version\n%w", f.VersionDetector.Command, err)
+		}
+	} else {
+		metadata["java-version"], err = f.javaVersion()
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine java version\n%w", err)
+		}
+	}
+
+	for _, p := range f.VersionProbes {
+		metadata[p.MetadataKey], err = p.Detect(f.Executor)
+		if err != nil {
+			return nil, fmt.Errorf("unable to determine %s version\n%w", p.Command, err)
+		}
+	}
+
+	if env := f.environment(); len(env) > 0 {
+		metadata["environment"] = env
 	}
 
 	for k, v := range additionalMetadata {
@@ -87,25 +228,101 @@ func (f *ApplicationFactory) expectedMetadata(additionalMetadata map[string]inte
 	return metadata, nil
 }
 
+// excludeSubmodules removes the entries below the git submodules of applicationPath from a file listing.
+func (f *ApplicationFactory) excludeSubmodules(applicationPath string, files []sherpa.FileEntry) ([]sherpa.FileEntry, error) {
+	submodules, err := GitSubmodules(applicationPath)
+	if err != nil {
+		return nil, err
+	}
+	if len(submodules) == 0 {
+		return files, nil
+	}
+
+	// file listing paths have symlinks resolved
+	root, err := filepath.EvalSymlinks(applicationPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to resolve %s\n%w", applicationPath, err)
+	}
+
+	var filtered []sherpa.FileEntry
+	for _, e := range files {
+		excluded := false
+		for _, s := range submodules {
+			if strings.HasPrefix(e.Path, filepath.Join(root, s)+string(filepath.Separator)) {
+				excluded = true
+				break
+			}
+		}
+
+		if !excluded {
+			filtered = append(filtered, e)
+		}
+	}
+
+	return filtered, nil
+}
+
+// environment returns the values of the tracked environment variables that are set, and of the Environment, which
+// takes precedence, replacing sensitive values with their hash.
+func (f *ApplicationFactory) environment() map[string]string {
+	keys := append([]string{}, f.TrackedEnvironment...)
+	for k := range f.Environment {
+		keys = append(keys, k)
+	}
+
+	env := map[string]string{}
+	for _, k := range keys {
+		v, ok := f.Environment[k]
+		if !ok {
+			v, ok = os.LookupEnv(k)
+		}
+		if !ok {
+			continue
+		}
+
+		if sensitive.MatchString(k) || sensitive.MatchString(v) {
+			s := sha256.Sum256([]byte(v))
+			v = fmt.Sprintf("sha256:%s", hex.EncodeToString(s[:]))
+		}
+		env[k] = v
+	}
+
+	return env
+}
+
 func (f *ApplicationFactory) javaVersion() (string, error) {
-	buf := &bytes.Buffer{}
-
-	if err := f.Executor.Execute(effect.Execution{
-		Command: "javac",
-		Args:    []string{"-version"},
-		Stdout:  buf,
-		Stderr:  buf,
-	}); err != nil {
-		return "", fmt.Errorf("error executing 'javac -version':\n Combined Output: %s: \n%w", buf.String(), err)
-	}
-
-	s := strings.Split(strings.TrimSpace(buf.String()), " ")
-	switch len(s) {
-	case 2:
-		return s[1], nil
-	case 1:
-		return s[0], nil
-	default:
+	if javaHome, ok := os.LookupEnv("JAVA_HOME"); ok {
+		if v, err := javaReleaseVersion(filepath.Join(javaHome, "release")); err == nil {
+			return v, nil
+		}
+	}
+
+	v, err := JavacVersionDetector.Detect(f.Executor)
+	if errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist) {
+		f.Logger.Debugf("Unable to find javac, recording java version as unknown: %s", err)
 		return "unknown", nil
 	}
+
+	return v, err
+}
+
+// javaReleaseVersion reads the JAVA_VERSION entry from a JDK release file.
+func javaReleaseVersion(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	for _, line := range strings.Split(string(b), "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || k != "JAVA_VERSION" {
+			continue
+		}
+
+		if v = strings.Trim(v, `"`); v != "" {
+			return v, nil
+		}
+	}
+
+	return "", fmt.Errorf("unable to find JAVA_VERSION in %s", path)
 }
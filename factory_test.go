@@ -17,10 +17,13 @@
 package libbs_test
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/paketo-buildpacks/libpak/sbom"
 
@@ -48,7 +51,8 @@ func testFactory(t *testing.T, context spec.G, it spec.S) {
 	it.Before(func() {
 		executor = &mocks.Executor{}
 		applicationFactory = &libbs.ApplicationFactory{
-			Executor: executor,
+			Executor:      executor,
+			EagerMetadata: true,
 		}
 	})
 
@@ -133,5 +137,613 @@ func testFactory(t *testing.T, context spec.G, it spec.S) {
 				Expect(metadata["addl-key"]).To(Equal("addl-value"))
 			})
 		})
+
+		it("uses the default layer name", func() {
+			Expect(application.LayerContributor.Name).To(Equal(libbs.DefaultLayerName))
+		})
+
+		it("uses the default layer types", func() {
+			Expect(application.LayerContributor.ExpectedTypes).To(Equal(libcnb.LayerTypes{Cache: true}))
+		})
+	})
+
+	context("java version", func() {
+		var (
+			appDir   string
+			javaHome string
+		)
+
+		it.Before(func() {
+			var err error
+			appDir, err = ioutil.TempDir("", "application-application")
+			Expect(err).NotTo(HaveOccurred())
+
+			javaHome, err = ioutil.TempDir("", "application-java-home")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(os.Setenv("JAVA_HOME", javaHome)).To(Succeed())
+
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution := args.Get(0).(effect.Execution)
+				_, err := execution.Stdout.Write([]byte("javac some-version"))
+				Expect(err).NotTo(HaveOccurred())
+			}).Return(nil)
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("JAVA_HOME")).To(Succeed())
+			Expect(os.RemoveAll(appDir)).To(Succeed())
+			Expect(os.RemoveAll(javaHome)).To(Succeed())
+		})
+
+		newApplication := func() libbs.Application {
+			resolver := libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "*"}},
+				},
+			}
+
+			application, err := applicationFactory.NewApplication(
+				map[string]interface{}{},
+				[]string{},
+				resolver,
+				libbs.Cache{},
+				"",
+				nil,
+				appDir,
+				sbom.NewSyftCLISBOMScanner(libcnb.Layers{}, executor, bard.Logger{}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			return application
+		}
+
+		it("reads the version from $JAVA_HOME/release", func() {
+			Expect(ioutil.WriteFile(filepath.Join(javaHome, "release"),
+				[]byte("IMPLEMENTOR=\"Test\"\nJAVA_VERSION=\"17.0.2\"\nJAVA_VERSION_DATE=\"2022-01-18\"\n"), 0644)).To(Succeed())
+
+			metadata := newApplication().LayerContributor.ExpectedMetadata.(map[string]interface{})
+			Expect(metadata["java-version"]).To(Equal("17.0.2"))
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		it("falls back to javac if $JAVA_HOME/release is malformed", func() {
+			Expect(ioutil.WriteFile(filepath.Join(javaHome, "release"), []byte("IMPLEMENTOR=\"Test\"\n"), 0644)).To(Succeed())
+
+			metadata := newApplication().LayerContributor.ExpectedMetadata.(map[string]interface{})
+			Expect(metadata["java-version"]).To(Equal("some-version"))
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+		})
+
+		it("falls back to javac if $JAVA_HOME/release is missing", func() {
+			metadata := newApplication().LayerContributor.ExpectedMetadata.(map[string]interface{})
+			Expect(metadata["java-version"]).To(Equal("some-version"))
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+		})
+
+		it("records an unknown version when javac is not found", func() {
+			executor.ExpectedCalls = nil
+			executor.On("Execute", mock.Anything).Return(&exec.Error{Name: "javac", Err: exec.ErrNotFound})
+
+			metadata := newApplication().LayerContributor.ExpectedMetadata.(map[string]interface{})
+			Expect(metadata["java-version"]).To(Equal("unknown"))
+		})
+
+		it("fails when javac fails", func() {
+			executor.ExpectedCalls = nil
+			executor.On("Execute", mock.Anything).Return(fmt.Errorf("exit status 1"))
+
+			_, err := applicationFactory.NewApplication(map[string]interface{}{}, []string{}, libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "*"}},
+				},
+			}, libbs.Cache{}, "", nil, appDir, nil)
+			Expect(err).To(MatchError(ContainSubstring("unable to determine java version")))
+		})
+
+		it("uses the configured version detector", func() {
+			detector := libbs.KotlincVersionDetector
+			applicationFactory.VersionDetector = &detector
+			executor.ExpectedCalls = nil
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				_, err := args.Get(0).(effect.Execution).Stdout.Write([]byte("info: kotlinc-jvm 1.9.22 (JRE 17.0.2+8)"))
+				Expect(err).NotTo(HaveOccurred())
+			}).Return(nil)
+
+			metadata := newApplication().LayerContributor.ExpectedMetadata.(map[string]interface{})
+			Expect(metadata["kotlin-version"]).To(Equal("1.9.22"))
+			Expect(metadata).NotTo(HaveKey("java-version"))
+		})
+
+		it("records the versions of the version probes", func() {
+			applicationFactory.VersionProbes = []libbs.VersionDetector{libbs.KotlincVersionDetector, libbs.ScalaVersionDetector}
+			executor.ExpectedCalls = nil
+			executor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool { return e.Command == "javac" })).
+				Run(func(args mock.Arguments) {
+					_, err := args.Get(0).(effect.Execution).Stdout.Write([]byte("javac 17.0.2"))
+					Expect(err).NotTo(HaveOccurred())
+				}).Return(nil)
+			executor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool { return e.Command == "kotlinc" })).
+				Run(func(args mock.Arguments) {
+					_, err := args.Get(0).(effect.Execution).Stdout.Write([]byte("info: kotlinc-jvm 1.9.22 (JRE 17.0.2+8)"))
+					Expect(err).NotTo(HaveOccurred())
+				}).Return(nil)
+			executor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool { return e.Command == "scala" })).
+				Run(func(args mock.Arguments) {
+					_, err := args.Get(0).(effect.Execution).Stderr.Write([]byte("Scala code runner version 3.3.1 -- Copyright 2002-2023, LAMP/EPFL"))
+					Expect(err).NotTo(HaveOccurred())
+				}).Return(nil)
+
+			metadata := newApplication().LayerContributor.ExpectedMetadata.(map[string]interface{})
+			Expect(metadata["java-version"]).To(Equal("17.0.2"))
+			Expect(metadata["kotlin-version"]).To(Equal("1.9.22"))
+			Expect(metadata["scala-version"]).To(Equal("3.3.1"))
+		})
+
+		it("fails when a version probe fails", func() {
+			applicationFactory.VersionProbes = []libbs.VersionDetector{libbs.ScalaVersionDetector}
+			executor.ExpectedCalls = nil
+			executor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool { return e.Command == "javac" })).Return(nil)
+			executor.On("Execute", mock.MatchedBy(func(e effect.Execution) bool { return e.Command == "scala" })).
+				Return(fmt.Errorf("test-error"))
+
+			_, err := applicationFactory.NewApplication(map[string]interface{}{}, []string{}, libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "*"}},
+				},
+			}, libbs.Cache{}, "", nil, appDir, nil)
+			Expect(err).To(MatchError(ContainSubstring("unable to determine scala version")))
+		})
+	})
+
+	context("tracked environment", func() {
+		var appDir string
+
+		it.Before(func() {
+			var err error
+			appDir, err = ioutil.TempDir("", "application-application")
+			Expect(err).NotTo(HaveOccurred())
+
+			applicationFactory.TrackedEnvironment = []string{"TEST_OPTS", "TEST_PASSWORD", "TEST_UNSET"}
+
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution := args.Get(0).(effect.Execution)
+				_, err := execution.Stdout.Write([]byte("javac some-version"))
+				Expect(err).NotTo(HaveOccurred())
+			}).Return(nil)
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("TEST_OPTS")).To(Succeed())
+			Expect(os.Unsetenv("TEST_PASSWORD")).To(Succeed())
+			Expect(os.RemoveAll(appDir)).To(Succeed())
+		})
+
+		metadata := func() map[string]interface{} {
+			resolver := libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "*"}},
+				},
+			}
+
+			application, err := applicationFactory.NewApplication(
+				map[string]interface{}{},
+				[]string{},
+				resolver,
+				libbs.Cache{},
+				"",
+				nil,
+				appDir,
+				sbom.NewSyftCLISBOMScanner(libcnb.Layers{}, executor, bard.Logger{}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			return application.LayerContributor.ExpectedMetadata.(map[string]interface{})
+		}
+
+		it("does not add environment if no tracked variables are set", func() {
+			Expect(metadata()).NotTo(HaveKey("environment"))
+		})
+
+		it("adds tracked variables", func() {
+			Expect(os.Setenv("TEST_OPTS", "-Xmx1g")).To(Succeed())
+
+			Expect(metadata()["environment"]).To(Equal(map[string]string{"TEST_OPTS": "-Xmx1g"}))
+		})
+
+		it("changes when a tracked variable changes", func() {
+			Expect(os.Setenv("TEST_OPTS", "-Xmx1g")).To(Succeed())
+			before := metadata()["environment"]
+
+			Expect(os.Setenv("TEST_OPTS", "-Xmx2g")).To(Succeed())
+			Expect(metadata()["environment"]).NotTo(Equal(before))
+		})
+
+		it("hashes sensitive values", func() {
+			Expect(os.Setenv("TEST_OPTS", "-Dtoken=test-token")).To(Succeed())
+			Expect(os.Setenv("TEST_PASSWORD", "test-password")).To(Succeed())
+
+			env := metadata()["environment"].(map[string]string)
+			Expect(env["TEST_OPTS"]).To(HavePrefix("sha256:"))
+			Expect(env["TEST_OPTS"]).NotTo(ContainSubstring("test-token"))
+			Expect(env["TEST_PASSWORD"]).To(HavePrefix("sha256:"))
+			Expect(env["TEST_PASSWORD"]).NotTo(ContainSubstring("test-password"))
+
+			Expect(os.Setenv("TEST_PASSWORD", "other-password")).To(Succeed())
+			Expect(metadata()["environment"].(map[string]string)["TEST_PASSWORD"]).NotTo(Equal(env["TEST_PASSWORD"]))
+		})
+
+		it("records the build environment, which takes precedence", func() {
+			Expect(os.Setenv("TEST_OPTS", "-Xmx1g")).To(Succeed())
+			applicationFactory.Environment = map[string]string{"TEST_OPTS": "-Xmx2g", "TEST_BUILD_OPTS": "-Dtest"}
+
+			Expect(metadata()["environment"]).To(Equal(map[string]string{"TEST_OPTS": "-Xmx2g", "TEST_BUILD_OPTS": "-Dtest"}))
+		})
+
+		it("stores the build environment", func() {
+			applicationFactory.Environment = map[string]string{"TEST_BUILD_OPTS": "-Dtest"}
+
+			application, err := applicationFactory.NewApplication(
+				map[string]interface{}{},
+				[]string{},
+				libbs.ArtifactResolver{},
+				libbs.Cache{},
+				"",
+				nil,
+				appDir,
+				sbom.NewSyftCLISBOMScanner(libcnb.Layers{}, executor, bard.Logger{}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(application.Environment).To(Equal(map[string]string{"TEST_BUILD_OPTS": "-Dtest"}))
+		})
+	})
+
+	context("ExcludeSubmodules", func() {
+		var appDir string
+
+		it.Before(func() {
+			var err error
+			appDir, err = ioutil.TempDir("", "application-application")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ioutil.WriteFile(filepath.Join(appDir, ".gitmodules"),
+				[]byte("[submodule \"lib\"]\n\tpath = lib\n\turl = https://example.com/lib.git\n"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(appDir, "pom.xml"), []byte{}, 0644)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(appDir, "lib", "src"), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(appDir, "lib", ".git"), []byte("gitdir: ../.git/modules/lib\n"), 0644)).
+				To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(appDir, "lib", "src", "Lib.java"), []byte{}, 0644)).To(Succeed())
+
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution := args.Get(0).(effect.Execution)
+				_, err := execution.Stdout.Write([]byte("javac some-version"))
+				Expect(err).NotTo(HaveOccurred())
+			}).Return(nil)
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(appDir)).To(Succeed())
+		})
+
+		paths := func() []string {
+			resolver := libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "*"}},
+				},
+			}
+
+			application, err := applicationFactory.NewApplication(
+				map[string]interface{}{},
+				[]string{},
+				resolver,
+				libbs.Cache{},
+				"",
+				nil,
+				appDir,
+				sbom.NewSyftCLISBOMScanner(libcnb.Layers{}, executor, bard.Logger{}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			root, err := filepath.EvalSymlinks(appDir)
+			Expect(err).NotTo(HaveOccurred())
+
+			var paths []string
+			for _, e := range application.LayerContributor.ExpectedMetadata.(map[string]interface{})["files"].([]sherpa.FileEntry) {
+				rel, err := filepath.Rel(root, e.Path)
+				Expect(err).NotTo(HaveOccurred())
+				paths = append(paths, rel)
+			}
+			return paths
+		}
+
+		it("includes submodule content by default", func() {
+			Expect(paths()).To(ContainElements("lib", filepath.Join("lib", ".git"), filepath.Join("lib", "src", "Lib.java")))
+		})
+
+		it("excludes submodule content", func() {
+			applicationFactory.ExcludeSubmodules = true
+
+			Expect(paths()).To(ConsistOf(".gitmodules", "lib", "pom.xml"))
+		})
+	})
+
+	context("LayerName", func() {
+		var appDir string
+
+		it.Before(func() {
+			var err error
+			appDir, err = ioutil.TempDir("", "application-application")
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution := args.Get(0).(effect.Execution)
+				_, err := execution.Stdout.Write([]byte("javac some-version"))
+				Expect(err).NotTo(HaveOccurred())
+			}).Return(nil)
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(appDir)).To(Succeed())
+		})
+
+		it("uses the configured layer name", func() {
+			applicationFactory.LayerName = "Compiled Kotlin Application"
+
+			application, err := applicationFactory.NewApplication(
+				map[string]interface{}{},
+				[]string{},
+				libbs.ArtifactResolver{},
+				libbs.Cache{},
+				"",
+				nil,
+				appDir,
+				sbom.NewSyftCLISBOMScanner(libcnb.Layers{}, executor, bard.Logger{}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(application.LayerContributor.Name).To(Equal("Compiled Kotlin Application"))
+		})
+
+		it("fails with invalid configuration", func() {
+			Expect(os.Setenv("BP_BUILD_SBOM_PARALLELISM", "0")).To(Succeed())
+			defer os.Unsetenv("BP_BUILD_SBOM_PARALLELISM")
+
+			_, err := applicationFactory.NewApplication(
+				map[string]interface{}{},
+				[]string{},
+				libbs.ArtifactResolver{},
+				libbs.Cache{},
+				"",
+				nil,
+				appDir,
+				sbom.NewSyftCLISBOMScanner(libcnb.Layers{}, executor, bard.Logger{}),
+			)
+			Expect(err).To(MatchError("failed to validate configuration\ninvalid configuration values:\n" +
+				"BP_BUILD_SBOM_PARALLELISM=0, expected a positive integer"))
+		})
+
+		it("uses the configured layer types", func() {
+			applicationFactory.LayerTypes = libcnb.LayerTypes{Cache: true, Launch: true}
+
+			application, err := applicationFactory.NewApplication(
+				map[string]interface{}{},
+				[]string{},
+				libbs.ArtifactResolver{},
+				libbs.Cache{},
+				"",
+				nil,
+				appDir,
+				sbom.NewSyftCLISBOMScanner(libcnb.Layers{}, executor, bard.Logger{}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(application.LayerContributor.ExpectedTypes).To(Equal(libcnb.LayerTypes{Cache: true, Launch: true}))
+		})
+	})
+
+	context("lazy metadata", func() {
+		var appDir string
+
+		it.Before(func() {
+			var err error
+			appDir, err = ioutil.TempDir("", "application-application")
+			Expect(err).NotTo(HaveOccurred())
+
+			applicationFactory.EagerMetadata = false
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution := args.Get(0).(effect.Execution)
+				_, err := execution.Stdout.Write([]byte("javac some-version"))
+				Expect(err).NotTo(HaveOccurred())
+			}).Return(nil)
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(appDir)).To(Succeed())
+		})
+
+		newApplication := func() libbs.Application {
+			resolver := libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "*"}},
+				},
+			}
+
+			application, err := applicationFactory.NewApplication(
+				map[string]interface{}{"addl-key": "addl-value"},
+				[]string{"test-argument"},
+				resolver,
+				libbs.Cache{},
+				"",
+				nil,
+				appDir,
+				sbom.NewSyftCLISBOMScanner(libcnb.Layers{}, executor, bard.Logger{}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			return application
+		}
+
+		it("does not compute metadata when the application is created", func() {
+			application := newApplication()
+
+			Expect(application.LayerContributor.ExpectedMetadata).To(BeNil())
+			executor.AssertNotCalled(t, "Execute", mock.Anything)
+		})
+
+		it("computes metadata when it is resolved", func() {
+			application := newApplication()
+
+			Expect(application.ResolveMetadata()).To(Succeed())
+
+			metadata := application.LayerContributor.ExpectedMetadata.(map[string]interface{})
+			Expect(metadata["java-version"]).To(Equal("some-version"))
+			Expect(metadata["addl-key"]).To(Equal("addl-value"))
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+
+			Expect(application.ResolveMetadata()).To(Succeed())
+			executor.AssertNumberOfCalls(t, "Execute", 1)
+		})
+
+		it("returns metadata errors when it is resolved", func() {
+			applicationFactory.VersionDetector = &libbs.VersionDetector{Command: "test-tool", MetadataKey: "test-version"}
+			executor.ExpectedCalls = nil
+			executor.On("Execute", mock.Anything).Return(fmt.Errorf("test-error"))
+
+			application := newApplication()
+
+			Expect(application.ResolveMetadata()).To(MatchError(ContainSubstring("unable to determine test-tool version")))
+		})
+
+		it("restores the layer when the application is contributed again", func() {
+			Expect(os.WriteFile(filepath.Join(appDir, "stub-application.jar"), []byte{}, 0644)).To(Succeed())
+			layersDir, err := ioutil.TempDir("", "application-layers")
+			Expect(err).NotTo(HaveOccurred())
+			defer os.RemoveAll(layersDir)
+
+			application := newApplication()
+			application.Command = "test-command"
+			application.Logger = bard.NewLogger(ioutil.Discard)
+			application.SBOMScanner = &fakeSBOMScanner{}
+			application.BOM = &libcnb.BOM{}
+
+			layers := libcnb.Layers{Path: layersDir}
+			layer, err := layers.Layer("test-layer")
+			Expect(err).NotTo(HaveOccurred())
+
+			layer, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			// the workspace differs from the sources once it has been built
+			Expect(os.WriteFile(filepath.Join(appDir, "test-output"), []byte{}, 0644)).To(Succeed())
+
+			_, err = application.Contribute(layer)
+			Expect(err).NotTo(HaveOccurred())
+
+			var commands []string
+			for _, c := range executor.Calls {
+				commands = append(commands, c.Arguments[0].(effect.Execution).Command)
+			}
+			Expect(commands).To(Equal([]string{"javac", "test-command"}))
+		})
+	})
+
+	context("Config", func() {
+		var appDir string
+
+		it.Before(func() {
+			var err error
+			appDir, err = ioutil.TempDir("", "application-application")
+			Expect(err).NotTo(HaveOccurred())
+
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				execution := args.Get(0).(effect.Execution)
+				_, err := execution.Stdout.Write([]byte("javac some-version"))
+				Expect(err).NotTo(HaveOccurred())
+			}).Return(nil)
+
+			Expect(ioutil.WriteFile(filepath.Join(appDir, libbs.ConfigFileName), []byte(`
+arguments = "--batch-mode package"
+artifact-pattern = "target/*.war"
+timeout = "10m"
+`), 0644)).To(Succeed())
+
+			config, err := libbs.LoadConfig(appDir)
+			Expect(err).NotTo(HaveOccurred())
+			applicationFactory.Config = &config
+			applicationFactory.ArgumentsConfigurationKey = "TEST_ARGUMENTS_KEY"
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(appDir)).To(Succeed())
+
+			for _, k := range []string{"TEST_ARGUMENTS_KEY", "TEST_ARTIFACT_KEY"} {
+				Expect(os.Unsetenv(k)).To(Succeed())
+			}
+		})
+
+		newApplication := func() libbs.Application {
+			resolver := libbs.ArtifactResolver{
+				ArtifactConfigurationKey: "TEST_ARTIFACT_KEY",
+				ModuleConfigurationKey:   "TEST_MODULE_KEY",
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{
+						{Name: "TEST_ARGUMENTS_KEY", Default: "package"},
+						{Name: "TEST_ARTIFACT_KEY", Default: "target/*.jar"},
+					},
+				},
+			}
+
+			arguments, err := libbs.ResolveArguments("TEST_ARGUMENTS_KEY", resolver.ConfigurationResolver)
+			Expect(err).NotTo(HaveOccurred())
+
+			application, err := applicationFactory.NewApplication(
+				map[string]interface{}{},
+				arguments,
+				resolver,
+				libbs.Cache{},
+				"",
+				nil,
+				appDir,
+				sbom.NewSyftCLISBOMScanner(libcnb.Layers{}, executor, bard.Logger{}),
+			)
+			Expect(err).NotTo(HaveOccurred())
+
+			return application
+		}
+
+		it("configures the application from the file", func() {
+			application := newApplication()
+
+			Expect(application.Arguments).To(Equal([]string{"--batch-mode", "package"}))
+			Expect(application.ArtifactResolver.Pattern()).To(Equal("target/*.war"))
+			Expect(application.Timeout).To(Equal(10 * time.Minute))
+		})
+
+		it("prefers the environment to the file", func() {
+			Expect(os.Setenv("TEST_ARGUMENTS_KEY", "verify")).To(Succeed())
+			Expect(os.Setenv("TEST_ARTIFACT_KEY", "build/*.jar")).To(Succeed())
+
+			application := newApplication()
+
+			Expect(application.Arguments).To(Equal([]string{"verify"}))
+			Expect(application.ArtifactResolver.Pattern()).To(Equal("build/*.jar"))
+		})
+
+		it("does not change the environment", func() {
+			newApplication()
+
+			for _, k := range []string{"TEST_ARGUMENTS_KEY", "TEST_ARTIFACT_KEY", "TEST_MODULE_KEY"} {
+				_, ok := os.LookupEnv(k)
+				Expect(ok).To(BeFalse())
+			}
+		})
+
+		it("treats the configured module as explicitly set", func() {
+			applicationFactory.Config = &libbs.Config{Module: "api"}
+
+			application := newApplication()
+
+			Expect(application.ArtifactResolver.Pattern()).To(Equal(filepath.Join("api", "target/*.jar")))
+		})
 	})
 }
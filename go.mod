@@ -3,7 +3,9 @@ module github.com/paketo-buildpacks/libbs
 go 1.23.4
 
 require (
+	github.com/BurntSushi/toml v1.4.0
 	github.com/buildpacks/libcnb v1.30.4
+	github.com/heroku/color v0.0.6
 	github.com/magiconair/properties v1.8.9
 	github.com/mattn/go-shellwords v1.0.12
 	github.com/onsi/gomega v1.36.2
@@ -15,13 +17,11 @@ require (
 )
 
 require (
-	github.com/BurntSushi/toml v1.4.0 // indirect
 	github.com/Masterminds/semver/v3 v3.3.1 // indirect
 	github.com/creack/pty v1.1.24 // indirect
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/google/go-cmp v0.6.0 // indirect
 	github.com/h2non/filetype v1.1.3 // indirect
-	github.com/heroku/color v0.0.6 // indirect
 	github.com/imdario/mergo v0.3.16 // indirect
 	github.com/kr/text v0.2.0 // indirect
 	github.com/mattn/go-colorable v0.1.14 // indirect
@@ -0,0 +1,178 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreRule is a line of a gitignore-style file.  A negated rule re-includes the paths it matches, an anchored rule
+// is matched against the whole path relative to the application path rather than against any trailing name, and a
+// directory rule only matches directories.
+type ignoreRule struct {
+	anchored  bool
+	directory bool
+	negated   bool
+	segments  []string
+}
+
+// ignoreRules are ordered rules in which the last rule that matches a path decides whether it is ignored.
+type ignoreRules []ignoreRule
+
+// readIgnoreFile parses the gitignore-style file at path.  Blank lines and lines starting with # are skipped.
+func readIgnoreFile(path string) (ignoreRules, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open ignore file %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	var rules ignoreRules
+	s := bufio.NewScanner(in)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r := ignoreRule{negated: strings.HasPrefix(line, "!")}
+		line = strings.TrimPrefix(line, "!")
+
+		r.directory = strings.HasSuffix(line, "/")
+		line = strings.TrimSuffix(line, "/")
+
+		r.anchored = strings.Contains(line, "/")
+		line = strings.TrimPrefix(line, "/")
+
+		if line == "" {
+			continue
+		}
+		r.segments = strings.Split(line, "/")
+
+		rules = append(rules, r)
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read ignore file %s\n%w", path, err)
+	}
+
+	return rules, nil
+}
+
+// ignored determines whether the path at rel, relative to the application path, is ignored.
+func (i ignoreRules) ignored(rel string, dir bool) bool {
+	names := strings.Split(filepath.ToSlash(rel), "/")
+
+	ignored := false
+	for _, r := range i {
+		if r.directory && !dir {
+			continue
+		}
+
+		if r.anchored && matchSegments(r.segments, names) || !r.anchored && matchSegments(r.segments, names[len(names)-1:]) {
+			ignored = !r.negated
+		}
+	}
+
+	return ignored
+}
+
+// matchSegments determines whether the glob segments, in which ** matches any number of names, match names.
+func matchSegments(segments []string, names []string) bool {
+	if len(segments) == 0 {
+		return len(names) == 0
+	}
+
+	if segments[0] == "**" {
+		for j := 0; j <= len(names); j++ {
+			if matchSegments(segments[1:], names[j:]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	if len(names) == 0 {
+		return false
+	}
+	if ok, _ := filepath.Match(segments[0], names[0]); !ok {
+		return false
+	}
+
+	return matchSegments(segments[1:], names[1:])
+}
+
+// unignored returns the interesting files below applicationPath that are not ignored by the IgnoreFile, if one is
+// configured.  The .git directory is never considered.
+func (a *ArtifactResolver) unignored(applicationPath string) ([]string, bool, error) {
+	if a.IgnoreFile == "" {
+		return nil, false, nil
+	}
+
+	rules, err := readIgnoreFile(filepath.Join(applicationPath, a.IgnoreFile))
+	if err != nil {
+		return nil, false, err
+	}
+
+	detector := a.InterestingFileDetector
+	if detector == nil {
+		detector = JARInterestingFileDetector{}
+	}
+	_, jars := detector.(JARInterestingFileDetector)
+
+	var candidates []string
+	if err := filepath.Walk(applicationPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(applicationPath, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+
+		if info.IsDir() {
+			if rel == ".git" || rules.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.Mode().IsRegular() || rules.ignored(rel, false) || jars && !isZip(path) {
+			return nil
+		}
+
+		if ok, err := detector.Interesting(path); err != nil {
+			return fmt.Errorf("unable to investigate %s\n%w", path, err)
+		} else if ok {
+			candidates = append(candidates, path)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, false, fmt.Errorf("unable to walk %s\n%w", applicationPath, err)
+	}
+
+	a.Logger.Debugf("Files in %s not ignored by %s matched candidates %s", applicationPath, a.IgnoreFile, candidates)
+	return candidates, true, nil
+}
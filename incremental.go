@@ -0,0 +1,58 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IncrementalMarkers are, for each build tool that supports incremental builds, the paths relative to the workspace
+// that are only present once the tool has built it and that let it skip up-to-date work in a subsequent build.
+var IncrementalMarkers = map[string][]string{
+	"gradle": {".gradle", "build"},
+	"maven":  {filepath.Join("target", "maven-status")},
+	"sbt":    {filepath.Join("target", "streams"), filepath.Join("project", "target")},
+}
+
+// SupportsIncremental determines whether a build tool, e.g. gradle, maven, or sbt, supports incremental builds.
+func SupportsIncremental(tool string) bool {
+	_, ok := IncrementalMarkers[strings.ToLower(tool)]
+	return ok
+}
+
+// HasIncrementalOutput determines whether the workspace at applicationPath contains the output of a previous build
+// by a tool that supports incremental builds.
+func HasIncrementalOutput(applicationPath string, tool string) (bool, error) {
+	markers, ok := IncrementalMarkers[strings.ToLower(tool)]
+	if !ok {
+		return false, nil
+	}
+
+	for _, m := range markers {
+		file := filepath.Join(applicationPath, m)
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return false, nil
+		} else if err != nil {
+			return false, fmt.Errorf("unable to stat %s\n%w", file, err)
+		}
+	}
+
+	return true, nil
+}
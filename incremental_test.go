@@ -0,0 +1,94 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libbs"
+)
+
+func testIncremental(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		var err error
+
+		path, err = ioutil.TempDir("", "incremental")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(path)).To(Succeed())
+	})
+
+	context("SupportsIncremental", func() {
+		it("supports known incremental tools", func() {
+			Expect(libbs.SupportsIncremental("gradle")).To(BeTrue())
+			Expect(libbs.SupportsIncremental("Maven")).To(BeTrue())
+			Expect(libbs.SupportsIncremental("sbt")).To(BeTrue())
+		})
+
+		it("does not support other tools", func() {
+			Expect(libbs.SupportsIncremental("leiningen")).To(BeFalse())
+			Expect(libbs.SupportsIncremental("unknown")).To(BeFalse())
+		})
+	})
+
+	context("HasIncrementalOutput", func() {
+		it("detects gradle output", func() {
+			Expect(os.MkdirAll(filepath.Join(path, ".gradle", "8.5", "fileHashes"), 0755)).To(Succeed())
+			Expect(os.MkdirAll(filepath.Join(path, "build", "classes"), 0755)).To(Succeed())
+
+			Expect(libbs.HasIncrementalOutput(path, "gradle")).To(BeTrue())
+		})
+
+		it("detects maven output", func() {
+			Expect(os.MkdirAll(filepath.Join(path, "target", "maven-status", "maven-compiler-plugin"), 0755)).To(Succeed())
+
+			Expect(libbs.HasIncrementalOutput(path, "maven")).To(BeTrue())
+		})
+
+		it("does not detect partial output", func() {
+			Expect(os.MkdirAll(filepath.Join(path, "build"), 0755)).To(Succeed())
+
+			Expect(libbs.HasIncrementalOutput(path, "gradle")).To(BeFalse())
+		})
+
+		it("does not detect output in a clean workspace", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, "pom.xml"), []byte{}, 0644)).To(Succeed())
+
+			Expect(libbs.HasIncrementalOutput(path, "maven")).To(BeFalse())
+		})
+
+		it("does not detect output for other tools", func() {
+			Expect(os.MkdirAll(filepath.Join(path, "target"), 0755)).To(Succeed())
+
+			Expect(libbs.HasIncrementalOutput(path, "leiningen")).To(BeFalse())
+		})
+	})
+}
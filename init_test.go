@@ -25,9 +25,19 @@ import (
 
 func TestUnit(t *testing.T) {
 	suite := spec.New("libbs", spec.Report(report.Terminal{}))
+	suite("Config", testConfig)
 	suite("Factory", testFactory)
 	suite("Application", testApplication)
 	suite("Resolvers", testResolvers)
 	suite("Cache", testCache)
+	suite("Artifact", testArtifact)
+	suite("Module", testModule)
+	suite("Ownership", testOwnership)
+	suite("Writer", testWriter)
+	suite("VersionDetector", testVersionDetector)
+	suite("Incremental", testIncremental)
+	suite("License", testLicense)
+	suite("Validate", testValidate)
+	suite("Wrapper", testWrapper)
 	suite.Run(t)
 }
@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// KeepFileName is the name of the file, in the root of the application path, that lists the files kept when the
+// workspace is purged.  Each line is a glob, relative to the application path, and lines starting with # are comments.
+const KeepFileName = ".libbs-keep"
+
+// keepRule is a glob, relative to the application path, of files kept when the workspace is purged.  A negated rule
+// removes the files it matches even if an earlier rule keeps them.
+type keepRule struct {
+	negated  bool
+	segments []string
+}
+
+// keepRules are ordered rules in which the last rule that matches a path decides whether it is kept.
+type keepRules []keepRule
+
+// newKeepRules parses globs, each of which is negated if it starts with !, into keep rules.
+func newKeepRules(globs []string) keepRules {
+	var rules keepRules
+	for _, g := range globs {
+		g = strings.TrimSpace(g)
+		negated := strings.HasPrefix(g, "!")
+		if g = strings.TrimPrefix(g, "!"); g == "" {
+			continue
+		}
+
+		rules = append(rules, keepRule{
+			negated:  negated,
+			segments: strings.Split(filepath.Clean(g), string(filepath.Separator)),
+		})
+	}
+
+	return rules
+}
+
+// readKeepFile reads the globs listed in the KeepFileName file in applicationPath, if it exists.
+func readKeepFile(applicationPath string) ([]string, error) {
+	file := filepath.Join(applicationPath, KeepFileName)
+
+	in, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to open %s\n%w", file, err)
+	}
+	defer in.Close()
+
+	var globs []string
+	s := bufio.NewScanner(in)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			globs = append(globs, line)
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	return globs, nil
+}
+
+// match determines whether the path at rel, relative to the application path, is kept, starting from whether its
+// parent is kept, and whether any rule may match a path below it, in which case it must be descended into.
+func (k keepRules) match(rel string, inherited bool) (bool, bool) {
+	names := strings.Split(rel, string(filepath.Separator))
+
+	keep, descend := inherited, false
+	for _, r := range k {
+		if len(r.segments) < len(names) || !r.matches(names) {
+			continue
+		}
+
+		if len(r.segments) == len(names) {
+			keep = !r.negated
+		} else {
+			descend = true
+		}
+	}
+
+	return keep, descend
+}
+
+// matches determines whether the leading segments of the rule match names.
+func (r keepRule) matches(names []string) bool {
+	for i, name := range names {
+		if ok, _ := filepath.Match(r.segments[i], name); !ok {
+			return false
+		}
+	}
+
+	return true
+}
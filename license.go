@@ -0,0 +1,188 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/libjvm"
+)
+
+// LicenseScanFileName is the name of the file, in the application layer, that the license inventory of the build
+// dependencies is written to when $BP_BUILD_LICENSE_SCAN is set.
+const LicenseScanFileName = "build-dependency-licenses.json"
+
+// licenseFile matches the names of license files embedded in a JAR.
+var licenseFile = regexp.MustCompile(`(?i)^META-INF/(LICENSE|LICENCE|NOTICE)([.-][^/]*)?$`)
+
+// DependencyLicense is the license metadata embedded in a build dependency.
+type DependencyLicense struct {
+
+	// Name is the name of the dependency.
+	Name string `json:"name"`
+
+	// Version is the version of the dependency.
+	Version string `json:"version"`
+
+	// SHA256 is the SHA256 hash of the dependency.
+	SHA256 string `json:"sha256"`
+
+	// Licenses are the licenses declared in the Bundle-License manifest entry and the embedded Maven POM.
+	Licenses []string `json:"licenses"`
+
+	// LicenseFiles are the license and notice files embedded in the dependency.
+	LicenseFiles []string `json:"license-files"`
+}
+
+// ScanLicenses reads the license metadata embedded in each of the dependencies, which are JARs in the cache at
+// cachePath.  Dependencies whose JAR cannot be found or read are listed without licenses.
+func ScanLicenses(cachePath string, dependencies []libjvm.MavenJAR) ([]DependencyLicense, error) {
+	jars := map[string]string{}
+	if root, err := filepath.EvalSymlinks(cachePath); err == nil {
+		if err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !info.IsDir() && filepath.Ext(path) == ".jar" {
+				jars[filepath.Base(path)] = path
+			}
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("unable to walk %s\n%w", cachePath, err)
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("unable to resolve %s\n%w", cachePath, err)
+	}
+
+	licenses := []DependencyLicense{}
+	for _, d := range dependencies {
+		l := DependencyLicense{Name: d.Name, Version: d.Version, SHA256: d.SHA256, Licenses: []string{}, LicenseFiles: []string{}}
+
+		if path, ok := jars[fmt.Sprintf("%s-%s.jar", d.Name, d.Version)]; ok && isZip(path) {
+			if err := readLicenses(path, &l); err != nil {
+				return nil, fmt.Errorf("unable to read licenses of %s\n%w", path, err)
+			}
+		}
+
+		licenses = append(licenses, l)
+	}
+
+	sort.Slice(licenses, func(i, j int) bool {
+		if licenses[i].Name != licenses[j].Name {
+			return licenses[i].Name < licenses[j].Name
+		}
+		return licenses[i].Version < licenses[j].Version
+	})
+
+	return licenses, nil
+}
+
+// readLicenses reads the license metadata embedded in the JAR at path.
+func readLicenses(path string, license *DependencyLicense) error {
+	z, err := zip.OpenReader(path)
+	if err != nil {
+		return fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer z.Close()
+
+	for _, f := range z.File {
+		switch {
+		case f.Name == "META-INF/MANIFEST.MF":
+			m, err := manifest(f)
+			if err != nil {
+				return err
+			}
+
+			if s, ok := m.Get("Bundle-License"); ok {
+				for _, l := range strings.Split(s, ",") {
+					license.Licenses = appendUnique(license.Licenses, strings.TrimSpace(l))
+				}
+			}
+		case strings.HasPrefix(f.Name, "META-INF/maven/") && strings.HasSuffix(f.Name, "/pom.xml"):
+			names, err := pomLicenses(f)
+			if err != nil {
+				return err
+			}
+
+			for _, l := range names {
+				license.Licenses = appendUnique(license.Licenses, l)
+			}
+		case licenseFile.MatchString(f.Name):
+			license.LicenseFiles = append(license.LicenseFiles, f.Name)
+		}
+	}
+
+	return nil
+}
+
+// pomLicenses returns the names of the licenses declared in an embedded Maven POM.
+func pomLicenses(f *zip.File) ([]string, error) {
+	in, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s\n%w", f.Name, err)
+	}
+	defer in.Close()
+
+	var pom struct {
+		Licenses []struct {
+			Name string `xml:"name"`
+		} `xml:"licenses>license"`
+	}
+	if err := xml.NewDecoder(in).Decode(&pom); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("unable to decode %s\n%w", f.Name, err)
+	}
+
+	var names []string
+	for _, l := range pom.Licenses {
+		if s := strings.TrimSpace(l.Name); s != "" {
+			names = append(names, s)
+		}
+	}
+
+	return names, nil
+}
+
+// writeLicenses writes the license inventory of the build dependencies to path.
+func writeLicenses(path string, licenses []DependencyLicense) error {
+	b, err := json.MarshalIndent(licenses, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to encode licenses\n%w", err)
+	}
+
+	if err := os.WriteFile(path, append(b, '\n'), 0644); err != nil {
+		return fmt.Errorf("unable to write %s\n%w", path, err)
+	}
+
+	return nil
+}
+
+func appendUnique(values []string, value string) []string {
+	if value == "" || contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
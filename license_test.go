@@ -0,0 +1,100 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs_test
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libjvm"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libbs"
+)
+
+func testLicense(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		var err error
+
+		path, err = ioutil.TempDir("", "license")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(path, "org", "test"), 0755)).To(Succeed())
+		out, err := os.Create(filepath.Join(path, "org", "test", "test-file-1.1.1.jar"))
+		Expect(err).NotTo(HaveOccurred())
+		z := zip.NewWriter(out)
+		for _, e := range [][]string{
+			{"META-INF/MANIFEST.MF", "Manifest-Version: 1.0\nBundle-License: Apache-2.0, MIT\n"},
+			{"META-INF/maven/org.test/test-file/pom.xml", `<?xml version="1.0"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <licenses>
+    <license><name>Apache-2.0</name></license>
+    <license><name>EPL-2.0</name></license>
+  </licenses>
+</project>`},
+			{"META-INF/LICENSE.txt", "test-license"},
+			{"org/test/Test.class", ""},
+		} {
+			w, err := z.Create(e[0])
+			Expect(err).NotTo(HaveOccurred())
+			_, err = w.Write([]byte(e[1]))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(z.Close()).To(Succeed())
+		Expect(out.Close()).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(path)).To(Succeed())
+	})
+
+	it("reads the embedded license metadata", func() {
+		Expect(libbs.ScanLicenses(path, []libjvm.MavenJAR{
+			{Name: "test-file", Version: "1.1.1", SHA256: "test-sha256-1"},
+			{Name: "missing-file", Version: "2.0.0", SHA256: "test-sha256-2"},
+		})).To(Equal([]libbs.DependencyLicense{
+			{
+				Name:         "missing-file",
+				Version:      "2.0.0",
+				SHA256:       "test-sha256-2",
+				Licenses:     []string{},
+				LicenseFiles: []string{},
+			},
+			{
+				Name:         "test-file",
+				Version:      "1.1.1",
+				SHA256:       "test-sha256-1",
+				Licenses:     []string{"Apache-2.0", "MIT", "EPL-2.0"},
+				LicenseFiles: []string{"META-INF/LICENSE.txt"},
+			},
+		}))
+	})
+
+	it("returns no licenses without dependencies", func() {
+		Expect(libbs.ScanLicenses(filepath.Join(path, "missing"), nil)).To(BeEmpty())
+	})
+}
@@ -0,0 +1,181 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/heroku/color"
+)
+
+// CgroupMountPath is the mount point of the unified cgroup v2 hierarchy.
+const CgroupMountPath = "/sys/fs/cgroup"
+
+// parseMemorySize parses a size in bytes, optionally with a K, M, G or T suffix, each optionally followed by i and B,
+// denoting a power of 1024, e.g. 2G or 512MiB.
+func parseMemorySize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "B"), "I")
+
+	multiplier := int64(1)
+	if i := strings.IndexAny(s, "KMGT"); i >= 0 && i == len(s)-1 {
+		multiplier = int64(1) << (10 * (strings.IndexByte("KMGT", s[i]) + 1))
+		s = s[:i]
+	}
+
+	n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("size must be positive")
+	}
+	if n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("size must be at most %d bytes", int64(math.MaxInt64))
+	}
+
+	return n * multiplier, nil
+}
+
+func validMemorySize(s string) bool {
+	_, err := parseMemorySize(s)
+	return err == nil
+}
+
+// memoryCgroup creates a cgroup for the build whose memory is limited to $BP_BUILD_MEMORY_LIMIT.  Returns the empty
+// string if no limit is configured or the cgroup cannot be created, in which case the build runs without a limit.
+func (a Application) memoryCgroup() (string, error) {
+	s, _ := a.ArtifactResolver.ConfigurationResolver.Resolve("BP_BUILD_MEMORY_LIMIT")
+	if s == "" {
+		return "", nil
+	}
+
+	limit, err := parseMemorySize(s)
+	if err != nil {
+		return "", fmt.Errorf("unable to parse BP_BUILD_MEMORY_LIMIT %s\n%w", s, err)
+	}
+
+	if runtime.GOOS != "linux" {
+		a.Logger.Body(color.YellowString("Ignoring BP_BUILD_MEMORY_LIMIT %s, memory limits are only supported on Linux", s))
+		return "", nil
+	}
+
+	parent := a.CgroupPath
+	if parent == "" {
+		if parent, err = selfCgroup(); err != nil {
+			a.Logger.Body(color.YellowString("Ignoring BP_BUILD_MEMORY_LIMIT %s, %s", s, err))
+			return "", nil
+		}
+	}
+
+	// the memory controller must be enabled for children, which fails harmlessly if it already is or is not permitted
+	_ = os.WriteFile(filepath.Join(parent, "cgroup.subtree_control"), []byte("+memory"), 0644)
+
+	cgroup := filepath.Join(parent, fmt.Sprintf("libbs-build-%d", os.Getpid()))
+	if err := os.Mkdir(cgroup, 0755); err != nil && !os.IsExist(err) {
+		a.Logger.Body(color.YellowString("Ignoring BP_BUILD_MEMORY_LIMIT %s, unable to create cgroup %s: %s", s, cgroup, err))
+		return "", nil
+	}
+
+	if err := os.WriteFile(filepath.Join(cgroup, "memory.max"), []byte(strconv.FormatInt(limit, 10)), 0644); err != nil {
+		_ = os.Remove(cgroup)
+		a.Logger.Body(color.YellowString("Ignoring BP_BUILD_MEMORY_LIMIT %s, unable to limit memory of cgroup %s: %s", s, cgroup, err))
+		return "", nil
+	}
+	// swapping would hide the limit
+	_ = os.WriteFile(filepath.Join(cgroup, "memory.swap.max"), []byte("0"), 0644)
+
+	a.Logger.Bodyf("Limiting build memory to %s", s)
+	return cgroup, nil
+}
+
+// selfCgroup returns the cgroup v2 directory of the current process.
+func selfCgroup() (string, error) {
+	in, err := os.Open("/proc/self/cgroup")
+	if err != nil {
+		return "", fmt.Errorf("unable to open /proc/self/cgroup\n%w", err)
+	}
+	defer in.Close()
+
+	s := bufio.NewScanner(in)
+	for s.Scan() {
+		if path, ok := strings.CutPrefix(s.Text(), "0::"); ok {
+			dir := filepath.Join(CgroupMountPath, path)
+			if _, err := os.Stat(filepath.Join(dir, "cgroup.controllers")); err != nil {
+				return "", fmt.Errorf("cgroup v2 is not mounted at %s", CgroupMountPath)
+			}
+			return dir, nil
+		}
+	}
+	if err := s.Err(); err != nil {
+		return "", fmt.Errorf("unable to read /proc/self/cgroup\n%w", err)
+	}
+
+	return "", fmt.Errorf("cgroup v2 is not available")
+}
+
+// inCgroup wraps command so that it is moved into cgroup before it is executed.
+func inCgroup(cgroup string, command string, args []string) (string, []string) {
+	return "sh", append([]string{"-c", `echo $$ > "$0" && exec "$@"`, filepath.Join(cgroup, "cgroup.procs"), command}, args...)
+}
+
+// removeCgroup removes the build's cgroup once the build has exited, returning whether any of its processes were
+// killed for running out of memory.
+func removeCgroup(cgroup string) bool {
+	oom := false
+	if b, err := os.ReadFile(filepath.Join(cgroup, "memory.events")); err == nil {
+		for _, line := range strings.Split(string(b), "\n") {
+			if f := strings.Fields(line); len(f) == 2 && f[0] == "oom_kill" && f[1] != "0" {
+				oom = true
+			}
+		}
+	}
+
+	_ = os.RemoveAll(cgroup)
+	return oom
+}
+
+// killed determines whether err is the exit of a process that was killed with SIGKILL, as the OOM killer does, either
+// directly or as reported by a shell with exit code 137.  A process killed without $BP_BUILD_MEMORY_LIMIT configured
+// is assumed to have been killed externally rather than for running out of memory.
+func (a Application) killed(err error) bool {
+	if s, _ := a.ArtifactResolver.ConfigurationResolver.Resolve("BP_BUILD_MEMORY_LIMIT"); s == "" {
+		return false
+	}
+
+	var e *exec.ExitError
+	if !errors.As(err, &e) {
+		return false
+	}
+
+	if e.ExitCode() == 128+int(syscall.SIGKILL) {
+		return true
+	}
+
+	status, ok := e.Sys().(syscall.WaitStatus)
+	return ok && status.Signaled() && status.Signal() == syscall.SIGKILL
+}
@@ -0,0 +1,40 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+const (
+	// MetricArtifactSize is the size, in bytes, of each artifact persisted to the application layer, tagged with the
+	// artifact name.
+	MetricArtifactSize = "libbs.artifact.size.bytes"
+
+	// MetricBuildDuration is the duration, in seconds, of the build command, tagged with the command.
+	MetricBuildDuration = "libbs.build.duration.seconds"
+
+	// MetricCacheHit is 1 if the application layer was reused from the cache and 0 if it was built.
+	MetricCacheHit = "libbs.cache.hit"
+
+	// MetricDependencies is the number of build dependencies in the cache, tagged with the cache layer name.
+	MetricDependencies = "libbs.dependencies.count"
+)
+
+// MetricsSink receives the metrics, such as build timings and artifact sizes, emitted while contributing the
+// application layer.
+type MetricsSink interface {
+
+	// Record records a single value for the metric name.
+	Record(name string, value float64, tags map[string]string)
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BuildDescriptors are the names of the files that identify a directory as the root of a build module.
+var BuildDescriptors = []string{
+	"pom.xml",
+	"pom.atom",
+	"pom.clj",
+	"pom.groovy",
+	"pom.rb",
+	"pom.scala",
+	"pom.yaml",
+	"pom.yml",
+	"build.gradle",
+	"build.gradle.kts",
+	"build.sbt",
+	"project.clj",
+	"deps.edn",
+}
+
+// BuildToolDescriptor associates the name of a build descriptor file with the build tool that builds it.
+type BuildToolDescriptor struct {
+
+	// Name is the name of the build descriptor file, e.g. pom.xml.
+	Name string
+
+	// Tool is the name of the build tool, e.g. maven.
+	Tool string
+}
+
+// DefaultBuildToolDescriptors are the build descriptors of the standard build tools, in order of precedence.
+var DefaultBuildToolDescriptors = []BuildToolDescriptor{
+	{Name: "pom.xml", Tool: "maven"},
+	{Name: "pom.atom", Tool: "maven"},
+	{Name: "pom.clj", Tool: "maven"},
+	{Name: "pom.groovy", Tool: "maven"},
+	{Name: "pom.rb", Tool: "maven"},
+	{Name: "pom.scala", Tool: "maven"},
+	{Name: "pom.yaml", Tool: "maven"},
+	{Name: "pom.yml", Tool: "maven"},
+	{Name: "build.gradle", Tool: "gradle"},
+	{Name: "build.gradle.kts", Tool: "gradle"},
+	{Name: "build.sbt", Tool: "sbt"},
+	{Name: "project.clj", Tool: "leiningen"},
+	{Name: "deps.edn", Tool: "clojure"},
+}
+
+// BuildToolDetector detects the build tool of an application from its build descriptors.
+type BuildToolDetector struct {
+
+	// Descriptors are the build descriptors, in order of precedence, that identify the build tool.  Defaults to
+	// DefaultBuildToolDescriptors.  Buildpacks may add their own, e.g. BUILD.bazel, to the defaults with Register.
+	Descriptors []BuildToolDescriptor
+}
+
+// NewBuildToolDetector creates a BuildToolDetector with the DefaultBuildToolDescriptors.
+func NewBuildToolDetector() BuildToolDetector {
+	return BuildToolDetector{Descriptors: append([]BuildToolDescriptor{}, DefaultBuildToolDescriptors...)}
+}
+
+// Register adds a build descriptor, with a lower precedence than the existing descriptors, for tool.
+func (b *BuildToolDetector) Register(name string, tool string) {
+	if b.Descriptors == nil {
+		b.Descriptors = append([]BuildToolDescriptor{}, DefaultBuildToolDescriptors...)
+	}
+	b.Descriptors = append(b.Descriptors, BuildToolDescriptor{Name: name, Tool: tool})
+}
+
+// Detect returns the tool of the first of the Descriptors that exists in applicationPath.  If none of the descriptors
+// exist, an empty tool is returned.
+func (b BuildToolDetector) Detect(applicationPath string) (string, error) {
+	descriptors := b.Descriptors
+	if descriptors == nil {
+		descriptors = DefaultBuildToolDescriptors
+	}
+
+	for _, d := range descriptors {
+		file := filepath.Join(applicationPath, d.Name)
+		if fileInfo, err := os.Stat(file); err == nil && !fileInfo.IsDir() {
+			return d.Tool, nil
+		} else if err != nil && !os.IsNotExist(err) {
+			return "", fmt.Errorf("unable to stat %s\n%w", file, err)
+		}
+	}
+
+	return "", nil
+}
+
+// DetectBuildTool returns the tool of the first of the DefaultBuildToolDescriptors that exists in applicationPath, or
+// an empty tool if none of them exist.
+func DetectBuildTool(applicationPath string) (string, error) {
+	return BuildToolDetector{}.Detect(applicationPath)
+}
+
+// ResolveModuleRoot returns the first of the candidate directories, relative to applicationPath, that contains a
+// build descriptor.  If none of the candidates contain a build descriptor, applicationPath is returned.
+func ResolveModuleRoot(applicationPath string, candidates []string) (string, error) {
+	for _, c := range candidates {
+		dir := filepath.Join(applicationPath, c)
+		if rel, err := filepath.Rel(applicationPath, dir); err != nil || strings.HasPrefix(rel, "..") {
+			return "", fmt.Errorf("module candidate %s is outside of %s", c, applicationPath)
+		}
+
+		for _, d := range BuildDescriptors {
+			file := filepath.Join(dir, d)
+			if fileInfo, err := os.Stat(file); err == nil && !fileInfo.IsDir() {
+				return dir, nil
+			} else if err != nil && !os.IsNotExist(err) {
+				return "", fmt.Errorf("unable to stat %s\n%w", file, err)
+			}
+		}
+	}
+
+	return applicationPath, nil
+}
+
+// GitSubmodules returns the paths, relative to applicationPath, of the git submodules declared in its .gitmodules
+// file.  If there is no .gitmodules file, no paths are returned.
+func GitSubmodules(applicationPath string) ([]string, error) {
+	file := filepath.Join(applicationPath, ".gitmodules")
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", file, err)
+	}
+
+	var paths []string
+	for _, line := range strings.Split(string(b), "\n") {
+		k, v, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok || strings.TrimSpace(k) != "path" {
+			continue
+		}
+
+		v = strings.Trim(strings.TrimSpace(v), `"`)
+		path := filepath.Clean(filepath.FromSlash(v))
+		if path == "." || strings.HasPrefix(path, "..") || filepath.IsAbs(path) {
+			return nil, fmt.Errorf("submodule %s is outside of %s", v, applicationPath)
+		}
+		paths = append(paths, path)
+	}
+
+	return paths, nil
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libbs"
+)
+
+func testModule(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		path string
+	)
+
+	it.Before(func() {
+		var err error
+
+		path, err = ioutil.TempDir("", "module")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(path, "services", "api"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(path, "services", "worker"), 0755)).To(Succeed())
+		Expect(os.MkdirAll(filepath.Join(path, "docs"), 0755)).To(Succeed())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(path)).To(Succeed())
+	})
+
+	context("DetectBuildTool", func() {
+		it("detects the standard build tools", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, "build.gradle.kts"), []byte{}, 0644)).To(Succeed())
+
+			Expect(libbs.DetectBuildTool(path)).To(Equal("gradle"))
+		})
+
+		it("prefers the descriptors in order", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, "build.gradle"), []byte{}, 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(path, "pom.xml"), []byte{}, 0644)).To(Succeed())
+
+			Expect(libbs.DetectBuildTool(path)).To(Equal("maven"))
+		})
+
+		it("returns no tool without a build descriptor", func() {
+			Expect(libbs.DetectBuildTool(path)).To(BeEmpty())
+		})
+
+		it("detects a registered descriptor", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, "BUILD.bazel"), []byte{}, 0644)).To(Succeed())
+			Expect(libbs.DetectBuildTool(path)).To(BeEmpty())
+
+			detector := libbs.NewBuildToolDetector()
+			detector.Register("BUILD.bazel", "bazel")
+
+			Expect(detector.Detect(path)).To(Equal("bazel"))
+			Expect(libbs.DefaultBuildToolDescriptors).NotTo(ContainElement(libbs.BuildToolDescriptor{Name: "BUILD.bazel", Tool: "bazel"}))
+		})
+
+		it("detects only the configured descriptors", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, "pom.xml"), []byte{}, 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(path, "pom.atom"), []byte{}, 0644)).To(Succeed())
+
+			detector := libbs.BuildToolDetector{Descriptors: []libbs.BuildToolDescriptor{{Name: "pom.atom", Tool: "polyglot-maven"}}}
+
+			Expect(detector.Detect(path)).To(Equal("polyglot-maven"))
+		})
+	})
+
+	context("ResolveModuleRoot", func() {
+		it("returns the first candidate containing a build descriptor", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, "services", "api", "pom.xml"), []byte{}, 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(path, "services", "worker", "build.gradle"), []byte{}, 0644)).To(Succeed())
+
+			Expect(libbs.ResolveModuleRoot(path, []string{"docs", "services/worker", "services/api"})).
+				To(Equal(filepath.Join(path, "services", "worker")))
+		})
+
+		it("returns the application path if no candidate contains a build descriptor", func() {
+			Expect(libbs.ResolveModuleRoot(path, []string{"docs", "services/api"})).To(Equal(path))
+		})
+
+		it("ignores directories named like build descriptors", func() {
+			Expect(os.MkdirAll(filepath.Join(path, "docs", "pom.xml"), 0755)).To(Succeed())
+
+			Expect(libbs.ResolveModuleRoot(path, []string{"docs"})).To(Equal(path))
+		})
+
+		it("fails with a candidate outside of the application path", func() {
+			_, err := libbs.ResolveModuleRoot(path, []string{"../other"})
+
+			Expect(err).To(MatchError(HavePrefix("module candidate ../other is outside of")))
+		})
+	})
+
+	context("GitSubmodules", func() {
+		it("returns the submodule paths", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, ".gitmodules"), []byte(`[submodule "api"]
+	path = services/api
+	url = https://example.com/api.git
+[submodule "docs"]
+	path = "docs"
+	url = https://example.com/docs.git
+`), 0644)).To(Succeed())
+
+			Expect(libbs.GitSubmodules(path)).To(Equal([]string{filepath.Join("services", "api"), "docs"}))
+		})
+
+		it("returns no paths without .gitmodules", func() {
+			Expect(libbs.GitSubmodules(path)).To(BeEmpty())
+		})
+
+		it("fails with a submodule outside of the application path", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, ".gitmodules"), []byte("[submodule \"other\"]\n\tpath = ../other\n"), 0644)).
+				To(Succeed())
+
+			_, err := libbs.GitSubmodules(path)
+			Expect(err).To(MatchError(HavePrefix("submodule ../other is outside of")))
+		})
+	})
+}
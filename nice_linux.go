@@ -0,0 +1,63 @@
+//go:build linux
+
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"fmt"
+	"runtime"
+	"syscall"
+)
+
+// withNiceness runs f on an OS thread of its own whose niceness is adjusted by nice, so that the processes f starts
+// inherit it.  If the niceness cannot be adjusted, warn is called with the reason and f is run regardless.  The thread
+// is discarded once f returns, as its niceness cannot be restored without privileges.
+func withNiceness(nice int, warn func(error), f func() error) error {
+	result := make(chan error, 1)
+
+	go func() {
+		// the thread is left locked so that it exits with the goroutine rather than running others with its niceness
+		runtime.LockOSThread()
+
+		if err := adjustThreadNiceness(nice); err != nil {
+			warn(err)
+		}
+		result <- f()
+	}()
+
+	return <-result
+}
+
+// adjustThreadNiceness adjusts the niceness of the current thread by nice, clamped to the range -20 to 19 as nice(1)
+// does.  On Linux, each thread has a niceness of its own that the processes it forks inherit.
+func adjustThreadNiceness(nice int) error {
+	tid := syscall.Gettid()
+
+	// the raw getpriority(2) returns 20 - niceness, so that the value is never negative
+	p, err := syscall.Getpriority(syscall.PRIO_PROCESS, tid)
+	if err != nil {
+		return fmt.Errorf("unable to get niceness\n%w", err)
+	}
+
+	niceness := min(max(20-p+nice, -20), 19)
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, tid, niceness); err != nil {
+		return fmt.Errorf("unable to set niceness to %d\n%w", niceness, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// DefaultTextExtensions are the extensions of the files whose line endings are normalized when no extensions are
+// configured.
+var DefaultTextExtensions = []string{".conf", ".json", ".properties", ".txt", ".xml", ".yaml", ".yml"}
+
+// normalizeLineEndings rewrites CRLF line endings to LF in path, or in every file below path if it is a directory,
+// whose name has one of the extensions.  Files containing a NUL byte are treated as binary and left untouched.
+func normalizeLineEndings(path string, name string, extensions []string) error {
+	fileInfo, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("unable to stat %s\n%w", path, err)
+	}
+
+	if !fileInfo.IsDir() {
+		if !hasExtension(name, extensions) {
+			return nil
+		}
+		return normalizeFile(path, fileInfo.Mode())
+	}
+
+	return filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !info.Mode().IsRegular() || !hasExtension(info.Name(), extensions) {
+			return nil
+		}
+
+		return normalizeFile(path, info.Mode())
+	})
+}
+
+func normalizeFile(path string, mode os.FileMode) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	if bytes.IndexByte(b, 0) >= 0 || !bytes.Contains(b, []byte("\r\n")) {
+		return nil
+	}
+
+	if err := os.WriteFile(path, bytes.ReplaceAll(b, []byte("\r\n"), []byte("\n")), mode.Perm()); err != nil {
+		return fmt.Errorf("unable to write %s\n%w", path, err)
+	}
+
+	return nil
+}
+
+func hasExtension(name string, extensions []string) bool {
+	for _, e := range extensions {
+		if strings.EqualFold(filepath.Ext(name), e) {
+			return true
+		}
+	}
+	return false
+}
+
+// volatileSBOMKeys are the keys of the SBOM values, such as CycloneDX's metadata.timestamp and serialNumber and SPDX's
+// creationInfo.created, that differ between scans of identical input.
+var volatileSBOMKeys = []string{"created", "serialNumber", "timestamp"}
+
+// normalizeSBOM rewrites the JSON SBOM at path, if it exists, with its volatile keys removed, its lists of objects
+// sorted and its object keys in order, so that its bytes are stable across scans of identical input.
+func normalizeSBOM(path string) error {
+	fileInfo, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to stat %s\n%w", path, err)
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return fmt.Errorf("unable to decode %s\n%w", path, err)
+	}
+
+	v, err = normalizeJSON(v)
+	if err != nil {
+		return fmt.Errorf("unable to normalize %s\n%w", path, err)
+	}
+
+	out := &bytes.Buffer{}
+	e := json.NewEncoder(out)
+	e.SetEscapeHTML(false)
+	if err := e.Encode(v); err != nil {
+		return fmt.Errorf("unable to encode %s\n%w", path, err)
+	}
+
+	if err := os.WriteFile(path, out.Bytes(), fileInfo.Mode().Perm()); err != nil {
+		return fmt.Errorf("unable to write %s\n%w", path, err)
+	}
+
+	return nil
+}
+
+// normalizeJSON removes the volatile keys from the objects of a decoded JSON value and sorts its lists of objects by
+// their encoding.  Lists of other values, such as strings, keep their order.
+func normalizeJSON(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for _, k := range volatileSBOMKeys {
+			delete(t, k)
+		}
+
+		for k, c := range t {
+			n, err := normalizeJSON(c)
+			if err != nil {
+				return nil, err
+			}
+			t[k] = n
+		}
+
+		return t, nil
+	case []interface{}:
+		keys := make([]string, len(t))
+		objects := true
+		for i, c := range t {
+			n, err := normalizeJSON(c)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = n
+
+			if _, ok := n.(map[string]interface{}); !ok {
+				objects = false
+				continue
+			}
+
+			b, err := json.Marshal(n)
+			if err != nil {
+				return nil, err
+			}
+			keys[i] = string(b)
+		}
+
+		if objects {
+			sort.Sort(byKey{values: t, keys: keys})
+		}
+
+		return t, nil
+	default:
+		return v, nil
+	}
+}
+
+// byKey sorts values by their corresponding keys.
+type byKey struct {
+	values []interface{}
+	keys   []string
+}
+
+func (b byKey) Len() int           { return len(b.values) }
+func (b byKey) Less(i, j int) bool { return b.keys[i] < b.keys[j] }
+func (b byKey) Swap(i, j int) {
+	b.values[i], b.values[j] = b.values[j], b.values[i]
+	b.keys[i], b.keys[j] = b.keys[j], b.keys[i]
+}
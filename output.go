@@ -0,0 +1,153 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/paketo-buildpacks/libpak/bard"
+)
+
+// buildOutput is the set of writers that the build's stdout and stderr are sent to.
+type buildOutput struct {
+	Stdout io.Writer
+	Stderr io.Writer
+
+	flushers   []func() error
+	closers    []io.Closer
+	buildScans []*OutputScanner
+	warnings   []*OutputScanner
+}
+
+// Close flushes any output that has been buffered by the writers and closes any files that output is written to.
+func (b buildOutput) Close() error {
+	for _, f := range b.flushers {
+		if err := f(); err != nil {
+			return err
+		}
+	}
+	for _, c := range b.closers {
+		if err := c.Close(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BuildScans returns the unique text matched by the build scan scanners.
+func (b buildOutput) BuildScans() []string {
+	return matches(b.buildScans)
+}
+
+// Warnings returns the unique lines matched by the warning scanners.
+func (b buildOutput) Warnings() []string {
+	return matches(b.warnings)
+}
+
+func matches(scanners []*OutputScanner) []string {
+	var matches []string
+	for _, s := range scanners {
+		for _, m := range s.Matches() {
+			if !contains(matches, m) {
+				matches = append(matches, m)
+			}
+		}
+	}
+	return matches
+}
+
+func (a Application) buildOutput() (buildOutput, error) {
+	b := buildOutput{
+		Stdout: bard.NewWriter(a.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+		Stderr: bard.NewWriter(a.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+	}
+
+	if a.MergeOutput {
+		w := NewSynchronizedWriter(b.Stdout)
+		o := NewLineWriter(w, a.OutputBufferSize)
+		e := NewLineWriter(w, a.OutputBufferSize)
+		b.Stdout, b.Stderr = o, e
+		b.flushers = append(b.flushers, o.Flush, e.Flush)
+	} else if a.OutputBufferSize > 0 {
+		o := NewLineWriter(b.Stdout, a.OutputBufferSize)
+		e := NewLineWriter(b.Stderr, a.OutputBufferSize)
+		b.Stdout, b.Stderr = o, e
+		b.flushers = append(b.flushers, o.Flush, e.Flush)
+	}
+
+	if a.BuildScanPattern != nil {
+		o := NewOutputScanner(a.BuildScanPattern)
+		e := NewOutputScanner(a.BuildScanPattern)
+		b.Stdout, b.Stderr = io.MultiWriter(b.Stdout, o), io.MultiWriter(b.Stderr, e)
+		b.flushers = append(b.flushers, o.Flush, e.Flush)
+		b.buildScans = append(b.buildScans, o, e)
+	}
+
+	if len(a.WarningPatterns) > 0 {
+		var patterns []string
+		for _, p := range a.WarningPatterns {
+			patterns = append(patterns, fmt.Sprintf("(?:%s)", p.String()))
+		}
+		pattern, err := regexp.Compile(strings.Join(patterns, "|"))
+		if err != nil {
+			return buildOutput{}, fmt.Errorf("unable to compile warning patterns\n%w", err)
+		}
+
+		o := &OutputScanner{Pattern: pattern, Lines: true}
+		e := &OutputScanner{Pattern: pattern, Lines: true}
+		b.Stdout, b.Stderr = io.MultiWriter(b.Stdout, o), io.MultiWriter(b.Stderr, e)
+		b.flushers = append(b.flushers, o.Flush, e.Flush)
+		b.warnings = append(b.warnings, o, e)
+	}
+
+	if a.BuildLogPath != "" {
+		if err := os.MkdirAll(filepath.Dir(a.BuildLogPath), 0755); err != nil {
+			return buildOutput{}, fmt.Errorf("unable to create directory %s\n%w", filepath.Dir(a.BuildLogPath), err)
+		}
+
+		f, err := os.OpenFile(a.BuildLogPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+		if err != nil {
+			return buildOutput{}, fmt.Errorf("unable to open %s\n%w", a.BuildLogPath, err)
+		}
+		b.closers = append(b.closers, f)
+
+		var w io.Writer = f
+		if a.BuildLogMaxSize > 0 {
+			w = NewTruncatingWriter(w, a.BuildLogMaxSize)
+		}
+		w = NewSynchronizedWriter(w)
+
+		b.Stdout, b.Stderr = io.MultiWriter(b.Stdout, w), io.MultiWriter(b.Stderr, w)
+	}
+
+	if a.capture != nil {
+		max := a.CapturedOutputMaxSize
+		if max <= 0 {
+			max = DefaultCapturedOutputMaxSize
+		}
+		w := NewSynchronizedWriter(NewTruncatingWriter(a.capture, max))
+
+		b.Stdout, b.Stderr = io.MultiWriter(b.Stdout, w), io.MultiWriter(b.Stderr, w)
+	}
+
+	return b, nil
+}
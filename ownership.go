@@ -0,0 +1,76 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Ownership is the user and group that files are owned by.
+type Ownership struct {
+
+	// UID is the user id.
+	UID int
+
+	// GID is the group id.
+	GID int
+}
+
+// NewOwnershipFromEnvironment creates an Ownership from the $CNB_USER_ID and $CNB_GROUP_ID environment variables.
+// Returns nil if either is not set.
+func NewOwnershipFromEnvironment() (*Ownership, error) {
+	u, uok := os.LookupEnv("CNB_USER_ID")
+	g, gok := os.LookupEnv("CNB_GROUP_ID")
+	if !uok || !gok {
+		return nil, nil
+	}
+
+	uid, err := strconv.Atoi(u)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse $CNB_USER_ID %s\n%w", u, err)
+	}
+
+	gid, err := strconv.Atoi(g)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse $CNB_GROUP_ID %s\n%w", g, err)
+	}
+
+	return &Ownership{UID: uid, GID: gid}, nil
+}
+
+// Apply changes the ownership of path and everything below it.  Symlinks are changed rather than their targets.  This
+// is a no-op unless the current process is running as root.
+func (o Ownership) Apply(path string) error {
+	if os.Geteuid() != 0 {
+		return nil
+	}
+
+	return filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if err := os.Lchown(path, o.UID, o.GID); err != nil {
+			return fmt.Errorf("unable to change ownership of %s to %d:%d\n%w", path, o.UID, o.GID, err)
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,59 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs_test
+
+import (
+	"os"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libbs"
+)
+
+func testOwnership(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	context("NewOwnershipFromEnvironment", func() {
+		it.After(func() {
+			Expect(os.Unsetenv("CNB_USER_ID")).To(Succeed())
+			Expect(os.Unsetenv("CNB_GROUP_ID")).To(Succeed())
+		})
+
+		it("returns nil if not configured", func() {
+			Expect(libbs.NewOwnershipFromEnvironment()).To(BeNil())
+		})
+
+		it("reads $CNB_USER_ID and $CNB_GROUP_ID", func() {
+			Expect(os.Setenv("CNB_USER_ID", "1001")).To(Succeed())
+			Expect(os.Setenv("CNB_GROUP_ID", "1002")).To(Succeed())
+
+			Expect(libbs.NewOwnershipFromEnvironment()).To(Equal(&libbs.Ownership{UID: 1001, GID: 1002}))
+		})
+
+		it("fails with an invalid id", func() {
+			Expect(os.Setenv("CNB_USER_ID", "test-user")).To(Succeed())
+			Expect(os.Setenv("CNB_GROUP_ID", "1002")).To(Succeed())
+
+			_, err := libbs.NewOwnershipFromEnvironment()
+			Expect(err).To(MatchError(HavePrefix("unable to parse $CNB_USER_ID test-user")))
+		})
+	})
+}
@@ -0,0 +1,113 @@
+//go:build unix
+
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/buildpacks/libcnb"
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/paketo-buildpacks/libpak/effect/mocks"
+	sbomMocks "github.com/paketo-buildpacks/libpak/sbom/mocks"
+	"github.com/sclevine/spec"
+	"github.com/sclevine/spec/report"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/paketo-buildpacks/libbs"
+)
+
+func TestOwnershipUnix(t *testing.T) {
+	suite := spec.New("libbs/ownership", spec.Report(report.Terminal{}))
+	suite("Ownership", testOwnershipUnix)
+	suite.Run(t)
+}
+
+func testOwnershipUnix(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		ctx libcnb.BuildContext
+	)
+
+	it.Before(func() {
+		if os.Geteuid() != 0 {
+			t.Skip("changing ownership requires root")
+		}
+
+		var err error
+
+		ctx.Application.Path, err = ioutil.TempDir("", "ownership-application")
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx.Layers.Path, err = ioutil.TempDir("", "ownership-layers")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(ctx.Application.Path)).To(Succeed())
+		Expect(os.RemoveAll(ctx.Layers.Path)).To(Succeed())
+	})
+
+	it("changes the ownership of restored artifacts", func() {
+		b, err := os.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(os.WriteFile(filepath.Join(ctx.Application.Path, "stub-application.jar"), b, 0644)).To(Succeed())
+
+		executor := &mocks.Executor{}
+		executor.On("Execute", mock.Anything).Return(nil)
+
+		sbomScanner := &sbomMocks.SBOMScanner{}
+		sbomScanner.On("ScanBuild", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		Expect(os.Setenv("BP_BOM_LABEL_DISABLED", "true")).To(Succeed())
+		defer os.Unsetenv("BP_BOM_LABEL_DISABLED")
+
+		application := libbs.Application{
+			ApplicationPath: ctx.Application.Path,
+			ArtifactResolver: libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "*"}},
+				},
+			},
+			Command:          "test-command",
+			Executor:         executor,
+			LayerContributor: libpak.NewLayerContributor("test", map[string]interface{}{}, libcnb.LayerTypes{Cache: true}),
+			Logger:           bard.NewLogger(ioutil.Discard),
+			SBOMScanner:      sbomScanner,
+			RestoreOwnership: &libbs.Ownership{UID: 1001, GID: 1002},
+		}
+
+		layer, err := ctx.Layers.Layer("test-layer")
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = application.Contribute(layer)
+		Expect(err).NotTo(HaveOccurred())
+
+		fileInfo, err := os.Stat(filepath.Join(ctx.Application.Path, "fixture-marker"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fileInfo.Sys().(*syscall.Stat_t).Uid).To(Equal(uint32(1001)))
+		Expect(fileInfo.Sys().(*syscall.Stat_t).Gid).To(Equal(uint32(1002)))
+	})
+}
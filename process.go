@@ -0,0 +1,116 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/buildpacks/libcnb"
+)
+
+// ProcessArtifacts returns the paths that the artifacts resolved with the ProcessArtifactPatterns are restored to,
+// keyed by their process type, from the resolved artifacts recorded in the metadata of a contributed layer, so that a
+// process can be registered for each of them, e.g. with SuggestProcess.
+func (a Application) ProcessArtifacts(layer libcnb.Layer) map[string]string {
+	processes := map[string]string{}
+	for _, r := range recordedArtifacts(layer.Metadata[ResolvedArtifactsMetadataKey]) {
+		if r.Process != "" {
+			processes[r.Process] = filepath.Join(a.restorePath(), filepath.Dir(r.Path), r.Name)
+		}
+	}
+
+	return processes
+}
+
+// resolveArtifacts resolves the artifacts built in applicationPath, with the ProcessArtifactPatterns if any are set,
+// returning the process type of each artifact if so.
+func (a Application) resolveArtifacts(applicationPath string) ([]string, []string, error) {
+	if len(a.ProcessArtifactPatterns) == 0 {
+		artifacts, err := a.ArtifactResolver.ResolveMany(applicationPath)
+		return nil, artifacts, err
+	}
+
+	resolver := a.ArtifactResolver
+	if resolver.InterestingFileDetector == nil {
+		resolver.InterestingFileDetector = JARInterestingFileDetector{}
+	}
+	outputPath := resolver.outputPath(applicationPath)
+
+	var processes []string
+	for p := range a.ProcessArtifactPatterns {
+		processes = append(processes, p)
+	}
+	sort.Strings(processes)
+
+	var artifacts []string
+	for _, p := range processes {
+		artifact, candidates, err := resolver.resolvePattern(outputPath, a.ProcessArtifactPatterns[p], nil)
+		if err != nil {
+			return nil, nil, err
+		} else if artifact == "" {
+			return nil, nil, fmt.Errorf("unable to find single built artifact for process %s in %s, candidates: %s",
+				p, a.ProcessArtifactPatterns[p], candidates)
+		}
+		artifacts = append(artifacts, artifact)
+	}
+
+	return processes, artifacts, nil
+}
+
+// persistProcesses copies each of the artifacts into a directory of the layer named for its process type, handling
+// them once copied like persist.
+func (a Application) persistProcesses(layer libcnb.Layer, processes []string, artifacts []string) ([]ResolvedArtifact, error) {
+	var resolved []ResolvedArtifact
+
+	for i, artifact := range artifacts {
+		fileInfo, err := os.Stat(artifact)
+		if err != nil {
+			return nil, fmt.Errorf("unable to resolve artifact %s\n%w", artifact, err)
+		}
+
+		dir := filepath.Join(layer.Path, processes[i])
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return nil, fmt.Errorf("unable to create directory %s\n%w", dir, err)
+		}
+
+		dest := filepath.Join(dir, fileInfo.Name())
+		if fileInfo.IsDir() {
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				return nil, fmt.Errorf("unable to create directory %s\n%w", dest, err)
+			}
+			if err := a.copier().CopyDir(artifact, dest); err != nil {
+				return nil, fmt.Errorf("unable to copy the directory\n%w", err)
+			}
+		} else if err := a.copier().CopyFile(artifact, dest); err != nil {
+			return nil, fmt.Errorf("unable to copy the file %s to %s\n%w", artifact, dest, err)
+		}
+
+		r, err := a.describePersisted(layer, artifact, fileInfo, dest, false)
+		if err != nil {
+			return nil, err
+		}
+		r.Process = processes[i]
+		a.Logger.Bodyf("Persisted %s for process %s", fileInfo.Name(), r.Process)
+
+		resolved = append(resolved, r)
+	}
+
+	return resolved, nil
+}
@@ -0,0 +1,122 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/paketo-buildpacks/libpak/bard"
+)
+
+// snapshotWorkspace copies the workspace, as it is before the build, to a temporary directory so that it can be built
+// again by verifyReproducible.  The cache is not copied.
+func (a Application) snapshotWorkspace() (string, error) {
+	dir, err := os.MkdirTemp("", "reproducible")
+	if err != nil {
+		return "", fmt.Errorf("unable to create temporary directory\n%w", err)
+	}
+
+	if err := a.withoutCacheLink(func() error {
+		return copyDirectory(a.ApplicationPath, dir)
+	}); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", fmt.Errorf("unable to copy %s to %s\n%w", a.ApplicationPath, dir, err)
+	}
+
+	return dir, nil
+}
+
+// verifyReproducible builds the snapshot of the workspace a second time and fails if the artifacts it resolves differ
+// from the artifacts of the first build.
+func (a Application) verifyReproducible(snapshot string, artifacts []string) error {
+	a.Logger.Header("Verifying that the build is reproducible")
+	a.Logger.Bodyf("Executing %s %s in %s", filepath.Base(a.Command), strings.Join(a.Arguments, " "), snapshot)
+
+	if err := a.runBuild(snapshot,
+		bard.NewWriter(a.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+		bard.NewWriter(a.Logger.Logger.InfoWriter(), bard.WithIndent(3)),
+	); err != nil {
+		return fmt.Errorf("reproducibility build failed\n%w", err)
+	}
+	a.Logger.Info()
+
+	_, rebuilt, err := a.resolveArtifacts(snapshot)
+	if err != nil {
+		return fmt.Errorf("unable to resolve artifacts of reproducibility build\n%w", err)
+	}
+
+	expected, err := digests(a.ApplicationPath, artifacts)
+	if err != nil {
+		return err
+	}
+	actual, err := digests(snapshot, rebuilt)
+	if err != nil {
+		return err
+	}
+
+	var names []string
+	for name := range expected {
+		names = append(names, name)
+	}
+	for name := range actual {
+		if _, ok := expected[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var diff []string
+	for _, name := range names {
+		e, eOk := expected[name]
+		r, rOk := actual[name]
+
+		switch {
+		case !rOk:
+			diff = append(diff, fmt.Sprintf("%s: missing from the second build", name))
+		case !eOk:
+			diff = append(diff, fmt.Sprintf("%s: missing from the first build", name))
+		case e != r:
+			diff = append(diff, fmt.Sprintf("%s: %s != %s", name, e, r))
+		}
+	}
+
+	if len(diff) > 0 {
+		return fmt.Errorf("build is not reproducible, artifacts differ:\n%s", strings.Join(diff, "\n"))
+	}
+
+	a.Logger.Bodyf("Artifacts are identical: %s", names)
+	return nil
+}
+
+// digests returns the SHA256 of each of the artifacts, keyed by their path relative to root.
+func digests(root string, artifacts []string) (map[string]string, error) {
+	d := make(map[string]string, len(artifacts))
+
+	for _, artifact := range artifacts {
+		r, err := describeArtifact(filepath.Base(artifact), root, artifact)
+		if err != nil {
+			return nil, fmt.Errorf("unable to describe artifact %s\n%w", artifact, err)
+		}
+		d[r.Path] = r.SHA256
+	}
+
+	return d, nil
+}
@@ -18,15 +18,20 @@ package libbs
 
 import (
 	"archive/zip"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"path/filepath"
-	"sort"
+	"regexp"
+	"regexp/syntax"
 	"strings"
+	"text/template"
 
 	"github.com/magiconair/properties"
 	"github.com/mattn/go-shellwords"
 	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
 )
 
 //go:generate mockery -name InterestingFileDetector -case=underscore
@@ -46,11 +51,30 @@ func (AlwaysInterestingFileDetector) Interesting(path string) (bool, error) {
 	return true, nil
 }
 
+// DefaultJARMinimumSize is the size, in bytes, of an empty zip archive.  No smaller file can be a JAR.
+const DefaultJARMinimumSize = 22
+
 // JARInterestingFileDetector is an implementation of InterestingFileDetector that returns true if the path represents
 // a JAR file with a Main-Class manifest entry or a WAR file with a WEB-INF/ directory.
-type JARInterestingFileDetector struct{}
+type JARInterestingFileDetector struct {
+
+	// MinimumSize is the size, in bytes, below which a file is not interesting, without being opened, e.g. to skip
+	// placeholder JARs.  Defaults to DefaultJARMinimumSize.
+	MinimumSize int64
+}
 
 func (j JARInterestingFileDetector) Interesting(path string) (bool, error) {
+	min := j.MinimumSize
+	if min <= 0 {
+		min = DefaultJARMinimumSize
+	}
+
+	if fileInfo, err := os.Stat(path); err != nil {
+		return false, fmt.Errorf("unable to stat %s\n%w", path, err)
+	} else if fileInfo.Size() < min {
+		return false, nil
+	}
+
 	z, err := zip.OpenReader(path)
 	if err != nil {
 		return false, fmt.Errorf("unable to open %s\n%w", path, err)
@@ -74,23 +98,114 @@ func (JARInterestingFileDetector) entry(f *zip.File) (bool, error) {
 	}
 
 	if f.Name == "META-INF/MANIFEST.MF" {
-		m, err := f.Open()
+		p, err := manifest(f)
 		if err != nil {
-			return false, fmt.Errorf("unable to open %s\n%w", f.Name, err)
+			return false, err
 		}
-		defer m.Close()
 
-		b, err := ioutil.ReadAll(m)
-		if err != nil {
-			return false, fmt.Errorf("unable to read %s\n%w", f.Name, err)
+		if _, ok := p.Get("Main-Class"); ok {
+			return true, nil
 		}
+	}
 
-		p, err := properties.Load(b, properties.UTF8)
-		if err != nil {
-			return false, fmt.Errorf("unable to parse properties in %s\n%w", f.Name, err)
+	return false, nil
+}
+
+// ContentTypeFileDetector is an implementation of InterestingFileDetector that returns true if the path represents a
+// file whose content, as determined by its magic bytes rather than its name, is one of the ContentTypes.
+type ContentTypeFileDetector struct {
+
+	// ContentTypes are the content types that are interesting.
+	ContentTypes []ContentType
+}
+
+func (c ContentTypeFileDetector) Interesting(path string) (bool, error) {
+	if fileInfo, err := os.Stat(path); err != nil {
+		return false, fmt.Errorf("unable to stat %s\n%w", path, err)
+	} else if fileInfo.IsDir() {
+		return false, nil
+	}
+
+	t, err := DetectContentType(path)
+	if err != nil {
+		return false, fmt.Errorf("unable to detect content type of %s\n%w", path, err)
+	}
+
+	return containsContentType(c.ContentTypes, t), nil
+}
+
+// TarInterestingFileDetector is an implementation of InterestingFileDetector that returns true if the path represents
+// a tarball, uncompressed or gzip compressed, such as a distribution from sbt-native-packager or the Gradle
+// distribution plugin.  The content, rather than the name, of the file is inspected.
+type TarInterestingFileDetector struct{}
+
+func (TarInterestingFileDetector) Interesting(path string) (bool, error) {
+	if fileInfo, err := os.Stat(path); err != nil {
+		return false, fmt.Errorf("unable to stat %s\n%w", path, err)
+	} else if fileInfo.IsDir() {
+		return false, nil
+	}
+
+	t, err := DetectContentType(path)
+	if err != nil {
+		return false, fmt.Errorf("unable to detect content type of %s\n%w", path, err)
+	}
+
+	switch t {
+	case ContentTypeTar:
+		return true, nil
+	case ContentTypeGzip:
+		return gzipTarball(path)
+	default:
+		return false, nil
+	}
+}
+
+// NativeExecutableFileDetector is an implementation of InterestingFileDetector that returns true if the path represents
+// a regular file with an execute bit set whose content is a native executable, such as the output of GraalVM
+// native-image.  Executable scripts are not interesting.
+type NativeExecutableFileDetector struct{}
+
+func (NativeExecutableFileDetector) Interesting(path string) (bool, error) {
+	if fileInfo, err := os.Stat(path); err != nil {
+		return false, fmt.Errorf("unable to stat %s\n%w", path, err)
+	} else if !fileInfo.Mode().IsRegular() || fileInfo.Mode().Perm()&0111 == 0 {
+		return false, nil
+	}
+
+	ok, err := isNativeBinary(path)
+	if err != nil {
+		return false, fmt.Errorf("unable to detect content type of %s\n%w", path, err)
+	}
+
+	return ok, nil
+}
+
+// NativeImageDirectoryDetector is an implementation of InterestingFileDetector that returns true if the path represents
+// a directory that contains a native executable, such as the output directory of GraalVM native-image.  The directory
+// is persisted as a whole, so the auxiliary files, such as shared libraries, are restored alongside the executable.
+type NativeImageDirectoryDetector struct{}
+
+func (NativeImageDirectoryDetector) Interesting(path string) (bool, error) {
+	if fileInfo, err := os.Stat(path); err != nil {
+		return false, fmt.Errorf("unable to stat %s\n%w", path, err)
+	} else if !fileInfo.IsDir() {
+		return false, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return false, fmt.Errorf("unable to read directory %s\n%w", path, err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
 		}
 
-		if _, ok := p.Get("Main-Class"); ok {
+		if ok, err := (NativeExecutableFileDetector{}).Interesting(filepath.Join(path, e.Name())); err != nil {
+			return false, err
+		} else if ok {
 			return true, nil
 		}
 	}
@@ -98,7 +213,46 @@ func (JARInterestingFileDetector) entry(f *zip.File) (bool, error) {
 	return false, nil
 }
 
-// ArtifactResolver provides functionality for resolve build system built artifacts.
+// NativeImageAuxiliaryPatterns are the globs of the auxiliary files, such as the shared libraries and configuration
+// files, that GraalVM native-image writes alongside a native executable and that must be shipped with it.
+var NativeImageAuxiliaryPatterns = []string{"*.so", "*.dylib", "*.dll", "*-config.json"}
+
+// manifest parses a META-INF/MANIFEST.MF zip entry.
+func manifest(f *zip.File) (*properties.Properties, error) {
+	m, err := f.Open()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s\n%w", f.Name, err)
+	}
+	defer m.Close()
+
+	b, err := ioutil.ReadAll(m)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", f.Name, err)
+	}
+
+	p, err := properties.Load(b, properties.UTF8)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse properties in %s\n%w", f.Name, err)
+	}
+
+	return p, nil
+}
+
+// ArtifactPatternType is the syntax of an artifact pattern.
+type ArtifactPatternType string
+
+const (
+	// ArtifactPatternGlob is a space separated list of globs, relative to the application path.  This is the default.
+	ArtifactPatternGlob ArtifactPatternType = "glob"
+
+	// ArtifactPatternRegexp is a regular expression that must match the whole path of an artifact, relative to the
+	// application path and separated by /.
+	ArtifactPatternRegexp ArtifactPatternType = "regexp"
+)
+
+// ArtifactResolver provides functionality for resolve build system built artifacts.  Artifact patterns are resolved
+// against $BP_BUILD_OUTPUT_DIR, if set, rather than the application path.  A relative $BP_BUILD_OUTPUT_DIR is relative
+// to the application path.
 type ArtifactResolver struct {
 
 	// ArtifactConfigurationKey is the environment variable key to lookup for user configured artifacts.
@@ -115,92 +269,644 @@ type ArtifactResolver struct {
 
 	// AdditionalHelpMessage can be used to supply context specific instructions if no matching artifact is found
 	AdditionalHelpMessage string
+
+	// ArtifactPatternType is the syntax of the pattern and the FallbackPatterns.  Defaults to ArtifactPatternGlob.
+	ArtifactPatternType ArtifactPatternType
+
+	// CaseInsensitive, if true, matches artifact patterns against file names without regard to case.
+	CaseInsensitive bool
+
+	// MarkerFile is the path, relative to the application path, of a file written by the build that contains the
+	// relative path of the built artifact.  If set and the file exists, its contents are used instead of globbing.
+	MarkerFile string
+
+	// SkipHidden determines whether ResolveMany skips dot-prefixed files and directories, such as .git/, that are
+	// matched by a wildcard.  If nil, hidden directories are skipped and hidden files are kept.  Entries named
+	// explicitly by a dot-prefixed pattern segment are never skipped.
+	SkipHidden *bool
+
+	// PreferLatestVersion, if true, resolves the candidate with the highest version in its name, e.g. app-1.1.jar over
+	// app-1.0.jar, when a pattern matches multiple candidates.
+	PreferLatestVersion bool
+
+	// SnapshotRanking determines how SNAPSHOT versions are ranked by PreferLatestVersion.  Defaults to
+	// SnapshotRankingPreRelease.
+	SnapshotRanking SnapshotRanking
+
+	// DescendIntoArchives, if true, replaces a candidate that is an archive, such as a distribution zip, with the single
+	// interesting JAR it contains.  The archive is extracted to a temporary directory that Cleanup removes.
+	DescendIntoArchives bool
+
+	// FallbackPatterns are the space separated lists of globs that are tried, in order, when the pattern does not
+	// resolve an artifact, e.g. build/libs/*.jar after build/libs/*-all.jar.  The first that resolves an artifact wins.
+	FallbackPatterns []string
+
+	// IgnoreFile, if set, is the path, relative to the application path, of a gitignore-style file that lists the
+	// source of the application.  The artifacts are then resolved, instead of with the pattern, as the files that the
+	// file does not ignore and that the InterestingFileDetector, or a JARInterestingFileDetector if none is set, finds
+	// interesting.  Only a subset of the gitignore syntax is supported: comments, negation with !, directory rules
+	// ending in /, rules anchored by a /, and ** wildcards.
+	IgnoreFile string
+
+	// NativeImageAuxiliaryFiles, if true, adds the files matching the NativeImageAuxiliaryPatterns alongside each native
+	// executable resolved by ResolveMany to the artifacts so that they are persisted and restored with it.
+	NativeImageAuxiliaryFiles bool
+
+	// Logger is the logger used to write to the console.  If debug logging is enabled, the patterns tried and the
+	// candidates they match are logged.
+	Logger bard.Logger
+
+	// configured are the configuration keys whose defaults were set by a Config, which are treated as explicitly
+	// configured like those set in the environment.
+	configured []string
+
+	// extracted are the temporary directories that archives were extracted to when descending into them.
+	extracted []string
+}
+
+// Cleanup removes the temporary directories that archives were extracted to by DescendIntoArchives.  An artifact
+// resolved from within an archive no longer exists once the resolver has been cleaned up.
+func (a *ArtifactResolver) Cleanup() error {
+	for _, dir := range a.extracted {
+		if err := os.RemoveAll(dir); err != nil {
+			return fmt.Errorf("unable to remove %s\n%w", dir, err)
+		}
+	}
+	a.extracted = nil
+
+	return nil
 }
 
 // Pattern returns the space separated list of globs that ArtifactResolver will use for resolution.
 func (a *ArtifactResolver) Pattern() string {
-	pattern, ok := a.ConfigurationResolver.Resolve(a.ArtifactConfigurationKey)
+	pattern, ok := a.resolveConfigured(a.ArtifactConfigurationKey)
 	if ok {
 		return pattern
 	}
-	if module, ok := a.ConfigurationResolver.Resolve(a.ModuleConfigurationKey); ok {
+	if module, ok := a.resolveConfigured(a.ModuleConfigurationKey); ok {
 		return filepath.Join(module, pattern)
 	}
 	return pattern
 }
 
+// resolveConfigured resolves the value of a configuration key, and whether it was explicitly configured, either in the
+// environment or by a Config.
+func (a *ArtifactResolver) resolveConfigured(name string) (string, bool) {
+	v, ok := a.ConfigurationResolver.Resolve(name)
+	return v, ok || (name != "" && contains(a.configured, name))
+}
+
 // Resolve resolves the artifact that was created by the build system.
 func (a *ArtifactResolver) Resolve(applicationPath string) (string, error) {
-	pattern := a.Pattern()
-	file := filepath.Join(applicationPath, pattern)
-	candidates, err := filepath.Glob(file)
+	return a.resolve(applicationPath, nil)
+}
+
+func (a *ArtifactResolver) resolve(applicationPath string, tieBreaker func([]string) []string) (string, error) {
+	if artifact, ok, err := a.marker(applicationPath); err != nil {
+		return "", err
+	} else if ok {
+		a.Logger.Debugf("Artifact %s resolved from marker file %s", artifact, a.MarkerFile)
+		return artifact, nil
+	}
+
+	if candidates, ok, err := a.unignored(applicationPath); err != nil {
+		return "", err
+	} else if ok {
+		if len(candidates) == 1 {
+			return candidates[0], nil
+		}
+		return "", fmt.Errorf("unable to find single built artifact not ignored by %s, candidates: %s", a.IgnoreFile, candidates)
+	}
+
+	patterns := append([]string{a.Pattern()}, a.FallbackPatterns...)
+	outputPath := a.outputPath(applicationPath)
+
+	var tried []string
+	for _, pattern := range patterns {
+		artifact, candidates, err := a.resolvePattern(outputPath, pattern, tieBreaker)
+		if err != nil {
+			return "", err
+		} else if artifact != "" {
+			return artifact, nil
+		}
+
+		tried = append(tried, fmt.Sprintf("%s, candidates: %s", pattern, candidates))
+	}
+
+	helpMsg := fmt.Sprintf("unable to find single built artifact in %s", tried[0])
+	if len(tried) > 1 {
+		helpMsg = fmt.Sprintf("unable to find single built artifact in any of the patterns:\n%s", strings.Join(tried, "\n"))
+	}
+	if len(a.AdditionalHelpMessage) > 0 {
+		helpMsg = fmt.Sprintf("%s. %s", helpMsg, a.AdditionalHelpMessage)
+	}
+	return "", fmt.Errorf(helpMsg)
+}
+
+// resolvePattern resolves the single artifact matched by pattern.  If no single artifact is matched, the artifact is
+// empty and the candidates matched are returned.
+func (a *ArtifactResolver) resolvePattern(applicationPath string, pattern string, tieBreaker func([]string) []string) (string, []string, error) {
+	candidates, err := a.find(applicationPath, pattern)
 	if err != nil {
-		return "", fmt.Errorf("unable to find files with %s\n%w", pattern, err)
+		return "", nil, fmt.Errorf("unable to find files with %s\n%w", pattern, err)
+	}
+	a.Logger.Debugf("Artifact pattern %s in %s matched candidates %s", pattern, applicationPath, candidates)
+
+	if a.DescendIntoArchives {
+		if candidates, err = a.descend(candidates); err != nil {
+			return "", nil, err
+		}
 	}
 
 	if len(candidates) == 1 {
-		return candidates[0], nil
+		return candidates[0], nil, nil
+	}
+
+	if tieBreaker != nil {
+		if cs := tieBreaker(candidates); len(cs) == 1 {
+			return cs[0], nil, nil
+		} else if len(cs) > 1 {
+			candidates = cs
+		}
+	}
+
+	if a.PreferLatestVersion {
+		if latest, ok := latestVersion(candidates, a.SnapshotRanking); ok {
+			a.Logger.Bodyf("Resolved latest version %s from candidates %s", latest, candidates)
+			return latest, nil, nil
+		}
 	}
 
 	var artifacts []string
 	for _, c := range candidates {
 		if ok, err := a.InterestingFileDetector.Interesting(c); err != nil {
-			return "", fmt.Errorf("unable to investigate %s\n%w", c, err)
+			return "", nil, fmt.Errorf("unable to investigate %s\n%w", c, err)
 		} else if ok {
 			artifacts = append(artifacts, c)
+		} else {
+			a.Logger.Debugf("Ignoring candidate %s, not interesting", c)
 		}
 	}
 
 	if len(artifacts) == 1 {
-		return artifacts[0], nil
+		return artifacts[0], nil, nil
+	}
+
+	return "", candidates, nil
+}
+
+func (a *ArtifactResolver) ResolveMany(applicationPath string) ([]string, error) {
+	if artifact, ok, err := a.marker(applicationPath); err != nil {
+		return []string{}, err
+	} else if ok {
+		a.Logger.Debugf("Artifact %s resolved from marker file %s", artifact, a.MarkerFile)
+		return []string{artifact}, nil
+	}
+
+	if candidates, ok, err := a.unignored(applicationPath); err != nil {
+		return []string{}, err
+	} else if ok {
+		if len(candidates) == 0 {
+			return []string{}, fmt.Errorf("unable to find any built artifacts not ignored by %s", a.IgnoreFile)
+		}
+		return candidates, nil
 	}
 
-	sort.Strings(artifacts)
-	helpMsg := fmt.Sprintf("unable to find single built artifact in %s, candidates: %s", pattern, candidates)
+	outputPath := a.outputPath(applicationPath)
+
+	var tried []string
+	for _, pattern := range append([]string{a.Pattern()}, a.FallbackPatterns...) {
+		candidates, patterns, err := a.resolveManyPattern(outputPath, pattern)
+		if err != nil {
+			return []string{}, err
+		} else if len(candidates) > 0 && a.NativeImageAuxiliaryFiles {
+			return a.auxiliaryFiles(candidates)
+		} else if len(candidates) > 0 {
+			return candidates, nil
+		}
+
+		tried = append(tried, patterns...)
+	}
+
+	helpMsg := fmt.Sprintf("unable to find any built artifacts for pattern(s):\n%s", strings.Join(tried, "\n"))
 	if len(a.AdditionalHelpMessage) > 0 {
 		helpMsg = fmt.Sprintf("%s. %s", helpMsg, a.AdditionalHelpMessage)
 	}
-	return "", fmt.Errorf(helpMsg)
+	return []string{}, fmt.Errorf(helpMsg)
 }
 
-func (a *ArtifactResolver) ResolveMany(applicationPath string) ([]string, error) {
-	pattern := a.Pattern()
-
-	patterns, err := shellwords.Parse(pattern)
-	if err != nil {
-		return []string{}, fmt.Errorf("unable to parse shellwords patterns\n%w", err)
+// resolveManyPattern resolves the artifacts matched by a space separated list of globs, returning the individual
+// globs that were tried.
+func (a *ArtifactResolver) resolveManyPattern(applicationPath string, pattern string) ([]string, []string, error) {
+	patterns := []string{pattern}
+	if !a.regexpPatterns() {
+		var err error
+		if patterns, err = shellwords.Parse(pattern); err != nil {
+			return nil, nil, fmt.Errorf("unable to parse shellwords patterns\n%w", err)
+		}
 	}
 
 	var candidates []string
 	var badPatterns []string
 	for _, pattern := range patterns {
-		file := filepath.Join(applicationPath, pattern)
-		cs, err := filepath.Glob(file)
-		if err != nil {
-			// err will only be ErrBadPattern / "syntax error in pattern"
+		cs, err := a.find(applicationPath, pattern)
+		var syntaxErr *syntax.Error
+		if errors.Is(err, filepath.ErrBadPattern) || errors.As(err, &syntaxErr) {
 			badPatterns = append(badPatterns, pattern)
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("unable to find files with %s\n%w", pattern, err)
+		}
+		a.Logger.Debugf("Artifact pattern %s in %s matched candidates %s", pattern, applicationPath, cs)
+		for _, c := range cs {
+			if a.hidden(applicationPath, pattern, c) {
+				a.Logger.Debugf("Ignoring hidden candidate %s", c)
+				continue
+			}
+			candidates = append(candidates, c)
 		}
-		candidates = append(candidates, cs...)
 	}
 
 	if len(badPatterns) > 0 {
-		return []string{}, fmt.Errorf("unable to proceed due to bad pattern(s):\n%s", strings.Join(badPatterns, "\n"))
+		return nil, nil, fmt.Errorf("unable to proceed due to bad pattern(s):\n%s", strings.Join(badPatterns, "\n"))
 	}
 
-	if len(candidates) > 0 {
-		return candidates, nil
+	return candidates, patterns, nil
+}
+
+// auxiliaryFiles adds the files matching the NativeImageAuxiliaryPatterns alongside each native executable in
+// candidates to the candidates.
+func (a *ArtifactResolver) auxiliaryFiles(candidates []string) ([]string, error) {
+	artifacts := append([]string{}, candidates...)
+	for _, c := range candidates {
+		if ok, err := (NativeExecutableFileDetector{}).Interesting(c); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
+		for _, p := range NativeImageAuxiliaryPatterns {
+			files, err := filepath.Glob(filepath.Join(filepath.Dir(c), p))
+			if err != nil {
+				return nil, fmt.Errorf("unable to find files with %s\n%w", p, err)
+			}
+
+			for _, f := range files {
+				if !contains(artifacts, f) {
+					a.Logger.Debugf("Adding native-image auxiliary file %s", f)
+					artifacts = append(artifacts, f)
+				}
+			}
+		}
 	}
 
-	helpMsg := fmt.Sprintf("unable to find any built artifacts for pattern(s):\n%s", strings.Join(patterns, "\n"))
-	if len(a.AdditionalHelpMessage) > 0 {
-		helpMsg = fmt.Sprintf("%s. %s", helpMsg, a.AdditionalHelpMessage)
+	return artifacts, nil
+}
+
+// outputPath returns the path that artifact patterns are resolved against: $BP_BUILD_OUTPUT_DIR, relative to
+// applicationPath unless absolute, if configured, otherwise applicationPath.
+func (a *ArtifactResolver) outputPath(applicationPath string) string {
+	dir, ok := a.ConfigurationResolver.Resolve("BP_BUILD_OUTPUT_DIR")
+	if !ok || dir == "" {
+		return applicationPath
 	}
-	return []string{}, fmt.Errorf(helpMsg)
+
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(applicationPath, dir)
+	}
+	a.Logger.Debugf("Resolving artifacts in build output directory %s", dir)
+
+	return dir
+}
+
+// regexpPatterns determines whether patterns are regular expressions.
+func (a *ArtifactResolver) regexpPatterns() bool {
+	return a.ArtifactPatternType == ArtifactPatternRegexp
+}
+
+// find returns the files below applicationPath that match pattern, according to the ArtifactPatternType.
+func (a *ArtifactResolver) find(applicationPath string, pattern string) ([]string, error) {
+	switch a.ArtifactPatternType {
+	case "", ArtifactPatternGlob:
+		return a.glob(applicationPath, pattern)
+	case ArtifactPatternRegexp:
+		return a.match(applicationPath, pattern)
+	default:
+		return nil, fmt.Errorf("unsupported artifact pattern type %s", a.ArtifactPatternType)
+	}
+}
+
+// match returns the files and directories below applicationPath whose relative, / separated, path is matched in
+// full by the regular expression pattern.  .git directories are not searched.
+func (a *ArtifactResolver) match(applicationPath string, pattern string) ([]string, error) {
+	expr := fmt.Sprintf("^(?:%s)$", pattern)
+	if a.CaseInsensitive {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var candidates []string
+	if err := filepath.Walk(applicationPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(applicationPath, path)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		if info.IsDir() && info.Name() == ".git" {
+			return filepath.SkipDir
+		}
+
+		if re.MatchString(filepath.ToSlash(rel)) {
+			candidates = append(candidates, path)
+		}
+
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("unable to walk %s\n%w", applicationPath, err)
+	}
+
+	return candidates, nil
+}
+
+// glob returns the files below applicationPath that match pattern.
+func (a *ArtifactResolver) glob(applicationPath string, pattern string) ([]string, error) {
+	// patterns, even absolute ones, are relative to the application path and may contain .. to reach a sibling of a
+	// module, but not to escape the application path
+	rel, err := filepath.Rel(applicationPath, filepath.Join(applicationPath, pattern))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("pattern %s resolves outside of %s", pattern, applicationPath)
+	}
+
+	if !a.CaseInsensitive {
+		return filepath.Glob(filepath.Join(applicationPath, rel))
+	}
+
+	candidates := []string{applicationPath}
+	for _, segment := range strings.Split(rel, string(filepath.Separator)) {
+		segment = strings.ToLower(segment)
+		if _, err := filepath.Match(segment, ""); err != nil {
+			return nil, err
+		}
+
+		var next []string
+		for _, c := range candidates {
+			if segment == "." || segment == ".." {
+				next = append(next, filepath.Join(c, segment))
+				continue
+			}
+
+			cs, err := os.ReadDir(c)
+			if err != nil {
+				// mirror filepath.Glob, which ignores I/O errors
+				continue
+			}
+
+			for _, f := range cs {
+				if ok, _ := filepath.Match(segment, strings.ToLower(f.Name())); ok {
+					next = append(next, filepath.Join(c, f.Name()))
+				}
+			}
+		}
+		candidates = next
+	}
+
+	return candidates, nil
+}
+
+// descend replaces each candidate that is an archive with the single interesting JAR that it contains.
+func (a *ArtifactResolver) descend(candidates []string) ([]string, error) {
+	var descended []string
+
+	for _, c := range candidates {
+		if ok, err := isArchive(c); err != nil {
+			return nil, err
+		} else if !ok {
+			descended = append(descended, c)
+			continue
+		}
+
+		dir, err := os.MkdirTemp("", "artifact-archive")
+		if err != nil {
+			return nil, fmt.Errorf("unable to create temporary directory\n%w", err)
+		}
+
+		if err := extract(c, dir); err != nil {
+			_ = os.RemoveAll(dir)
+			return nil, err
+		}
+
+		var jars []string
+		if err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if info.IsDir() || !isZip(path) {
+				return nil
+			}
+
+			if ok, err := a.InterestingFileDetector.Interesting(path); err != nil {
+				return fmt.Errorf("unable to investigate %s\n%w", path, err)
+			} else if ok {
+				jars = append(jars, path)
+			}
+
+			return nil
+		}); err != nil {
+			_ = os.RemoveAll(dir)
+			return nil, fmt.Errorf("unable to walk %s\n%w", dir, err)
+		}
+
+		if len(jars) == 1 {
+			a.Logger.Debugf("Descended into archive %s, found %s", c, jars[0])
+			descended = append(descended, jars[0])
+			a.extracted = append(a.extracted, dir)
+		} else {
+			a.Logger.Debugf("Not descending into archive %s, interesting candidates %s", c, jars)
+			descended = append(descended, c)
+
+			if err := os.RemoveAll(dir); err != nil {
+				return nil, fmt.Errorf("unable to remove %s\n%w", dir, err)
+			}
+		}
+	}
+
+	return descended, nil
+}
+
+// hidden determines whether candidate, matched by pattern, should be skipped because a wildcard matched a hidden file
+// or directory.
+func (a *ArtifactResolver) hidden(applicationPath string, pattern string, candidate string) bool {
+	if a.SkipHidden != nil && !*a.SkipHidden || a.regexpPatterns() {
+		return false
+	}
+
+	rel, err := filepath.Rel(applicationPath, candidate)
+	if err != nil {
+		return false
+	}
+
+	segments := strings.Split(filepath.Clean(pattern), string(filepath.Separator))
+	names := strings.Split(rel, string(filepath.Separator))
+	if len(segments) != len(names) {
+		return false
+	}
+
+	for i, name := range names {
+		if !strings.HasPrefix(name, ".") || name == "." || name == ".." || strings.HasPrefix(segments[i], ".") {
+			continue
+		}
+
+		if a.SkipHidden != nil {
+			return true
+		}
+
+		if i < len(names)-1 {
+			return true
+		}
+		if fileInfo, err := os.Stat(candidate); err == nil && fileInfo.IsDir() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// marker returns the artifact named by the MarkerFile, if one is configured and has been written by the build.
+func (a *ArtifactResolver) marker(applicationPath string) (string, bool, error) {
+	if a.MarkerFile == "" {
+		return "", false, nil
+	}
+
+	file := filepath.Join(applicationPath, a.MarkerFile)
+	b, err := os.ReadFile(file)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, fmt.Errorf("unable to read marker file %s\n%w", file, err)
+	}
+
+	path := strings.TrimSpace(string(b))
+	if path == "" {
+		return "", false, nil
+	}
+
+	artifact := filepath.Join(applicationPath, path)
+	if _, err := os.Stat(artifact); err != nil {
+		return "", false, fmt.Errorf("unable to find artifact %s named by marker file %s\n%w", artifact, file, err)
+	}
+
+	return artifact, true, nil
+}
+
+// AuxiliaryClassifiers are the artifact name suffixes of secondary artifacts, such as source and javadoc JARs, that
+// are never launchable.
+var AuxiliaryClassifiers = []string{"-sources", "-javadoc", "-tests", "-test-fixtures", "-plain"}
+
+// ResolveLaunchable resolves the single launchable artifact created by the build system without contributing.  When
+// the resolver's pattern matches multiple candidates, artifacts named with one of the AuxiliaryClassifiers are
+// discarded before the resolver's InterestingFileDetector, or a JARInterestingFileDetector if none is set, is used to
+// break the tie.
+func ResolveLaunchable(applicationPath string, resolver ArtifactResolver) (string, error) {
+	if resolver.InterestingFileDetector == nil {
+		resolver.InterestingFileDetector = JARInterestingFileDetector{}
+	}
+
+	return resolver.resolve(applicationPath, func(candidates []string) []string {
+		var launchable []string
+		for _, c := range candidates {
+			name := strings.TrimSuffix(filepath.Base(c), filepath.Ext(c))
+
+			auxiliary := false
+			for _, s := range AuxiliaryClassifiers {
+				if strings.HasSuffix(name, s) {
+					auxiliary = true
+					break
+				}
+			}
+
+			if !auxiliary {
+				launchable = append(launchable, c)
+			}
+		}
+		return launchable
+	})
+}
+
+// ResolveWAR resolves the single WAR created by the build system without contributing.  When the resolver's pattern
+// matches multiple WARs, as a reactor build may produce, the WAR within the module configured with the resolver's
+// ModuleConfigurationKey, e.g. $BP_MAVEN_BUILT_MODULE, is preferred.  If no single WAR is found, the error lists the
+// WARs and explains how to select one.
+func ResolveWAR(applicationPath string, resolver ArtifactResolver) (string, error) {
+	if resolver.InterestingFileDetector == nil {
+		resolver.InterestingFileDetector = JARInterestingFileDetector{}
+	}
+
+	module, _ := resolver.ConfigurationResolver.Resolve(resolver.ModuleConfigurationKey)
+
+	var wars []string
+	artifact, err := resolver.resolve(applicationPath, func(candidates []string) []string {
+		wars = nil
+		for _, c := range candidates {
+			if t, err := DetectArtifactType(c); err == nil && t == War {
+				wars = append(wars, c)
+			}
+		}
+
+		if len(wars) <= 1 || module == "" {
+			return wars
+		}
+
+		var preferred []string
+		for _, w := range wars {
+			if within(filepath.Join(applicationPath, module), w) {
+				preferred = append(preferred, w)
+			}
+		}
+		if len(preferred) > 0 {
+			return preferred
+		}
+		return wars
+	})
+	if err == nil || len(wars) <= 1 {
+		return artifact, err
+	}
+
+	if module != "" {
+		return "", fmt.Errorf("unable to find single built WAR in module %s, candidates: %s", module, wars)
+	} else if resolver.ModuleConfigurationKey != "" {
+		return "", fmt.Errorf("unable to find single built WAR, candidates: %s, set $%s to the module of the WAR "+
+			"to use", wars, resolver.ModuleConfigurationKey)
+	}
+	return "", fmt.Errorf("unable to find single built WAR, candidates: %s, configure a pattern that matches only "+
+		"the WAR to use", wars)
 }
 
 // ResolveArguments resolves the arguments that should be passed to a build system.
 func ResolveArguments(configurationKey string, configurationResolver libpak.ConfigurationResolver) ([]string, error) {
 	s, _ := configurationResolver.Resolve(configurationKey)
+	return parseArguments(s)
+}
+
+// ResolveExpandedArguments resolves the arguments that should be passed to a build system like ResolveArguments,
+// expanding references to environment variables, $VAR or ${VAR}, before the arguments are parsed, e.g.
+// -Dmaven.repo.local=${CACHE_DIR}.  $$ is a literal $.  Undefined variables expand to an empty string, unless
+// keepUnknown is true, in which case they are left untouched, e.g. for the build system to expand itself.
+func ResolveExpandedArguments(configurationKey string, configurationResolver libpak.ConfigurationResolver, keepUnknown bool) ([]string, error) {
+	s, _ := configurationResolver.Resolve(configurationKey)
+	return parseArguments(expandEnv(s, keepUnknown))
+}
+
+func parseArguments(s string) ([]string, error) {
 	w, err := shellwords.Parse(s)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse arguments from %s\n%w", s, err)
@@ -208,3 +914,83 @@ func ResolveArguments(configurationKey string, configurationResolver libpak.Conf
 
 	return w, nil
 }
+
+// ModuleArgumentsKey returns the configuration key of the extra arguments for module, a path relative to the root of
+// a multi-module build, e.g. BP_MAVEN_BUILD_ARGUMENTS_SERVICES_API for services/api.  The module is upper-cased and
+// any character other than a letter or digit is replaced with an underscore.
+func ModuleArgumentsKey(configurationKey string, module string) string {
+	name := strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, filepath.ToSlash(filepath.Clean(module)))
+
+	return fmt.Sprintf("%s_%s", configurationKey, strings.ToUpper(name))
+}
+
+// ResolveModuleArguments resolves the arguments that should be passed to a build system when building module, e.g. with
+// -pl in a reactor build.  The extra arguments configured for the module with ModuleArgumentsKey are appended to the
+// arguments configured with configurationKey, so that they take precedence over them.
+func ResolveModuleArguments(configurationKey string, module string, configurationResolver libpak.ConfigurationResolver) ([]string, error) {
+	args, err := ResolveArguments(configurationKey, configurationResolver)
+	if err != nil {
+		return nil, err
+	}
+
+	extra, err := ResolveArguments(ModuleArgumentsKey(configurationKey, module), configurationResolver)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(args, extra...), nil
+}
+
+// expandEnv expands the references to environment variables in s, leaving references to undefined variables untouched
+// if keepUnknown is true.  $$ expands to a literal $.
+func expandEnv(s string, keepUnknown bool) string {
+	return os.Expand(s, func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+
+		if v, ok := os.LookupEnv(name); ok {
+			return v
+		} else if keepUnknown {
+			return fmt.Sprintf("${%s}", name)
+		}
+		return ""
+	})
+}
+
+// RenderArguments renders the arguments that should be passed to a build system from a text/template, executed with
+// the environment as its data, and parses the result as shell words.  Missing environment variables render as empty
+// strings, and a default function is available to supply a value for them, e.g. {{ default "test" .PROFILE }}.
+func RenderArguments(tmpl string, env map[string]string) ([]string, error) {
+	t, err := template.New("arguments").
+		Option("missingkey=zero").
+		Funcs(template.FuncMap{
+			"default": func(def string, value string) string {
+				if value == "" {
+					return def
+				}
+				return value
+			},
+		}).
+		Parse(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse arguments template %s\n%w", tmpl, err)
+	}
+
+	b := &strings.Builder{}
+	if err := t.Execute(b, env); err != nil {
+		return nil, fmt.Errorf("unable to render arguments template %s\n%w", tmpl, err)
+	}
+
+	w, err := shellwords.Parse(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse arguments from %s\n%w", b.String(), err)
+	}
+
+	return w, nil
+}
@@ -17,6 +17,9 @@
 package libbs_test
 
 import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -25,6 +28,7 @@ import (
 
 	. "github.com/onsi/gomega"
 	"github.com/paketo-buildpacks/libpak"
+	"github.com/paketo-buildpacks/libpak/bard"
 	"github.com/sclevine/spec"
 	"github.com/stretchr/testify/mock"
 
@@ -44,6 +48,19 @@ func testResolvers(t *testing.T, context spec.G, it spec.S) {
 	})
 
 	context("JARInterestingFileDetector", func() {
+		var path string
+
+		it.Before(func() {
+			var err error
+
+			path, err = ioutil.TempDir("", "jar-interesting-file-detector")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(path)).To(Succeed())
+		})
+
 		it("passes for executable JAR", func() {
 			Expect(libbs.JARInterestingFileDetector{}.Interesting(filepath.Join("testdata", "stub-executable.jar"))).
 				To(BeTrue())
@@ -58,6 +75,263 @@ func testResolvers(t *testing.T, context spec.G, it spec.S) {
 			Expect(libbs.JARInterestingFileDetector{}.Interesting(filepath.Join("testdata", "stub-application.jar"))).
 				To(BeFalse())
 		})
+
+		it("fails for an empty file without opening it", func() {
+			file := filepath.Join(path, "test.jar")
+			Expect(ioutil.WriteFile(file, []byte{}, 0644)).To(Succeed())
+
+			Expect(libbs.JARInterestingFileDetector{}.Interesting(file)).To(BeFalse())
+		})
+
+		it("fails for a file smaller than the minimum size", func() {
+			file := filepath.Join(path, "test.jar")
+			Expect(ioutil.WriteFile(file, []byte("placeholder"), 0644)).To(Succeed())
+
+			Expect(libbs.JARInterestingFileDetector{MinimumSize: 1024}.Interesting(file)).To(BeFalse())
+		})
+
+		it("passes for executable JAR larger than the minimum size", func() {
+			Expect(libbs.JARInterestingFileDetector{MinimumSize: 64}.Interesting(filepath.Join("testdata", "stub-executable.jar"))).
+				To(BeTrue())
+		})
+
+		it("fails for a missing file", func() {
+			_, err := libbs.JARInterestingFileDetector{}.Interesting(filepath.Join(path, "test.jar"))
+			Expect(err).To(MatchError(HavePrefix("unable to stat")))
+		})
+	})
+
+	context("ContentTypeFileDetector", func() {
+		var path string
+
+		it.Before(func() {
+			var err error
+			path, err = ioutil.TempDir("", "content-type")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ioutil.WriteFile(filepath.Join(path, "test-elf"),
+				[]byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00}, 0755)).To(Succeed())
+
+			out, err := os.Create(filepath.Join(path, "test-archive.jar"))
+			Expect(err).NotTo(HaveOccurred())
+			g := gzip.NewWriter(out)
+			_, err = g.Write([]byte("test-content"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(g.Close()).To(Succeed())
+			Expect(out.Close()).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(path)).To(Succeed())
+		})
+
+		it("sniffs content types", func() {
+			Expect(libbs.DetectContentType(filepath.Join("testdata", "stub-application.jar"))).
+				To(Equal(libbs.ContentTypeZip))
+			Expect(libbs.DetectContentType(filepath.Join(path, "test-archive.jar"))).To(Equal(libbs.ContentTypeGzip))
+			Expect(libbs.DetectContentType(filepath.Join(path, "test-elf"))).To(Equal(libbs.ContentTypeELF))
+		})
+
+		it("passes for matching content types regardless of name", func() {
+			d := libbs.ContentTypeFileDetector{ContentTypes: []libbs.ContentType{libbs.ContentTypeZip}}
+
+			Expect(d.Interesting(filepath.Join("testdata", "stub-application.jar"))).To(BeTrue())
+			Expect(d.Interesting(filepath.Join(path, "test-archive.jar"))).To(BeFalse())
+			Expect(d.Interesting(filepath.Join(path, "test-elf"))).To(BeFalse())
+		})
+
+		it("passes for archives", func() {
+			d := libbs.ContentTypeFileDetector{ContentTypes: libbs.ArchiveContentTypes}
+
+			Expect(d.Interesting(filepath.Join("testdata", "stub-application.jar"))).To(BeTrue())
+			Expect(d.Interesting(filepath.Join(path, "test-archive.jar"))).To(BeTrue())
+			Expect(d.Interesting(filepath.Join(path, "test-elf"))).To(BeFalse())
+		})
+
+		it("passes for native executables", func() {
+			d := libbs.ContentTypeFileDetector{ContentTypes: libbs.NativeContentTypes}
+
+			Expect(d.Interesting(filepath.Join(path, "test-elf"))).To(BeTrue())
+			Expect(d.Interesting(filepath.Join("testdata", "stub-application.jar"))).To(BeFalse())
+		})
+
+		it("fails for directories", func() {
+			d := libbs.ContentTypeFileDetector{ContentTypes: libbs.ArchiveContentTypes}
+
+			Expect(d.Interesting(path)).To(BeFalse())
+		})
+	})
+
+	context("TarInterestingFileDetector", func() {
+		var path string
+
+		it.Before(func() {
+			var err error
+			path, err = ioutil.TempDir("", "tar-interesting")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(path)).To(Succeed())
+		})
+
+		it("passes for a gzip compressed tarball", func() {
+			Expect(libbs.TarInterestingFileDetector{}.Interesting(filepath.Join("testdata", "stub-distribution.tgz"))).To(BeTrue())
+		})
+
+		it("passes for a tarball", func() {
+			in, err := os.Open(filepath.Join("testdata", "stub-distribution.tgz"))
+			Expect(err).NotTo(HaveOccurred())
+			defer in.Close()
+			gz, err := gzip.NewReader(in)
+			Expect(err).NotTo(HaveOccurred())
+			b, err := ioutil.ReadAll(gz)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(filepath.Join(path, "test.tar"), b, 0644)).To(Succeed())
+
+			Expect(libbs.TarInterestingFileDetector{}.Interesting(filepath.Join(path, "test.tar"))).To(BeTrue())
+		})
+
+		it("fails for a gzip compressed file that is not a tarball", func() {
+			out, err := os.Create(filepath.Join(path, "test.gz"))
+			Expect(err).NotTo(HaveOccurred())
+			gz := gzip.NewWriter(out)
+			_, err = gz.Write([]byte("test-content"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(gz.Close()).To(Succeed())
+			Expect(out.Close()).To(Succeed())
+
+			Expect(libbs.TarInterestingFileDetector{}.Interesting(filepath.Join(path, "test.gz"))).To(BeFalse())
+		})
+
+		it("fails for a JAR", func() {
+			Expect(libbs.TarInterestingFileDetector{}.Interesting(filepath.Join("testdata", "stub-application.jar"))).To(BeFalse())
+		})
+
+		it("fails for directories", func() {
+			Expect(libbs.TarInterestingFileDetector{}.Interesting(path)).To(BeFalse())
+		})
+	})
+
+	context("NativeExecutableFileDetector", func() {
+		var path string
+
+		it.Before(func() {
+			var err error
+			path, err = ioutil.TempDir("", "native-executable")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(path)).To(Succeed())
+		})
+
+		it("passes for an executable ELF binary", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, "test-elf"),
+				[]byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00}, 0755)).To(Succeed())
+
+			Expect(libbs.NativeExecutableFileDetector{}.Interesting(filepath.Join(path, "test-elf"))).To(BeTrue())
+		})
+
+		it("fails for an ELF binary without an execute bit", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, "test-elf"),
+				[]byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00}, 0644)).To(Succeed())
+
+			Expect(libbs.NativeExecutableFileDetector{}.Interesting(filepath.Join(path, "test-elf"))).To(BeFalse())
+		})
+
+		it("fails for an executable script", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, "test-script"), []byte("#!/bin/sh\necho test\n"), 0755)).To(Succeed())
+
+			Expect(libbs.NativeExecutableFileDetector{}.Interesting(filepath.Join(path, "test-script"))).To(BeFalse())
+		})
+
+		it("fails for a plain JAR", func() {
+			Expect(libbs.NativeExecutableFileDetector{}.Interesting(filepath.Join("testdata", "stub-application.jar"))).To(BeFalse())
+		})
+
+		it("fails for directories", func() {
+			Expect(libbs.NativeExecutableFileDetector{}.Interesting(path)).To(BeFalse())
+		})
+	})
+
+	context("NativeImageDirectoryDetector", func() {
+		var path string
+
+		it.Before(func() {
+			var err error
+			path, err = ioutil.TempDir("", "native-image-directory")
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(path)).To(Succeed())
+		})
+
+		it("passes for a directory containing a native executable", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, "test-elf"),
+				[]byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00}, 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(path, "libawt.so"), []byte{}, 0644)).To(Succeed())
+
+			Expect(libbs.NativeImageDirectoryDetector{}.Interesting(path)).To(BeTrue())
+		})
+
+		it("fails for a directory without a native executable", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, "test-script"), []byte("#!/bin/sh\necho test\n"), 0755)).To(Succeed())
+
+			Expect(libbs.NativeImageDirectoryDetector{}.Interesting(path)).To(BeFalse())
+		})
+
+		it("fails for a native executable", func() {
+			Expect(ioutil.WriteFile(filepath.Join(path, "test-elf"),
+				[]byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00}, 0755)).To(Succeed())
+
+			Expect(libbs.NativeImageDirectoryDetector{}.Interesting(filepath.Join(path, "test-elf"))).To(BeFalse())
+		})
+	})
+
+	context("NativeImageAuxiliaryFiles", func() {
+		var (
+			path     string
+			resolver libbs.ArtifactResolver
+		)
+
+		it.Before(func() {
+			var err error
+			path, err = ioutil.TempDir("", "native-image-auxiliary")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(ioutil.WriteFile(filepath.Join(path, "test-elf"),
+				[]byte{0x7f, 'E', 'L', 'F', 0x02, 0x01, 0x01, 0x00}, 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(path, "libawt.so"), []byte{}, 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(path, "resource-config.json"), []byte("{}"), 0644)).To(Succeed())
+			Expect(ioutil.WriteFile(filepath.Join(path, "test.txt"), []byte{}, 0644)).To(Succeed())
+
+			resolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "test-elf"}},
+				},
+				NativeImageAuxiliaryFiles: true,
+			}
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(path)).To(Succeed())
+		})
+
+		it("adds the auxiliary files alongside a native executable", func() {
+			Expect(resolver.ResolveMany(path)).To(Equal([]string{
+				filepath.Join(path, "test-elf"),
+				filepath.Join(path, "libawt.so"),
+				filepath.Join(path, "resource-config.json"),
+			}))
+		})
+
+		it("does not add auxiliary files by default", func() {
+			resolver.NativeImageAuxiliaryFiles = false
+
+			Expect(resolver.ResolveMany(path)).To(Equal([]string{filepath.Join(path, "test-elf")}))
+		})
 	})
 
 	context("Resolve", func() {
@@ -136,6 +410,316 @@ func testResolvers(t *testing.T, context spec.G, it spec.S) {
 				filepath.Join(path, "test-file-1"), filepath.Join(path, "test-file-2"))))
 		})
 
+		context("PreferLatestVersion", func() {
+			it.Before(func() {
+				resolver.PreferLatestVersion = true
+
+				for _, name := range []string{"test-1.0.jar", "test-1.1.jar", "test-1.10.jar", "test-2.0-SNAPSHOT.jar"} {
+					Expect(ioutil.WriteFile(filepath.Join(path, name), []byte{}, 0644)).To(Succeed())
+				}
+			})
+
+			it("passes with the highest version", func() {
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "test-2.0-SNAPSHOT.jar")))
+			})
+
+			it("ranks a SNAPSHOT below its release", func() {
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-2.0.jar"), []byte{}, 0644)).To(Succeed())
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "test-2.0.jar")))
+			})
+
+			it("ranks SNAPSHOTs lowest", func() {
+				resolver.SnapshotRanking = libbs.SnapshotRankingLowest
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "test-1.10.jar")))
+			})
+
+			it("logs the choice", func() {
+				b := &bytes.Buffer{}
+				resolver.Logger = bard.NewLogger(b)
+
+				_, err := resolver.Resolve(path)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(b.String()).To(ContainSubstring("Resolved latest version"))
+				Expect(b.String()).To(ContainSubstring("test-2.0-SNAPSHOT.jar"))
+			})
+
+			it("falls back to the detector when versions are equal", func() {
+				resolver.SnapshotRanking = libbs.SnapshotRankingLowest
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-other-1.10.jar"), []byte{}, 0644)).To(Succeed())
+				detector.On("Interesting", mock.Anything).Return(false, nil)
+
+				_, err := resolver.Resolve(path)
+				Expect(err).To(MatchError(ContainSubstring("unable to find single built artifact")))
+			})
+		})
+
+		context("Logger", func() {
+			it("logs the patterns and candidates at debug", func() {
+				b := &bytes.Buffer{}
+				resolver.Logger = bard.NewLoggerWithOptions(b, bard.WithDebug(b))
+
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file-1"), []byte{}, 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file-2"), []byte{}, 0644)).To(Succeed())
+				detector.On("Interesting", filepath.Join(path, "test-file-1")).Return(true, nil)
+				detector.On("Interesting", filepath.Join(path, "test-file-2")).Return(false, nil)
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "test-file-1")))
+
+				Expect(b.String()).To(ContainSubstring("Artifact pattern test-* in %s matched candidates", path))
+				Expect(b.String()).To(ContainSubstring(filepath.Join(path, "test-file-1")))
+				Expect(b.String()).To(ContainSubstring("Ignoring candidate %s, not interesting", filepath.Join(path, "test-file-2")))
+			})
+
+			it("is silent without debug", func() {
+				b := &bytes.Buffer{}
+				resolver.Logger = bard.NewLogger(b)
+
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file"), []byte{}, 0644)).To(Succeed())
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "test-file")))
+				Expect(b.String()).To(BeEmpty())
+			})
+		})
+
+		context("DescendIntoArchives", func() {
+			it.Before(func() {
+				resolver.DescendIntoArchives = true
+				resolver.InterestingFileDetector = libbs.JARInterestingFileDetector{}
+
+				b, err := ioutil.ReadFile(filepath.Join("testdata", "stub-executable.jar"))
+				Expect(err).NotTo(HaveOccurred())
+
+				out, err := os.Create(filepath.Join(path, "test-dist.zip"))
+				Expect(err).NotTo(HaveOccurred())
+				z := zip.NewWriter(out)
+				w, err := z.Create("test-dist/lib/stub-executable.jar")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = w.Write(b)
+				Expect(err).NotTo(HaveOccurred())
+				w, err = z.Create("test-dist/bin/test-dist")
+				Expect(err).NotTo(HaveOccurred())
+				_, err = w.Write([]byte("#!/bin/sh\n"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(z.Close()).To(Succeed())
+				Expect(out.Close()).To(Succeed())
+			})
+
+			it("resolves the executable jar inside the archive", func() {
+				artifact, err := resolver.Resolve(path)
+				Expect(err).NotTo(HaveOccurred())
+				defer resolver.Cleanup()
+
+				Expect(filepath.Base(artifact)).To(Equal("stub-executable.jar"))
+				Expect(artifact).NotTo(HavePrefix(path))
+				Expect(libbs.JARInterestingFileDetector{}.Interesting(artifact)).To(BeTrue())
+			})
+
+			it("removes the extracted archive on cleanup", func() {
+				artifact, err := resolver.Resolve(path)
+				Expect(err).NotTo(HaveOccurred())
+				extracted := filepath.Dir(filepath.Dir(filepath.Dir(artifact)))
+				Expect(extracted).To(BeADirectory())
+
+				Expect(resolver.Cleanup()).To(Succeed())
+
+				Expect(extracted).NotTo(BeAnExistingFile())
+			})
+
+			it("does not descend without the option", func() {
+				resolver.DescendIntoArchives = false
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "test-dist.zip")))
+			})
+		})
+
+		context("FallbackPatterns", func() {
+			it.Before(func() {
+				resolver.FallbackPatterns = []string{"build/libs/*.jar", "target/*.jar"}
+			})
+
+			it("resolves from the first fallback that matches", func() {
+				Expect(os.MkdirAll(filepath.Join(path, "build", "libs"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "build", "libs", "app.jar"), []byte{}, 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(path, "target"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "target", "app.jar"), []byte{}, 0644)).To(Succeed())
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "build", "libs", "app.jar")))
+			})
+
+			it("prefers the pattern", func() {
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file"), []byte{}, 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(path, "target"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "target", "app.jar"), []byte{}, 0644)).To(Succeed())
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "test-file")))
+			})
+
+			it("falls back when the pattern matches multiple candidates", func() {
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file-1"), []byte{}, 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file-2"), []byte{}, 0644)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(path, "target"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "target", "app.jar"), []byte{}, 0644)).To(Succeed())
+				detector.On("Interesting", mock.Anything).Return(true, nil)
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "target", "app.jar")))
+			})
+
+			it("fails listing each pattern tried", func() {
+				_, err := resolver.Resolve(path)
+
+				Expect(err).To(MatchError("unable to find single built artifact in any of the patterns:\n" +
+					"test-*, candidates: []\nbuild/libs/*.jar, candidates: []\ntarget/*.jar, candidates: []"))
+			})
+		})
+
+		context("CaseInsensitive", func() {
+			it.Before(func() {
+				resolver.ConfigurationResolver.Configurations[0].Default = "target/app.jar"
+				Expect(os.MkdirAll(filepath.Join(path, "Target"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "Target", "App.jar"), []byte{}, 0644)).To(Succeed())
+			})
+
+			it("does not match a differently cased file by default", func() {
+				_, err := resolver.Resolve(path)
+
+				Expect(err).To(MatchError("unable to find single built artifact in target/app.jar, candidates: []"))
+			})
+
+			it("matches a differently cased file when enabled", func() {
+				resolver.CaseInsensitive = true
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "Target", "App.jar")))
+			})
+
+			it("matches globs when enabled", func() {
+				resolver.CaseInsensitive = true
+				resolver.ConfigurationResolver.Configurations[0].Default = "TARGET/*.JAR"
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "Target", "App.jar")))
+			})
+		})
+
+		context("MarkerFile", func() {
+			it.Before(func() {
+				resolver.MarkerFile = filepath.Join("target", "artifact-path.txt")
+				Expect(os.MkdirAll(filepath.Join(path, "target"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file-1"), []byte{}, 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "target", "marked-file"), []byte{}, 0644)).To(Succeed())
+			})
+
+			it("resolves the artifact named by the marker file", func() {
+				Expect(ioutil.WriteFile(filepath.Join(path, "target", "artifact-path.txt"), []byte("target/marked-file\n"), 0644)).To(Succeed())
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "target", "marked-file")))
+			})
+
+			it("falls back to globbing if the marker file is absent", func() {
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "test-file-1")))
+			})
+
+			it("fails if the marker file names a missing artifact", func() {
+				Expect(ioutil.WriteFile(filepath.Join(path, "target", "artifact-path.txt"), []byte("target/missing-file"), 0644)).To(Succeed())
+
+				_, err := resolver.Resolve(path)
+				Expect(err).To(MatchError(HavePrefix("unable to find artifact")))
+			})
+		})
+
+		context("IgnoreFile", func() {
+			it.Before(func() {
+				resolver.IgnoreFile = ".libbs-source"
+				resolver.InterestingFileDetector = nil
+
+				executable, err := ioutil.ReadFile(filepath.Join("testdata", "stub-executable.jar"))
+				Expect(err).NotTo(HaveOccurred())
+				plain, err := ioutil.ReadFile(filepath.Join("testdata", "stub-application.jar"))
+				Expect(err).NotTo(HaveOccurred())
+
+				for file, content := range map[string][]byte{
+					filepath.Join("src", "main", "java", "Test.java"):             []byte("class Test {}"),
+					filepath.Join("src", "test", "resources", "test-fixture.jar"): executable,
+					filepath.Join("docs", "README.md"):                            []byte("test-readme"),
+					filepath.Join("target", "lib", "test-plain.jar"):              plain,
+					filepath.Join("target", "test-original.jar"):                  executable,
+					filepath.Join("target", "test.jar"):                           executable,
+					"pom.xml":                                                     []byte("<project/>"),
+				} {
+					Expect(os.MkdirAll(filepath.Dir(filepath.Join(path, file)), 0755)).To(Succeed())
+					Expect(ioutil.WriteFile(filepath.Join(path, file), content, 0644)).To(Succeed())
+				}
+			})
+
+			it("resolves the single interesting file that is not ignored", func() {
+				Expect(ioutil.WriteFile(filepath.Join(path, ".libbs-source"), []byte(`# source
+src/
+pom.xml
+*.md
+/target/*-original.jar
+`), 0644)).To(Succeed())
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "target", "test.jar")))
+			})
+
+			it("re-includes negated files", func() {
+				Expect(ioutil.WriteFile(filepath.Join(path, ".libbs-source"), []byte(`**/*.jar
+!target/test.jar
+`), 0644)).To(Succeed())
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "target", "test.jar")))
+			})
+
+			it("fails with multiple candidates", func() {
+				Expect(ioutil.WriteFile(filepath.Join(path, ".libbs-source"), []byte("src/\n"), 0644)).To(Succeed())
+
+				_, err := resolver.Resolve(path)
+				Expect(err).To(MatchError(fmt.Sprintf("unable to find single built artifact not ignored by .libbs-source, candidates: [%s %s]",
+					filepath.Join(path, "target", "test-original.jar"), filepath.Join(path, "target", "test.jar"))))
+			})
+
+			it("fails if the ignore file is absent", func() {
+				_, err := resolver.Resolve(path)
+				Expect(err).To(MatchError(HavePrefix("unable to open ignore file")))
+			})
+		})
+
+		context("$BP_BUILD_OUTPUT_DIR", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("BP_BUILD_OUTPUT_DIR")).To(Succeed())
+			})
+
+			it("resolves the pattern relative to the application path", func() {
+				Expect(os.Setenv("BP_BUILD_OUTPUT_DIR", "test-output")).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(path, "test-output"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file"), []byte{}, 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-output", "test-built-file"), []byte{}, 0644)).To(Succeed())
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "test-output", "test-built-file")))
+			})
+
+			it("resolves the pattern in an absolute directory outside of the application path", func() {
+				output, err := ioutil.TempDir("", "artifact-resolver-output")
+				Expect(err).NotTo(HaveOccurred())
+				defer os.RemoveAll(output)
+
+				Expect(os.Setenv("BP_BUILD_OUTPUT_DIR", output)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file"), []byte{}, 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(output, "test-built-file"), []byte{}, 0644)).To(Succeed())
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(output, "test-built-file")))
+			})
+
+			it("fails if the output directory has no candidates", func() {
+				Expect(os.Setenv("BP_BUILD_OUTPUT_DIR", "test-output")).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file"), []byte{}, 0644)).To(Succeed())
+
+				_, err := resolver.Resolve(path)
+				Expect(err).To(MatchError("unable to find single built artifact in test-*, candidates: []"))
+			})
+		})
+
 		context("$TEST_ARTIFACT_CONFIGURATION_KEY", func() {
 			it.Before(func() {
 				Expect(os.Setenv("TEST_ARTIFACT_CONFIGURATION_KEY", "another-file")).To(Succeed())
@@ -168,6 +752,168 @@ func testResolvers(t *testing.T, context spec.G, it spec.S) {
 				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "test-directory", "test-file")))
 			})
 		})
+
+		context("pattern with ..", func() {
+			it.After(func() {
+				Expect(os.Unsetenv("TEST_MODULE_CONFIGURATION_KEY")).To(Succeed())
+			})
+
+			it("resolves a sibling of the module", func() {
+				Expect(os.Setenv("TEST_MODULE_CONFIGURATION_KEY", "test-directory")).To(Succeed())
+				resolver.ConfigurationResolver.Configurations[0].Default = "../dist/*.jar"
+				Expect(os.MkdirAll(filepath.Join(path, "test-directory"), 0755)).To(Succeed())
+				Expect(os.MkdirAll(filepath.Join(path, "dist"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "dist", "test.jar"), []byte{}, 0644)).To(Succeed())
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "dist", "test.jar")))
+			})
+
+			it("rejects a pattern that escapes the application path", func() {
+				resolver.ConfigurationResolver.Configurations[0].Default = "test-directory/../../dist/*.jar"
+
+				_, err := resolver.Resolve(path)
+				Expect(err).To(MatchError(fmt.Sprintf("unable to find files with test-directory/../../dist/*.jar\n"+
+					"pattern test-directory/../../dist/*.jar resolves outside of %s", path)))
+			})
+
+			it("resolves an absolute pattern relative to the application path", func() {
+				resolver.ConfigurationResolver.Configurations[0].Default = "/target/*.jar"
+				Expect(os.MkdirAll(filepath.Join(path, "target"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "target", "test.jar"), []byte{}, 0644)).To(Succeed())
+
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "target", "test.jar")))
+
+				resolver.CaseInsensitive = true
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "target", "test.jar")))
+			})
+
+			it("rejects an absolute pattern that escapes the application path", func() {
+				resolver.ConfigurationResolver.Configurations[0].Default = "/target/../../dist/*.jar"
+
+				_, err := resolver.Resolve(path)
+				Expect(err).To(MatchError(ContainSubstring("pattern /target/../../dist/*.jar resolves outside of")))
+			})
+		})
+	})
+
+	context("ResolveLaunchable", func() {
+		var (
+			path     string
+			resolver libbs.ArtifactResolver
+		)
+
+		it.Before(func() {
+			var err error
+
+			path, err = ioutil.TempDir("", "launchable-resolver")
+			Expect(err).NotTo(HaveOccurred())
+
+			resolver = libbs.ArtifactResolver{
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{{Default: "*.jar"}},
+				},
+			}
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(path)).To(Succeed())
+		})
+
+		copyStub := func(source string, name string) {
+			b, err := ioutil.ReadFile(filepath.Join("testdata", source))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(ioutil.WriteFile(filepath.Join(path, name), b, 0644)).To(Succeed())
+		}
+
+		it("discards auxiliary artifacts", func() {
+			copyStub("stub-executable.jar", "test-1.0.0.jar")
+			copyStub("stub-executable.jar", "test-1.0.0-sources.jar")
+			copyStub("stub-executable.jar", "test-1.0.0-javadoc.jar")
+
+			Expect(libbs.ResolveLaunchable(path, resolver)).To(Equal(filepath.Join(path, "test-1.0.0.jar")))
+		})
+
+		it("discards artifacts that are not interesting", func() {
+			copyStub("stub-executable.jar", "test-1.0.0.jar")
+			copyStub("stub-application.jar", "test-1.0.0-library.jar")
+
+			Expect(libbs.ResolveLaunchable(path, resolver)).To(Equal(filepath.Join(path, "test-1.0.0.jar")))
+		})
+
+		it("fails when the artifact is ambiguous", func() {
+			copyStub("stub-executable.jar", "test-1.0.0.jar")
+			copyStub("stub-executable.jar", "other-1.0.0.jar")
+			copyStub("stub-executable.jar", "other-1.0.0-plain.jar")
+
+			_, err := libbs.ResolveLaunchable(path, resolver)
+			Expect(err).To(MatchError(fmt.Sprintf("unable to find single built artifact in *.jar, candidates: [%s %s]",
+				filepath.Join(path, "other-1.0.0.jar"), filepath.Join(path, "test-1.0.0.jar"))))
+		})
+	})
+
+	context("ResolveWAR", func() {
+		var (
+			path     string
+			resolver libbs.ArtifactResolver
+		)
+
+		it.Before(func() {
+			var err error
+
+			path, err = ioutil.TempDir("", "war-resolver")
+			Expect(err).NotTo(HaveOccurred())
+
+			resolver = libbs.ArtifactResolver{
+				ArtifactConfigurationKey: "TEST_ARTIFACT_CONFIGURATION_KEY",
+				ModuleConfigurationKey:   "TEST_MODULE_CONFIGURATION_KEY",
+				ConfigurationResolver: libpak.ConfigurationResolver{
+					Configurations: []libpak.BuildpackConfiguration{
+						{Name: "TEST_ARTIFACT_CONFIGURATION_KEY", Default: "target/*.war"},
+					},
+				},
+			}
+			Expect(os.Setenv("TEST_ARTIFACT_CONFIGURATION_KEY", "*/target/*.war")).To(Succeed())
+
+			for _, module := range []string{"module-a", "module-b"} {
+				b, err := ioutil.ReadFile(filepath.Join("testdata", "stub-application.war"))
+				Expect(err).NotTo(HaveOccurred())
+				Expect(os.MkdirAll(filepath.Join(path, module, "target"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, module, "target", module+".war"), b, 0644)).To(Succeed())
+			}
+		})
+
+		it.After(func() {
+			Expect(os.RemoveAll(path)).To(Succeed())
+			Expect(os.Unsetenv("TEST_MODULE_CONFIGURATION_KEY")).To(Succeed())
+			Expect(os.Unsetenv("TEST_ARTIFACT_CONFIGURATION_KEY")).To(Succeed())
+		})
+
+		it("prefers the WAR of the configured module", func() {
+			Expect(os.Setenv("TEST_MODULE_CONFIGURATION_KEY", "module-b")).To(Succeed())
+
+			Expect(libbs.ResolveWAR(path, resolver)).To(Equal(filepath.Join(path, "module-b", "target", "module-b.war")))
+		})
+
+		it("fails with guidance when no module is configured", func() {
+			_, err := libbs.ResolveWAR(path, resolver)
+			Expect(err).To(MatchError(fmt.Sprintf("unable to find single built WAR, candidates: [%s %s], set "+
+				"$TEST_MODULE_CONFIGURATION_KEY to the module of the WAR to use",
+				filepath.Join(path, "module-a", "target", "module-a.war"),
+				filepath.Join(path, "module-b", "target", "module-b.war"))))
+		})
+
+		it("fails when the configured module has no WAR", func() {
+			Expect(os.Setenv("TEST_MODULE_CONFIGURATION_KEY", "module-c")).To(Succeed())
+
+			_, err := libbs.ResolveWAR(path, resolver)
+			Expect(err).To(MatchError(ContainSubstring("unable to find single built WAR in module module-c")))
+		})
+
+		it("resolves a single WAR", func() {
+			Expect(os.RemoveAll(filepath.Join(path, "module-b"))).To(Succeed())
+
+			Expect(libbs.ResolveWAR(path, resolver)).To(Equal(filepath.Join(path, "module-a", "target", "module-a.war")))
+		})
 	})
 
 	context("ResolveArguments", func() {
@@ -203,6 +949,127 @@ func testResolvers(t *testing.T, context spec.G, it spec.S) {
 					To(Equal([]string{"test-argument-3", "test-argument-4"}))
 			})
 		})
+
+		context("environment variables", func() {
+			it.Before(func() {
+				Expect(os.Setenv("TEST_CACHE_DIR", "/test/cache dir")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("TEST_CACHE_DIR")).To(Succeed())
+				Expect(os.Unsetenv("TEST_CONFIGURATION_KEY")).To(Succeed())
+			})
+
+			it("does not expand variables by default", func() {
+				Expect(os.Setenv("TEST_CONFIGURATION_KEY", "-Ddir=${TEST_CACHE_DIR} -Dtest=$TEST_UNDEFINED $$")).To(Succeed())
+
+				Expect(libbs.ResolveArguments("TEST_CONFIGURATION_KEY", resolver)).
+					To(Equal([]string{"-Ddir=${TEST_CACHE_DIR}", "-Dtest=$TEST_UNDEFINED", "$$"}))
+			})
+
+			it("expands a defined variable", func() {
+				Expect(os.Setenv("TEST_CONFIGURATION_KEY", `"-Dmaven.repo.local=${TEST_CACHE_DIR}" $TEST_CACHE_DIR`)).To(Succeed())
+
+				Expect(libbs.ResolveExpandedArguments("TEST_CONFIGURATION_KEY", resolver, false)).
+					To(Equal([]string{"-Dmaven.repo.local=/test/cache dir", "/test/cache", "dir"}))
+			})
+
+			it("expands an undefined variable to an empty string", func() {
+				Expect(os.Setenv("TEST_CONFIGURATION_KEY", "-Dtest=${TEST_UNDEFINED} test-argument")).To(Succeed())
+
+				Expect(libbs.ResolveExpandedArguments("TEST_CONFIGURATION_KEY", resolver, false)).
+					To(Equal([]string{"-Dtest=", "test-argument"}))
+			})
+
+			it("keeps an undefined variable", func() {
+				Expect(os.Setenv("TEST_CONFIGURATION_KEY", "-Dtest=${TEST_UNDEFINED} -Ddir=${TEST_CACHE_DIR}")).To(Succeed())
+
+				Expect(libbs.ResolveExpandedArguments("TEST_CONFIGURATION_KEY", resolver, true)).
+					To(Equal([]string{"-Dtest=${TEST_UNDEFINED}", "-Ddir=/test/cache", "dir"}))
+			})
+
+			it("expands an escaped $$ to a literal $", func() {
+				Expect(os.Setenv("TEST_CONFIGURATION_KEY", "-Dtest=$${TEST_CACHE_DIR}")).To(Succeed())
+
+				Expect(libbs.ResolveExpandedArguments("TEST_CONFIGURATION_KEY", resolver, false)).
+					To(Equal([]string{"-Dtest=${TEST_CACHE_DIR}"}))
+			})
+		})
+	})
+
+	context("ResolveModuleArguments", func() {
+		var (
+			resolver libpak.ConfigurationResolver
+		)
+
+		it.Before(func() {
+			resolver = libpak.ConfigurationResolver{
+				Configurations: []libpak.BuildpackConfiguration{
+					{Name: "TEST_CONFIGURATION_KEY", Default: "test-argument-1 test-argument-2"},
+				},
+			}
+
+			Expect(os.Setenv("TEST_CONFIGURATION_KEY_SERVICES_API", "-Dtest-argument-3 -Ptest-profile")).To(Succeed())
+		})
+
+		it.After(func() {
+			Expect(os.Unsetenv("TEST_CONFIGURATION_KEY_SERVICES_API")).To(Succeed())
+		})
+
+		it("derives the module configuration key", func() {
+			Expect(libbs.ModuleArgumentsKey("TEST_CONFIGURATION_KEY", "services/api")).
+				To(Equal("TEST_CONFIGURATION_KEY_SERVICES_API"))
+			Expect(libbs.ModuleArgumentsKey("TEST_CONFIGURATION_KEY", "web-app")).
+				To(Equal("TEST_CONFIGURATION_KEY_WEB_APP"))
+		})
+
+		it("appends the module arguments", func() {
+			Expect(libbs.ResolveModuleArguments("TEST_CONFIGURATION_KEY", "services/api", resolver)).
+				To(Equal([]string{"test-argument-1", "test-argument-2", "-Dtest-argument-3", "-Ptest-profile"}))
+		})
+
+		it("uses the base arguments for other modules", func() {
+			Expect(libbs.ResolveModuleArguments("TEST_CONFIGURATION_KEY", "services/web", resolver)).
+				To(Equal([]string{"test-argument-1", "test-argument-2"}))
+		})
+
+		it("fails with unparseable module arguments", func() {
+			Expect(os.Setenv("TEST_CONFIGURATION_KEY_SERVICES_API", `"unterminated`)).To(Succeed())
+
+			_, err := libbs.ResolveModuleArguments("TEST_CONFIGURATION_KEY", "services/api", resolver)
+			Expect(err).To(MatchError(ContainSubstring("unable to parse arguments")))
+		})
+	})
+
+	context("RenderArguments", func() {
+		tmpl := `clean package{{ if eq .PROFILE "prod" }} -Pprod{{ end }} -Dversion={{ default "0.0.0" .VERSION }}`
+
+		it("includes arguments conditionally", func() {
+			Expect(libbs.RenderArguments(tmpl, map[string]string{"PROFILE": "prod", "VERSION": "1.2.3"})).
+				To(Equal([]string{"clean", "package", "-Pprod", "-Dversion=1.2.3"}))
+		})
+
+		it("excludes arguments conditionally", func() {
+			Expect(libbs.RenderArguments(tmpl, map[string]string{"PROFILE": "dev"})).
+				To(Equal([]string{"clean", "package", "-Dversion=0.0.0"}))
+		})
+
+		it("renders missing variables as empty", func() {
+			Expect(libbs.RenderArguments(`package {{ .MISSING }}`, map[string]string{})).
+				To(Equal([]string{"package"}))
+		})
+
+		it("fails with an invalid template", func() {
+			_, err := libbs.RenderArguments(`package {{ if }}`, map[string]string{})
+
+			Expect(err).To(MatchError(HavePrefix("unable to parse arguments template")))
+		})
+
+		it("fails with a template that cannot be executed", func() {
+			_, err := libbs.RenderArguments(`package {{ index .PROFILE 10 }}`, map[string]string{"PROFILE": "dev"})
+
+			Expect(err).To(MatchError(HavePrefix("unable to render arguments template")))
+		})
 	})
 
 	context("ResolveMany", func() {
@@ -271,6 +1138,126 @@ func testResolvers(t *testing.T, context spec.G, it spec.S) {
 			Expect(err).To(MatchError(HavePrefix("unable to find any built artifacts for pattern(s):\ntest-*")))
 		})
 
+		it("logs the patterns and candidates at debug", func() {
+			b := &bytes.Buffer{}
+			resolver.Logger = bard.NewLoggerWithOptions(b, bard.WithDebug(b))
+			resolver.ConfigurationResolver.Configurations = []libpak.BuildpackConfiguration{
+				{Name: "TEST_ARTIFACT_CONFIGURATION_KEY", Default: "test-* other-*"},
+			}
+
+			Expect(ioutil.WriteFile(filepath.Join(path, "test-file"), []byte{}, 0644)).To(Succeed())
+
+			Expect(resolver.ResolveMany(path)).To(Equal([]string{filepath.Join(path, "test-file")}))
+
+			Expect(b.String()).To(ContainSubstring("Artifact pattern test-* in %s matched candidates [%s]", path, filepath.Join(path, "test-file")))
+			Expect(b.String()).To(ContainSubstring("Artifact pattern other-* in %s matched candidates []", path))
+		})
+
+		context("SkipHidden", func() {
+			it.Before(func() {
+				resolver.ConfigurationResolver.Configurations = []libpak.BuildpackConfiguration{
+					{Name: "TEST_ARTIFACT_CONFIGURATION_KEY", Default: "*"},
+				}
+
+				Expect(os.Mkdir(filepath.Join(path, ".git"), os.ModePerm)).To(Succeed())
+				Expect(os.Mkdir(filepath.Join(path, "target"), os.ModePerm)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, ".test-file"), []byte{}, 0644)).To(Succeed())
+			})
+
+			it("skips hidden directories by default", func() {
+				Expect(resolver.ResolveMany(path)).To(ConsistOf(filepath.Join(path, ".test-file"), filepath.Join(path, "target")))
+			})
+
+			it("skips hidden files and directories", func() {
+				skip := true
+				resolver.SkipHidden = &skip
+
+				Expect(resolver.ResolveMany(path)).To(ConsistOf(filepath.Join(path, "target")))
+			})
+
+			it("keeps hidden files and directories", func() {
+				skip := false
+				resolver.SkipHidden = &skip
+
+				Expect(resolver.ResolveMany(path)).
+					To(ConsistOf(filepath.Join(path, ".git"), filepath.Join(path, ".test-file"), filepath.Join(path, "target")))
+			})
+
+			it("keeps hidden entries named explicitly", func() {
+				skip := true
+				resolver.SkipHidden = &skip
+				resolver.ConfigurationResolver.Configurations = []libpak.BuildpackConfiguration{
+					{Name: "TEST_ARTIFACT_CONFIGURATION_KEY", Default: ".g* target"},
+				}
+
+				Expect(resolver.ResolveMany(path)).To(ConsistOf(filepath.Join(path, ".git"), filepath.Join(path, "target")))
+			})
+		})
+
+		context("MarkerFile", func() {
+			it.Before(func() {
+				resolver.MarkerFile = "artifact-path.txt"
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file-1"), []byte{}, 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file-2"), []byte{}, 0644)).To(Succeed())
+			})
+
+			it("resolves the artifact named by the marker file", func() {
+				Expect(ioutil.WriteFile(filepath.Join(path, "artifact-path.txt"), []byte("test-file-2"), 0644)).To(Succeed())
+
+				Expect(resolver.ResolveMany(path)).To(Equal([]string{filepath.Join(path, "test-file-2")}))
+			})
+
+			it("falls back to globbing if the marker file is absent", func() {
+				Expect(resolver.ResolveMany(path)).To(Equal([]string{filepath.Join(path, "test-file-1"), filepath.Join(path, "test-file-2")}))
+			})
+		})
+
+		context("IgnoreFile", func() {
+			it.Before(func() {
+				resolver.IgnoreFile = ".libbs-source"
+				resolver.InterestingFileDetector = libbs.AlwaysInterestingFileDetector{}
+				Expect(os.MkdirAll(filepath.Join(path, "src"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "src", "test-source"), []byte{}, 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file-1"), []byte{}, 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file-2"), []byte{}, 0644)).To(Succeed())
+			})
+
+			it("resolves the interesting files that are not ignored", func() {
+				Expect(ioutil.WriteFile(filepath.Join(path, ".libbs-source"), []byte(".libbs-source\nsrc/\n"), 0644)).To(Succeed())
+
+				Expect(resolver.ResolveMany(path)).To(Equal([]string{filepath.Join(path, "test-file-1"), filepath.Join(path, "test-file-2")}))
+			})
+
+			it("fails if every file is ignored", func() {
+				Expect(ioutil.WriteFile(filepath.Join(path, ".libbs-source"), []byte("*\n"), 0644)).To(Succeed())
+
+				_, err := resolver.ResolveMany(path)
+				Expect(err).To(MatchError("unable to find any built artifacts not ignored by .libbs-source"))
+			})
+		})
+
+		context("$BP_BUILD_OUTPUT_DIR", func() {
+			it.Before(func() {
+				Expect(os.Setenv("BP_BUILD_OUTPUT_DIR", "test-output")).To(Succeed())
+			})
+
+			it.After(func() {
+				Expect(os.Unsetenv("BP_BUILD_OUTPUT_DIR")).To(Succeed())
+			})
+
+			it("resolves the artifacts in the output directory", func() {
+				Expect(os.MkdirAll(filepath.Join(path, "test-output"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-file-1"), []byte{}, 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-output", "test-file-2"), []byte{}, 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "test-output", "test-file-3"), []byte{}, 0644)).To(Succeed())
+
+				Expect(resolver.ResolveMany(path)).To(Equal([]string{
+					filepath.Join(path, "test-output", "test-file-2"),
+					filepath.Join(path, "test-output", "test-file-3"),
+				}))
+			})
+		})
+
 		context("ResolveMany with multiple glob patterns", func() {
 			it.Before(func() {
 				resolver.ConfigurationResolver.Configurations[0] = libpak.BuildpackConfiguration{
@@ -301,6 +1288,40 @@ func testResolvers(t *testing.T, context spec.G, it spec.S) {
 			})
 		})
 
+		context("ArtifactPatternType regexp", func() {
+			it.Before(func() {
+				resolver.ArtifactPatternType = libbs.ArtifactPatternRegexp
+				Expect(os.MkdirAll(filepath.Join(path, "target"), 0755)).To(Succeed())
+				for _, name := range []string{"test-1.0.0.jar", "test-1.0.0-sources.jar", "test-1.0.0-javadoc.jar"} {
+					Expect(ioutil.WriteFile(filepath.Join(path, "target", name), []byte{}, 0644)).To(Succeed())
+				}
+			})
+
+			it("selects the jar that is not a sources or javadoc jar", func() {
+				resolver.ConfigurationResolver.Configurations[0] = libpak.BuildpackConfiguration{
+					Name: "TEST_ARTIFACT_CONFIGURATION_KEY", Default: `target/test-[0-9.]+\.jar`}
+
+				Expect(resolver.ResolveMany(path)).To(Equal([]string{filepath.Join(path, "target", "test-1.0.0.jar")}))
+				Expect(resolver.Resolve(path)).To(Equal(filepath.Join(path, "target", "test-1.0.0.jar")))
+			})
+
+			it("matches the whole relative path", func() {
+				resolver.ConfigurationResolver.Configurations[0] = libpak.BuildpackConfiguration{
+					Name: "TEST_ARTIFACT_CONFIGURATION_KEY", Default: `test-[0-9.]+\.jar`}
+
+				_, err := resolver.ResolveMany(path)
+				Expect(err).To(MatchError(HavePrefix("unable to find any built artifacts")))
+			})
+
+			it("fails with a bad pattern", func() {
+				resolver.ConfigurationResolver.Configurations[0] = libpak.BuildpackConfiguration{
+					Name: "TEST_ARTIFACT_CONFIGURATION_KEY", Default: "target/(.*"}
+
+				_, err := resolver.ResolveMany(path)
+				Expect(err).To(MatchError("unable to proceed due to bad pattern(s):\ntarget/(.*"))
+			})
+		})
+
 		context("ResolveMany with a bad pattern", func() {
 			it.Before(func() {
 				resolver.ConfigurationResolver.Configurations[0] = libpak.BuildpackConfiguration{
@@ -326,5 +1347,37 @@ func testResolvers(t *testing.T, context spec.G, it spec.S) {
 				Expect(err).To(MatchError("unable to proceed due to bad pattern(s):\nfirst-bad-[\nsecond-bad-["))
 			})
 		})
+
+		it("rejects a pattern that escapes the application path", func() {
+			resolver.ConfigurationResolver.Configurations[0].Default = "test-* ../dist/*.jar"
+
+			_, err := resolver.ResolveMany(path)
+			Expect(err).To(MatchError(fmt.Sprintf("unable to find files with ../dist/*.jar\n"+
+				"pattern ../dist/*.jar resolves outside of %s", path)))
+		})
+
+		context("FallbackPatterns", func() {
+			it.Before(func() {
+				resolver.FallbackPatterns = []string{"build/libs/*.jar", "target/*.jar target/*.war"}
+			})
+
+			it("resolves from the first fallback that matches", func() {
+				Expect(os.MkdirAll(filepath.Join(path, "target"), 0755)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "target", "app.jar"), []byte{}, 0644)).To(Succeed())
+				Expect(ioutil.WriteFile(filepath.Join(path, "target", "app.war"), []byte{}, 0644)).To(Succeed())
+
+				Expect(resolver.ResolveMany(path)).To(Equal([]string{
+					filepath.Join(path, "target", "app.jar"),
+					filepath.Join(path, "target", "app.war"),
+				}))
+			})
+
+			it("fails listing each pattern tried", func() {
+				_, err := resolver.ResolveMany(path)
+
+				Expect(err).To(MatchError("unable to find any built artifacts for pattern(s):\n" +
+					"test-*\nbuild/libs/*.jar\ntarget/*.jar\ntarget/*.war"))
+			})
+		})
 	})
 }
@@ -0,0 +1,160 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// stripArtifact copies the zip archive artifact to destination with the Copier, omitting any entries that match one
+// of the patterns.  The archive is stripped to a temporary file first so that the Copier writes the destination.
+func (a Application) stripArtifact(artifact string, destination string, patterns []string) ([]string, error) {
+	dir, err := os.MkdirTemp("", "artifact-strip")
+	if err != nil {
+		return nil, fmt.Errorf("unable to create temporary directory\n%w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, filepath.Base(artifact))
+	stripped, err := stripArchive(artifact, file, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.copier().CopyFile(file, destination); err != nil {
+		return nil, fmt.Errorf("unable to copy the file %s to %s\n%w", file, destination, err)
+	}
+
+	return stripped, nil
+}
+
+// stripArchive copies the zip archive at source to destination, omitting any entries that match one of the patterns.
+// A pattern matching a directory omits everything beneath that directory.  The destination has the mode of the source
+// and keeps any data preceding the archive, such as the launch script of a fully executable JAR.
+func stripArchive(source string, destination string, patterns []string) ([]string, error) {
+	for _, p := range patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid strip pattern %s\n%w", p, err)
+		}
+	}
+
+	in, err := zip.OpenReader(source)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s\n%w", source, err)
+	}
+	defer in.Close()
+
+	fileInfo, err := os.Stat(source)
+	if err != nil {
+		return nil, fmt.Errorf("unable to stat %s\n%w", source, err)
+	}
+
+	prefix, err := archivePrefix(source, in.File)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := os.OpenFile(destination, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, fileInfo.Mode().Perm())
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s\n%w", destination, err)
+	}
+	defer out.Close()
+
+	if err := out.Chmod(fileInfo.Mode()); err != nil {
+		return nil, fmt.Errorf("unable to set mode of %s\n%w", destination, err)
+	}
+
+	if _, err := out.Write(prefix); err != nil {
+		return nil, fmt.Errorf("unable to write %s\n%w", destination, err)
+	}
+
+	var stripped []string
+	z := zip.NewWriter(out)
+	z.SetOffset(int64(len(prefix)))
+	for _, f := range in.File {
+		if stripMatches(f.Name, patterns) {
+			stripped = append(stripped, f.Name)
+			continue
+		}
+
+		if err := z.Copy(f); err != nil {
+			return nil, fmt.Errorf("unable to copy entry %s to %s\n%w", f.Name, destination, err)
+		}
+	}
+
+	if err := z.Close(); err != nil {
+		return nil, fmt.Errorf("unable to write %s\n%w", destination, err)
+	}
+
+	return stripped, nil
+}
+
+// archivePrefix returns the data preceding the first entry of the zip archive at path, e.g. a launch script.
+func archivePrefix(path string, files []*zip.File) ([]byte, error) {
+	var first int64 = -1
+	for _, f := range files {
+		offset, err := f.DataOffset()
+		if err != nil {
+			return nil, fmt.Errorf("unable to find entry %s in %s\n%w", f.Name, path, err)
+		}
+		if first < 0 || offset < first {
+			first = offset
+		}
+	}
+	if first <= 0 {
+		return nil, nil
+	}
+
+	in, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	b := make([]byte, first)
+	if _, err := io.ReadFull(in, b); err != nil {
+		return nil, fmt.Errorf("unable to read %s\n%w", path, err)
+	}
+
+	// the first entry's local file header follows the prefix
+	if i := bytes.Index(b, []byte("PK\x03\x04")); i >= 0 {
+		return b[:i], nil
+	}
+	return nil, nil
+}
+
+// stripMatches determines whether a zip entry, or any of the directories containing it, matches one of the patterns.
+func stripMatches(name string, patterns []string) bool {
+	name = strings.TrimSuffix(name, "/")
+
+	for candidate := name; candidate != "." && candidate != ""; candidate = path.Dir(candidate) {
+		for _, p := range patterns {
+			if ok, _ := path.Match(strings.TrimSuffix(p, "/"), candidate); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,143 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-shellwords"
+)
+
+// ConfigurationValidator validates the value of a configuration key.
+type ConfigurationValidator struct {
+
+	// Name is the name of the configuration key.
+	Name string
+
+	// Expected describes the expected format of the value, e.g. "a positive integer".
+	Expected string
+
+	// Valid determines whether a value is valid.
+	Valid func(value string) bool
+}
+
+// ConfigurationValidators are the validators of the configuration keys owned by libbs.
+var ConfigurationValidators = []ConfigurationValidator{
+	{Name: "BP_BOM_LABEL_DISABLED", Expected: "a boolean", Valid: validBool},
+	{Name: "BP_BUILD_ARTIFACT_STRIP", Expected: "a space separated list of globs", Valid: validPatterns},
+	{Name: "BP_BUILD_FORBID_SNAPSHOTS", Expected: "a boolean", Valid: validBool},
+	{Name: "BP_BUILD_LICENSE_SCAN", Expected: "a boolean", Valid: validBool},
+	{Name: "BP_BUILD_MEMORY_LIMIT", Expected: "a size in bytes, optionally with a K, M, G or T suffix", Valid: validMemorySize},
+	{Name: "BP_BUILD_SBOM_PARALLELISM", Expected: "a positive integer", Valid: validPositiveInteger},
+	{Name: "BP_BUILD_VERIFY_CACHE", Expected: "a boolean", Valid: validBool},
+	{Name: "BP_BUILD_VERIFY_WRAPPER", Expected: "a boolean", Valid: validBool},
+	{Name: "BP_BUILD_WRAPPER_SHA256", Expected: "a space separated list of SHA256 checksums", Valid: validSHA256s},
+	{Name: "BP_EXCLUDE_FILES", Expected: "a colon separated list of globs", Valid: validPathList},
+	{Name: "BP_INCLUDE_FILES", Expected: "a colon separated list of globs", Valid: validPathList},
+	{Name: "BP_KEEP_FILES", Expected: "a colon separated list of globs", Valid: validPathList},
+	{Name: "BP_KEEP_SOURCES", Expected: "a boolean", Valid: validBool},
+}
+
+// ValidateConfiguration validates the configured values of the configuration keys owned by libbs, and the artifact
+// pattern of artifactResolver, returning a single error that lists every invalid value and its expected format.
+func ValidateConfiguration(artifactResolver ArtifactResolver) error {
+	var invalid []string
+
+	for _, v := range ConfigurationValidators {
+		if s, _ := artifactResolver.ConfigurationResolver.Resolve(v.Name); s != "" && !v.Valid(s) {
+			invalid = append(invalid, fmt.Sprintf("%s=%s, expected %s", v.Name, s, v.Expected))
+		}
+	}
+
+	valid, expected := validPatterns, "a space separated list of globs"
+	if artifactResolver.ArtifactPatternType == ArtifactPatternRegexp {
+		valid, expected = validRegexp, "a regular expression"
+	}
+	if s := artifactResolver.Pattern(); s != "" && !valid(s) {
+		name := artifactResolver.ArtifactConfigurationKey
+		if name == "" {
+			name = "artifact pattern"
+		}
+		invalid = append(invalid, fmt.Sprintf("%s=%s, expected %s", name, s, expected))
+	}
+
+	if len(invalid) > 0 {
+		return fmt.Errorf("invalid configuration values:\n%s", strings.Join(invalid, "\n"))
+	}
+
+	return nil
+}
+
+func validBool(s string) bool {
+	_, err := strconv.ParseBool(s)
+	return err == nil
+}
+
+func validSHA256s(s string) bool {
+	for _, c := range strings.Fields(s) {
+		if !sha256Sum.MatchString(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validPositiveInteger(s string) bool {
+	i, err := strconv.Atoi(strings.TrimSpace(s))
+	return err == nil && i > 0
+}
+
+func validPatterns(s string) bool {
+	patterns, err := shellwords.Parse(s)
+	if err != nil {
+		return false
+	}
+
+	for _, p := range patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+func validRegexp(s string) bool {
+	_, err := regexp.Compile(s)
+	return err == nil
+}
+
+func validPathList(s string) bool {
+	return len(invalidGlobs(s)) == 0
+}
+
+// invalidGlobs returns the globs of a colon separated list of globs that are malformed.
+func invalidGlobs(s string) []string {
+	var invalid []string
+	for _, p := range filepath.SplitList(s) {
+		if _, err := filepath.Match(p, "probe"); err != nil {
+			invalid = append(invalid, p)
+		}
+	}
+
+	return invalid
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libbs"
+)
+
+func testValidate(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		resolver libbs.ArtifactResolver
+	)
+
+	it.Before(func() {
+		resolver = libbs.ArtifactResolver{
+			ArtifactConfigurationKey: "TEST_ARTIFACT_CONFIGURATION_KEY",
+			ConfigurationResolver: libpak.ConfigurationResolver{
+				Configurations: []libpak.BuildpackConfiguration{
+					{Name: "TEST_ARTIFACT_CONFIGURATION_KEY", Default: "target/*.jar"},
+					{Name: "BP_BUILD_SBOM_PARALLELISM", Default: ""},
+				},
+			},
+		}
+	})
+
+	it.After(func() {
+		for _, k := range []string{"BP_BOM_LABEL_DISABLED", "BP_BUILD_MEMORY_LIMIT", "BP_BUILD_SBOM_PARALLELISM", "BP_BUILD_WRAPPER_SHA256",
+			"BP_INCLUDE_FILES", "TEST_ARTIFACT_CONFIGURATION_KEY"} {
+			Expect(os.Unsetenv(k)).To(Succeed())
+		}
+	})
+
+	it("passes with valid values", func() {
+		Expect(os.Setenv("BP_BOM_LABEL_DISABLED", "true")).To(Succeed())
+		Expect(os.Setenv("BP_BUILD_MEMORY_LIMIT", "2G")).To(Succeed())
+		Expect(os.Setenv("BP_BUILD_SBOM_PARALLELISM", "2")).To(Succeed())
+		Expect(os.Setenv("BP_BUILD_WRAPPER_SHA256", strings.Repeat("a", 64)+" "+strings.Repeat("B", 64))).To(Succeed())
+		Expect(os.Setenv("BP_INCLUDE_FILES", "static/*:templates/*")).To(Succeed())
+
+		Expect(libbs.ValidateConfiguration(resolver)).To(Succeed())
+	})
+
+	it("validates a regexp artifact pattern", func() {
+		resolver.ArtifactPatternType = libbs.ArtifactPatternRegexp
+		Expect(os.Setenv("TEST_ARTIFACT_CONFIGURATION_KEY", `target/[^/]+(?:-all)?\.jar`)).To(Succeed())
+		Expect(libbs.ValidateConfiguration(resolver)).To(Succeed())
+
+		Expect(os.Setenv("TEST_ARTIFACT_CONFIGURATION_KEY", "target/(.*")).To(Succeed())
+		Expect(libbs.ValidateConfiguration(resolver)).To(MatchError("invalid configuration values:\n" +
+			"TEST_ARTIFACT_CONFIGURATION_KEY=target/(.*, expected a regular expression"))
+	})
+
+	it("passes with defaults", func() {
+		Expect(libbs.ValidateConfiguration(resolver)).To(Succeed())
+	})
+
+	it("lists every invalid value", func() {
+		Expect(os.Setenv("BP_BOM_LABEL_DISABLED", "yes")).To(Succeed())
+		Expect(os.Setenv("BP_BUILD_MEMORY_LIMIT", "lots")).To(Succeed())
+		Expect(os.Setenv("BP_BUILD_SBOM_PARALLELISM", "many")).To(Succeed())
+		Expect(os.Setenv("BP_BUILD_WRAPPER_SHA256", "test-checksum")).To(Succeed())
+		Expect(os.Setenv("BP_INCLUDE_FILES", "static/[")).To(Succeed())
+		Expect(os.Setenv("TEST_ARTIFACT_CONFIGURATION_KEY", "target/*.jar 'build/*.jar")).To(Succeed())
+
+		Expect(libbs.ValidateConfiguration(resolver)).To(MatchError("invalid configuration values:\n" +
+			"BP_BOM_LABEL_DISABLED=yes, expected a boolean\n" +
+			"BP_BUILD_MEMORY_LIMIT=lots, expected a size in bytes, optionally with a K, M, G or T suffix\n" +
+			"BP_BUILD_SBOM_PARALLELISM=many, expected a positive integer\n" +
+			"BP_BUILD_WRAPPER_SHA256=test-checksum, expected a space separated list of SHA256 checksums\n" +
+			"BP_INCLUDE_FILES=static/[, expected a colon separated list of globs\n" +
+			"TEST_ARTIFACT_CONFIGURATION_KEY=target/*.jar 'build/*.jar, expected a space separated list of globs"))
+	})
+}
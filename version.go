@@ -0,0 +1,130 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// SnapshotRanking determines how SNAPSHOT versions are ranked when choosing the latest version of an artifact.
+type SnapshotRanking string
+
+const (
+	// SnapshotRankingPreRelease ranks a SNAPSHOT version as a pre-release of its version, below the release of the
+	// same version but above any earlier version.  This is the default.
+	SnapshotRankingPreRelease SnapshotRanking = "pre-release"
+
+	// SnapshotRankingLowest ranks a SNAPSHOT version below any release version.
+	SnapshotRankingLowest SnapshotRanking = "lowest"
+)
+
+var artifactVersion = regexp.MustCompile(`^.+?-(\d+(?:\.\d+)*)(?:[.-](.+))?$`)
+
+// version is a version parsed from an artifact name.
+type version struct {
+	numbers   []int
+	qualifier string
+}
+
+// parseVersion parses the version from an artifact name, such as app-1.2.3-SNAPSHOT.jar.
+func parseVersion(name string) (version, bool) {
+	m := artifactVersion.FindStringSubmatch(strings.TrimSuffix(name, filepath.Ext(name)))
+	if m == nil {
+		return version{}, false
+	}
+
+	var v version
+	for _, s := range strings.Split(m[1], ".") {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return version{}, false
+		}
+		v.numbers = append(v.numbers, n)
+	}
+	v.qualifier = m[2]
+
+	return v, true
+}
+
+func (v version) snapshot() bool {
+	return strings.EqualFold(v.qualifier, "SNAPSHOT") || strings.HasSuffix(strings.ToUpper(v.qualifier), "-SNAPSHOT")
+}
+
+// compare returns a negative number if v is earlier than o, a positive number if it is later, and zero if they are
+// equal.
+func (v version) compare(o version, ranking SnapshotRanking) int {
+	if ranking == SnapshotRankingLowest && v.snapshot() != o.snapshot() {
+		if v.snapshot() {
+			return -1
+		}
+		return 1
+	}
+
+	for i := 0; i < len(v.numbers) || i < len(o.numbers); i++ {
+		var a, b int
+		if i < len(v.numbers) {
+			a = v.numbers[i]
+		}
+		if i < len(o.numbers) {
+			b = o.numbers[i]
+		}
+
+		if a != b {
+			return a - b
+		}
+	}
+
+	switch {
+	case v.qualifier == o.qualifier:
+		return 0
+	case v.qualifier == "":
+		return 1
+	case o.qualifier == "":
+		return -1
+	default:
+		return strings.Compare(v.qualifier, o.qualifier)
+	}
+}
+
+// latestVersion returns the candidate whose name has the single highest version.
+func latestVersion(candidates []string, ranking SnapshotRanking) (string, bool) {
+	var (
+		latest  string
+		highest version
+		unique  bool
+	)
+
+	for _, c := range candidates {
+		v, ok := parseVersion(filepath.Base(c))
+		if !ok {
+			continue
+		}
+
+		if latest == "" {
+			latest, highest, unique = c, v, true
+		} else if r := v.compare(highest, ranking); r > 0 {
+			latest, highest, unique = c, v, true
+		} else if r == 0 {
+			unique = false
+		}
+	}
+
+	return latest, unique
+}
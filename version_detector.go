@@ -0,0 +1,118 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/paketo-buildpacks/libpak/effect"
+)
+
+// VersionDetector detects the version of a tool that affects the build by executing it and parsing its output.
+type VersionDetector struct {
+
+	// MetadataKey is the expected metadata key that the detected version is recorded under.
+	MetadataKey string
+
+	// Command is the command to execute.
+	Command string
+
+	// Args are the arguments to execute the command with.
+	Args []string
+
+	// Pattern is matched against the command's combined output.  Its first capture group is the version.  If the
+	// pattern does not match and the output is a single word, that word is the version.
+	Pattern *regexp.Regexp
+}
+
+// JavacVersionDetector detects the version of javac from the output of javac -version, e.g. javac 17.0.2.
+var JavacVersionDetector = VersionDetector{
+	MetadataKey: "java-version",
+	Command:     "javac",
+	Args:        []string{"-version"},
+	Pattern:     regexp.MustCompile(`(?m)^javac\s+(\S+)`),
+}
+
+// JavaVersionDetector detects the version of java from the output of java --version, e.g. openjdk 17.0.2 2022-01-18.
+var JavaVersionDetector = VersionDetector{
+	MetadataKey: "java-version",
+	Command:     "java",
+	Args:        []string{"--version"},
+	Pattern:     regexp.MustCompile(`(?m)^\S+\s+(\d\S*)`),
+}
+
+// KotlincVersionDetector detects the version of kotlinc from the output of kotlinc -version, e.g.
+// info: kotlinc-jvm 1.9.22 (JRE 17.0.2+8).
+var KotlincVersionDetector = VersionDetector{
+	MetadataKey: "kotlin-version",
+	Command:     "kotlinc",
+	Args:        []string{"-version"},
+	Pattern:     regexp.MustCompile(`kotlinc-jvm\s+(\S+)`),
+}
+
+// ScalaVersionDetector detects the version of scala from the output of scala -version, e.g.
+// Scala code runner version 2.13.12 -- Copyright 2002-2023, LAMP/EPFL and Lightbend, Inc.
+var ScalaVersionDetector = VersionDetector{
+	MetadataKey: "scala-version",
+	Command:     "scala",
+	Args:        []string{"-version"},
+	Pattern:     regexp.MustCompile(`version\s+(\d\S*)`),
+}
+
+// GroovyVersionDetector detects the version of groovy from the output of groovy --version, e.g.
+// Groovy Version: 4.0.15 JVM: 17.0.2 Vendor: Eclipse Adoptium OS: Linux.
+var GroovyVersionDetector = VersionDetector{
+	MetadataKey: "groovy-version",
+	Command:     "groovy",
+	Args:        []string{"--version"},
+	Pattern:     regexp.MustCompile(`Groovy Version:\s+(\S+)`),
+}
+
+// Detect executes the command and parses the version from its output.
+func (v VersionDetector) Detect(executor effect.Executor) (string, error) {
+	buf := &bytes.Buffer{}
+
+	if err := executor.Execute(effect.Execution{
+		Command: v.Command,
+		Args:    v.Args,
+		Stdout:  buf,
+		Stderr:  buf,
+	}); err != nil {
+		return "", fmt.Errorf("error executing '%s %s':\n Combined Output: %s: \n%w",
+			v.Command, strings.Join(v.Args, " "), buf.String(), err)
+	}
+
+	return v.Parse(buf.String()), nil
+}
+
+// Parse parses the version from the output of the command.  Returns unknown if no version can be found.
+func (v VersionDetector) Parse(output string) string {
+	if v.Pattern != nil {
+		if m := v.Pattern.FindStringSubmatch(output); len(m) > 1 {
+			return m[1]
+		}
+	}
+
+	if s := strings.Fields(output); len(s) == 1 {
+		return s[0]
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,109 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs_test
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/effect"
+	"github.com/paketo-buildpacks/libpak/effect/mocks"
+	"github.com/sclevine/spec"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/paketo-buildpacks/libbs"
+)
+
+func testVersionDetector(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	context("Parse", func() {
+		it("parses javac -version", func() {
+			Expect(libbs.JavacVersionDetector.Parse("javac 17.0.2\n")).To(Equal("17.0.2"))
+		})
+
+		it("parses javac -version with preceding output", func() {
+			Expect(libbs.JavacVersionDetector.Parse("Picked up JAVA_TOOL_OPTIONS: -Xmx1g\njavac 1.8.0_292\n")).
+				To(Equal("1.8.0_292"))
+		})
+
+		it("parses java --version", func() {
+			Expect(libbs.JavaVersionDetector.Parse(`openjdk 17.0.2 2022-01-18
+OpenJDK Runtime Environment Temurin-17.0.2+8 (build 17.0.2+8)
+OpenJDK 64-Bit Server VM Temurin-17.0.2+8 (build 17.0.2+8, mixed mode, sharing)
+`)).To(Equal("17.0.2"))
+		})
+
+		it("parses java --version with preceding output", func() {
+			Expect(libbs.JavaVersionDetector.Parse("Picked up JAVA_TOOL_OPTIONS: -Xmx1g\njava 21.0.1 2023-10-17 LTS\n")).
+				To(Equal("21.0.1"))
+		})
+
+		it("parses kotlinc -version", func() {
+			Expect(libbs.KotlincVersionDetector.Parse("info: kotlinc-jvm 1.9.22 (JRE 17.0.2+8)\n")).To(Equal("1.9.22"))
+		})
+
+		it("parses scala -version", func() {
+			Expect(libbs.ScalaVersionDetector.Parse("Scala code runner version 2.13.12 -- Copyright 2002-2023, LAMP/EPFL and Lightbend, Inc.\n")).
+				To(Equal("2.13.12"))
+		})
+
+		it("parses groovy --version", func() {
+			Expect(libbs.GroovyVersionDetector.Parse("Groovy Version: 4.0.15 JVM: 17.0.2 Vendor: Eclipse Adoptium OS: Linux\n")).
+				To(Equal("4.0.15"))
+		})
+
+		it("parses a single word", func() {
+			Expect(libbs.JavacVersionDetector.Parse("17.0.2\n")).To(Equal("17.0.2"))
+		})
+
+		it("returns unknown for unrecognized output", func() {
+			Expect(libbs.KotlincVersionDetector.Parse("command not found: kotlinc\n")).To(Equal("unknown"))
+		})
+	})
+
+	context("Detect", func() {
+		var executor *mocks.Executor
+
+		it.Before(func() {
+			executor = &mocks.Executor{}
+		})
+
+		it("executes the command", func() {
+			executor.On("Execute", mock.Anything).Run(func(args mock.Arguments) {
+				_, err := args.Get(0).(effect.Execution).Stderr.Write([]byte("info: kotlinc-jvm 1.9.22 (JRE 17.0.2+8)\n"))
+				Expect(err).NotTo(HaveOccurred())
+			}).Return(nil)
+
+			Expect(libbs.KotlincVersionDetector.Detect(executor)).To(Equal("1.9.22"))
+
+			e := executor.Calls[0].Arguments[0].(effect.Execution)
+			Expect(e.Command).To(Equal("kotlinc"))
+			Expect(e.Args).To(Equal([]string{"-version"}))
+		})
+
+		it("fails if the command fails", func() {
+			executor.On("Execute", mock.Anything).Return(fmt.Errorf("test-error"))
+
+			_, err := libbs.JavaVersionDetector.Detect(executor)
+			Expect(err).To(MatchError(ContainSubstring("error executing 'java --version'")))
+		})
+	})
+}
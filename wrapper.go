@@ -0,0 +1,137 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/magiconair/properties"
+)
+
+// Wrapper describes the files of a build tool wrapper, relative to the application path.
+type Wrapper struct {
+
+	// Properties is the path of the wrapper properties file.
+	Properties string
+
+	// JAR is the path of the wrapper JAR.
+	JAR string
+}
+
+// Wrappers are the build tool wrappers verified by VerifyWrapper.
+var Wrappers = []Wrapper{
+	{
+		Properties: filepath.Join("gradle", "wrapper", "gradle-wrapper.properties"),
+		JAR:        filepath.Join("gradle", "wrapper", "gradle-wrapper.jar"),
+	},
+	{
+		Properties: filepath.Join(".mvn", "wrapper", "maven-wrapper.properties"),
+		JAR:        filepath.Join(".mvn", "wrapper", "maven-wrapper.jar"),
+	},
+}
+
+var sha256Sum = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// VerifyWrapper verifies the checksums of each of the Wrappers in appPath.  The properties of a wrapper must declare
+// a distributionSha256Sum, so that the wrapper verifies the distribution it downloads.  Only the format of the
+// distributionSha256Sum is checked, as the distribution is not downloaded until the build runs.  If the wrapper JAR is
+// present, its SHA256 must match the wrapperSha256Sum declared by the properties, if any, and must be one of
+// sha256Sums, if any.  A wrapper JAR without either an expected checksum is rejected.  Wrappers that are not present
+// are ignored.
+func VerifyWrapper(appPath string, sha256Sums []string) error {
+	for _, w := range Wrappers {
+		file := filepath.Join(appPath, w.Properties)
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("unable to stat %s\n%w", file, err)
+		}
+
+		p, err := properties.LoadFile(file, properties.UTF8)
+		if err != nil {
+			return fmt.Errorf("unable to read %s\n%w", file, err)
+		}
+
+		distribution, ok := p.Get("distributionSha256Sum")
+		if !ok {
+			return fmt.Errorf("%s does not declare a distributionSha256Sum", w.Properties)
+		} else if !sha256Sum.MatchString(strings.TrimSpace(distribution)) {
+			return fmt.Errorf("distributionSha256Sum %s of %s is not a SHA256", distribution, w.Properties)
+		}
+
+		expected, declared := p.Get("wrapperSha256Sum")
+
+		jar := filepath.Join(appPath, w.JAR)
+		if _, err := os.Stat(jar); os.IsNotExist(err) && !declared {
+			continue
+		}
+
+		if !declared && len(sha256Sums) == 0 {
+			return fmt.Errorf("%s does not declare a wrapperSha256Sum and no expected checksums of %s are configured",
+				w.Properties, w.JAR)
+		}
+
+		actual, err := sha256File(jar)
+		if err != nil {
+			return err
+		}
+
+		if declared && !strings.EqualFold(actual, strings.TrimSpace(expected)) {
+			return fmt.Errorf("SHA256 of %s is %s, but %s declares wrapperSha256Sum %s", w.JAR, actual, w.Properties, expected)
+		}
+
+		if len(sha256Sums) > 0 && !containsFold(sha256Sums, actual) {
+			return fmt.Errorf("SHA256 of %s is %s, which is not one of the expected checksums %s",
+				w.JAR, actual, strings.Join(sha256Sums, ", "))
+		}
+	}
+
+	return nil
+}
+
+func containsFold(candidates []string, s string) bool {
+	for _, c := range candidates {
+		if strings.EqualFold(c, s) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sha256File returns the hex encoded SHA256 of the file at path.
+func sha256File(path string) (string, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("unable to open %s\n%w", path, err)
+	}
+	defer in.Close()
+
+	s := sha256.New()
+	if _, err := io.Copy(s, in); err != nil {
+		return "", fmt.Errorf("unable to hash %s\n%w", path, err)
+	}
+
+	return hex.EncodeToString(s.Sum(nil)), nil
+}
@@ -0,0 +1,145 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libbs"
+)
+
+func testWrapper(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+
+		distribution = "2db75c40782f5e8ba1fc278a5574bab070adccb2d21ca5a6e5ed840888448046"
+		jar          string
+		path         string
+	)
+
+	it.Before(func() {
+		var err error
+
+		path, err = ioutil.TempDir("", "wrapper")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(os.MkdirAll(filepath.Join(path, "gradle", "wrapper"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(path, "gradle", "wrapper", "gradle-wrapper.jar"), []byte("test-wrapper"), 0644)).
+			To(Succeed())
+
+		s := sha256.Sum256([]byte("test-wrapper"))
+		jar = hex.EncodeToString(s[:])
+	})
+
+	it.After(func() {
+		Expect(os.RemoveAll(path)).To(Succeed())
+	})
+
+	properties := func(content string) {
+		Expect(ioutil.WriteFile(filepath.Join(path, "gradle", "wrapper", "gradle-wrapper.properties"), []byte(content), 0644)).
+			To(Succeed())
+	}
+
+	it("passes without a wrapper", func() {
+		Expect(libbs.VerifyWrapper(path, nil)).To(Succeed())
+	})
+
+	it("passes with matching checksums", func() {
+		properties(fmt.Sprintf("distributionUrl=https\\://services.gradle.org/distributions/gradle-8.5-bin.zip\n"+
+			"distributionSha256Sum=%s\nwrapperSha256Sum=%s\n", distribution, jar))
+
+		Expect(libbs.VerifyWrapper(path, nil)).To(Succeed())
+	})
+
+	it("fails without an expected wrapper JAR checksum", func() {
+		properties(fmt.Sprintf("distributionSha256Sum=%s\n", distribution))
+
+		Expect(libbs.VerifyWrapper(path, nil)).To(MatchError("gradle/wrapper/gradle-wrapper.properties does not declare " +
+			"a wrapperSha256Sum and no expected checksums of gradle/wrapper/gradle-wrapper.jar are configured"))
+	})
+
+	it("passes without a wrapper JAR", func() {
+		Expect(os.Remove(filepath.Join(path, "gradle", "wrapper", "gradle-wrapper.jar"))).To(Succeed())
+		properties(fmt.Sprintf("distributionSha256Sum=%s\n", distribution))
+
+		Expect(libbs.VerifyWrapper(path, nil)).To(Succeed())
+	})
+
+	it("passes with a configured wrapper JAR checksum", func() {
+		properties(fmt.Sprintf("distributionSha256Sum=%s\n", distribution))
+
+		Expect(libbs.VerifyWrapper(path, []string{distribution, strings.ToUpper(jar)})).To(Succeed())
+	})
+
+	it("fails with a mismatched configured wrapper JAR checksum", func() {
+		properties(fmt.Sprintf("distributionSha256Sum=%s\n", distribution))
+
+		Expect(libbs.VerifyWrapper(path, []string{distribution})).To(MatchError(fmt.Sprintf(
+			"SHA256 of gradle/wrapper/gradle-wrapper.jar is %s, which is not one of the expected checksums %s",
+			jar, distribution)))
+	})
+
+	it("fails with a declared checksum that is not configured", func() {
+		properties(fmt.Sprintf("distributionSha256Sum=%s\nwrapperSha256Sum=%s\n", distribution, jar))
+
+		Expect(libbs.VerifyWrapper(path, []string{distribution})).
+			To(MatchError(ContainSubstring("which is not one of the expected checksums")))
+	})
+
+	it("fails with a mismatched wrapper JAR checksum", func() {
+		properties(fmt.Sprintf("distributionSha256Sum=%s\nwrapperSha256Sum=%s\n", distribution, distribution))
+
+		Expect(libbs.VerifyWrapper(path, nil)).To(MatchError(fmt.Sprintf(
+			"SHA256 of gradle/wrapper/gradle-wrapper.jar is %s, but gradle/wrapper/gradle-wrapper.properties declares "+
+				"wrapperSha256Sum %s", jar, distribution)))
+	})
+
+	it("fails without a distribution checksum", func() {
+		properties("distributionUrl=https\\://services.gradle.org/distributions/gradle-8.5-bin.zip\n")
+
+		Expect(libbs.VerifyWrapper(path, nil)).
+			To(MatchError("gradle/wrapper/gradle-wrapper.properties does not declare a distributionSha256Sum"))
+	})
+
+	it("fails with a malformed distribution checksum", func() {
+		properties("distributionSha256Sum=test-checksum\n")
+
+		Expect(libbs.VerifyWrapper(path, nil)).
+			To(MatchError("distributionSha256Sum test-checksum of gradle/wrapper/gradle-wrapper.properties is not a SHA256"))
+	})
+
+	it("verifies the Maven wrapper", func() {
+		Expect(os.MkdirAll(filepath.Join(path, ".mvn", "wrapper"), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(path, ".mvn", "wrapper", "maven-wrapper.jar"), []byte("test-wrapper"), 0644)).
+			To(Succeed())
+		Expect(ioutil.WriteFile(filepath.Join(path, ".mvn", "wrapper", "maven-wrapper.properties"),
+			[]byte(fmt.Sprintf("distributionSha256Sum=%s\nwrapperSha256Sum=%s\n", distribution, distribution)), 0644)).
+			To(Succeed())
+
+		Expect(libbs.VerifyWrapper(path, nil)).To(MatchError(HavePrefix("SHA256 of .mvn/wrapper/maven-wrapper.jar")))
+	})
+}
@@ -0,0 +1,225 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+)
+
+// DefaultLineWriterBufferSize is the buffer size used by a LineWriter when no positive size is specified.
+const DefaultLineWriterBufferSize = 64 * 1024
+
+// LineWriter is an io.Writer that buffers output and writes it to a delegate one complete line at a time.  Lines
+// longer than the buffer size are written in buffer-sized chunks.
+type LineWriter struct {
+	buffer []byte
+	size   int
+	writer io.Writer
+}
+
+// NewLineWriter creates a LineWriter that wraps another writer, buffering up to size bytes of a partial line.
+func NewLineWriter(writer io.Writer, size int) *LineWriter {
+	if size <= 0 {
+		size = DefaultLineWriterBufferSize
+	}
+
+	return &LineWriter{writer: writer, size: size}
+}
+
+func (l *LineWriter) Write(b []byte) (int, error) {
+	l.buffer = append(l.buffer, b...)
+
+	for {
+		i := bytes.IndexByte(l.buffer, '\n')
+		if i < 0 {
+			break
+		}
+
+		if err := l.write(i + 1); err != nil {
+			return len(b), err
+		}
+	}
+
+	for len(l.buffer) >= l.size {
+		if err := l.write(l.size); err != nil {
+			return len(b), err
+		}
+	}
+
+	return len(b), nil
+}
+
+// Flush writes any buffered partial line to the delegate.
+func (l *LineWriter) Flush() error {
+	if len(l.buffer) == 0 {
+		return nil
+	}
+
+	return l.write(len(l.buffer))
+}
+
+func (l *LineWriter) write(n int) error {
+	_, err := l.writer.Write(l.buffer[:n])
+	l.buffer = l.buffer[n:]
+	return err
+}
+
+// SynchronizedWriter is an io.Writer that serializes writes to a delegate so that it can be shared by concurrent
+// writers.
+type SynchronizedWriter struct {
+	mutex  sync.Mutex
+	writer io.Writer
+}
+
+// NewSynchronizedWriter creates a SynchronizedWriter that wraps another writer.
+func NewSynchronizedWriter(writer io.Writer) *SynchronizedWriter {
+	return &SynchronizedWriter{writer: writer}
+}
+
+func (s *SynchronizedWriter) Write(b []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.writer.Write(b)
+}
+
+// TruncatingWriter is an io.Writer that writes at most a limited number of bytes to a delegate.  Once the limit is
+// reached, a truncation marker is written and all further output is discarded.
+type TruncatingWriter struct {
+	limit     int64
+	truncated bool
+	writer    io.Writer
+	written   int64
+}
+
+// NewTruncatingWriter creates a TruncatingWriter that writes at most limit bytes to another writer.
+func NewTruncatingWriter(writer io.Writer, limit int64) *TruncatingWriter {
+	return &TruncatingWriter{writer: writer, limit: limit}
+}
+
+func (t *TruncatingWriter) Write(b []byte) (int, error) {
+	if t.truncated {
+		return len(b), nil
+	}
+
+	if remaining := t.limit - t.written; int64(len(b)) > remaining {
+		if _, err := t.writer.Write(b[:remaining]); err != nil {
+			return len(b), err
+		}
+		t.written, t.truncated = t.limit, true
+
+		if _, err := fmt.Fprintf(t.writer, "\n[build log truncated after %d bytes]\n", t.limit); err != nil {
+			return len(b), err
+		}
+
+		return len(b), nil
+	}
+
+	n, err := t.writer.Write(b)
+	t.written += int64(n)
+	return len(b), err
+}
+
+// OutputScanner is an io.Writer that collects the text, or optionally the whole line, of each line written to it that
+// matches a pattern.  Lines longer than bufio.MaxScanTokenSize are scanned in chunks of that size.
+type OutputScanner struct {
+
+	// Pattern is the pattern that output is matched against.
+	Pattern *regexp.Regexp
+
+	// Lines, if true, collects each entire line, with surrounding whitespace trimmed, that matches the pattern rather
+	// than only the matching text.
+	Lines bool
+
+	buffer  []byte
+	matches []string
+}
+
+// NewOutputScanner creates an OutputScanner that collects text matching pattern.
+func NewOutputScanner(pattern *regexp.Regexp) *OutputScanner {
+	return &OutputScanner{Pattern: pattern}
+}
+
+func (o *OutputScanner) Write(b []byte) (int, error) {
+	o.buffer = append(o.buffer, b...)
+
+	for {
+		i := bytes.IndexByte(o.buffer, '\n')
+		if i < 0 {
+			break
+		}
+
+		o.scan(o.buffer[:i])
+		o.buffer = o.buffer[i+1:]
+	}
+
+	for len(o.buffer) >= bufio.MaxScanTokenSize {
+		o.scan(o.buffer[:bufio.MaxScanTokenSize])
+		o.buffer = o.buffer[bufio.MaxScanTokenSize:]
+	}
+
+	return len(b), nil
+}
+
+// Flush scans any buffered partial line.
+func (o *OutputScanner) Flush() error {
+	if len(o.buffer) > 0 {
+		o.scan(o.buffer)
+		o.buffer = nil
+	}
+
+	return nil
+}
+
+// Matches returns the unique matches, in the order in which they were first seen.
+func (o *OutputScanner) Matches() []string {
+	return o.matches
+}
+
+func (o *OutputScanner) scan(line []byte) {
+	line = bytes.TrimRight(line, "\r")
+
+	if o.Lines {
+		if o.Pattern.Match(line) {
+			if s := string(bytes.TrimSpace(line)); !contains(o.matches, s) {
+				o.matches = append(o.matches, s)
+			}
+		}
+		return
+	}
+
+	for _, m := range o.Pattern.FindAll(line, -1) {
+		s := string(m)
+		if !contains(o.matches, s) {
+			o.matches = append(o.matches, s)
+		}
+	}
+}
+
+func contains(candidates []string, s string) bool {
+	for _, c := range candidates {
+		if c == s {
+			return true
+		}
+	}
+	return false
+}
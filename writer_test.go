@@ -0,0 +1,178 @@
+/*
+ * Copyright 2018-2020 the original author or authors.
+ *
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *      https://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package libbs_test
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+	"github.com/paketo-buildpacks/libpak/bard"
+	"github.com/sclevine/spec"
+
+	"github.com/paketo-buildpacks/libbs"
+)
+
+type recordingWriter struct {
+	writes []string
+}
+
+func (r *recordingWriter) Write(b []byte) (int, error) {
+	r.writes = append(r.writes, string(b))
+	return len(b), nil
+}
+
+func testWriter(t *testing.T, context spec.G, it spec.S) {
+	var (
+		Expect = NewWithT(t).Expect
+	)
+
+	context("LineWriter", func() {
+		it("writes complete lines", func() {
+			r := &recordingWriter{}
+			w := libbs.NewLineWriter(r, 0)
+
+			_, err := w.Write([]byte("test-1\ntest-"))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = w.Write([]byte("2\n"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(r.writes).To(Equal([]string{"test-1\n", "test-2\n"}))
+		})
+
+		it("flushes a partial line", func() {
+			r := &recordingWriter{}
+			w := libbs.NewLineWriter(r, 0)
+
+			_, err := w.Write([]byte("test-1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(r.writes).To(BeEmpty())
+
+			Expect(w.Flush()).To(Succeed())
+			Expect(r.writes).To(Equal([]string{"test-1"}))
+		})
+
+		it("preserves a long line written in fragments", func() {
+			line := strings.Repeat("x", 100_000)
+			b := &bytes.Buffer{}
+			w := libbs.NewLineWriter(bard.NewWriter(b, bard.WithIndent(3)), 128*1024)
+
+			for i := 0; i < len(line); i += 1000 {
+				_, err := w.Write([]byte(line[i : i+1000]))
+				Expect(err).NotTo(HaveOccurred())
+			}
+			_, err := w.Write([]byte("\n"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(b.String()).To(Equal("      " + line + "\n"))
+		})
+
+		it("writes lines longer than the buffer in chunks", func() {
+			r := &recordingWriter{}
+			w := libbs.NewLineWriter(r, 4)
+
+			_, err := w.Write([]byte("0123456789\n"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(r.writes).To(Equal([]string{"0123456789\n"}))
+
+			r.writes = nil
+			_, err = w.Write([]byte("0123456789"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(w.Flush()).To(Succeed())
+
+			Expect(r.writes).To(Equal([]string{"0123", "4567", "89"}))
+		})
+	})
+
+	context("OutputScanner", func() {
+		it("collects unique matches across writes", func() {
+			s := libbs.NewOutputScanner(libbs.GradleBuildScanPattern)
+
+			_, err := s.Write([]byte("Publishing build scan...\nhttps://gradle.com/s/abc"))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s.Write([]byte("def123\r\nhttps://gradle.com/s/abcdef123\nhttps://gradle.com/s/xyz"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.Flush()).To(Succeed())
+
+			Expect(s.Matches()).To(Equal([]string{"https://gradle.com/s/abcdef123", "https://gradle.com/s/xyz"}))
+		})
+
+		it("collects nothing without matches", func() {
+			s := libbs.NewOutputScanner(regexp.MustCompile(`test-[0-9]+`))
+
+			_, err := s.Write([]byte("test-line\n"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(s.Matches()).To(BeEmpty())
+		})
+
+		it("collects unique matching lines", func() {
+			s := &libbs.OutputScanner{Pattern: regexp.MustCompile(`(?i)\bwarning\b`), Lines: true}
+
+			_, err := s.Write([]byte("  [WARNING] test-1\r\ntest-line\n[WARNING] test-1\n"))
+			Expect(err).NotTo(HaveOccurred())
+			_, err = s.Write([]byte("warning: test-2"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.Flush()).To(Succeed())
+
+			Expect(s.Matches()).To(Equal([]string{"[WARNING] test-1", "warning: test-2"}))
+		})
+
+		it("scans long lines without a newline in chunks", func() {
+			s := libbs.NewOutputScanner(regexp.MustCompile(`test-[0-9]+`))
+
+			_, err := s.Write([]byte("test-1" + strings.Repeat("x", bufio.MaxScanTokenSize)))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.Matches()).To(Equal([]string{"test-1"}))
+
+			_, err = s.Write([]byte("test-2"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.Flush()).To(Succeed())
+
+			Expect(s.Matches()).To(Equal([]string{"test-1", "test-2"}))
+		})
+	})
+
+	context("TruncatingWriter", func() {
+		it("writes output below the limit", func() {
+			b := &bytes.Buffer{}
+			w := libbs.NewTruncatingWriter(b, 10)
+
+			_, err := w.Write([]byte("test-1"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(b.String()).To(Equal("test-1"))
+		})
+
+		it("truncates output at the limit", func() {
+			b := &bytes.Buffer{}
+			w := libbs.NewTruncatingWriter(b, 10)
+
+			n, err := w.Write([]byte("test-1\ntest-2\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(14))
+			_, err = w.Write([]byte("test-3\n"))
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(b.String()).To(Equal("test-1\ntes\n[build log truncated after 10 bytes]\n"))
+		})
+	})
+}